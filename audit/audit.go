@@ -0,0 +1,220 @@
+// Package audit describes the append-only log of employee changes: who made
+// each change, when, in which request, and the fields it changed.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/m/Assesment/models"
+)
+
+// operations recorded in the log
+const (
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpDelete  = "delete"
+	OpRestore = "restore"
+	OpPurge   = "purge"
+)
+
+// Operations are the recorded operations.
+var Operations = []string{OpCreate, OpUpdate, OpDelete, OpRestore, OpPurge}
+
+// SystemActor is the actor of changes made outside any request.
+const SystemActor = "system"
+
+// Entry is one change of one employee.
+type Entry struct {
+	ID         int64     `json:"id"`
+	EmployeeID int64     `json:"employee_id"`
+	Operation  string    `json:"operation"`
+	Actor      string    `json:"actor"`
+	RequestID  string    `json:"request_id,omitempty"`
+	At         time.Time `json:"at"`
+	// Version is the employee's version after the change, the last one it
+	// had for a purge.
+	Version int64    `json:"version"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is the before and after JSON value of a field, null on the side
+// where the employee didn't exist.
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+var null = json.RawMessage("null")
+
+// Diff lists the fields that differ between before and after, either being
+// nil for an employee created or purged.
+func Diff(before, after *models.Employee) []Change {
+	b, a := fieldValues(before), fieldValues(after)
+
+	changes := []Change{}
+	for i, field := range fields {
+		if string(b[i]) != string(a[i]) {
+			changes = append(changes, Change{Field: field, Before: b[i], After: a[i]})
+		}
+	}
+
+	return changes
+}
+
+// fields are the diffed fields, in the order of fieldValues.
+var fields = []string{"name", "position", "salary", "currency", "department_id", "manager_id", "deleted_at"}
+
+func fieldValues(employee *models.Employee) []json.RawMessage {
+	if employee == nil {
+		return []json.RawMessage{null, null, null, null, null, null, null}
+	}
+
+	// no department or manager is null
+	departmentID, managerID := reference(employee.DepartmentID), reference(employee.ManagerID)
+
+	values := make([]json.RawMessage, 0, len(fields))
+	for _, v := range []interface{}{employee.Name, employee.Position, employee.Salary, employee.Currency, departmentID, managerID, employee.DeletedAt} {
+		// strings, numbers and times always marshal
+		data, _ := json.Marshal(v)
+		values = append(values, data)
+	}
+
+	return values
+}
+
+// reference is the value of an optional id, nil for zero.
+func reference(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
+}
+
+// Now is the time entries are recorded at, in UTC to the microsecond every
+// backend keeps.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Caller is who makes the changes of a request.
+type Caller struct {
+	Actor     string
+	RequestID string
+}
+
+type callerKey struct{}
+
+// WithCaller returns ctx carrying the caller, recorded with every change
+// made under it.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom is the caller carried by ctx, SystemActor when there is none.
+func CallerFrom(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerKey{}).(Caller)
+	if caller.Actor == "" {
+		caller.Actor = SystemActor
+	}
+
+	return caller
+}
+
+// NewEntry is the entry of a change of the employee made by the caller of
+// ctx, before or after being nil for a creation or a purge.
+func NewEntry(ctx context.Context, operation string, before, after *models.Employee) Entry {
+	caller := CallerFrom(ctx)
+	entry := Entry{
+		Operation: operation,
+		Actor:     caller.Actor,
+		RequestID: caller.RequestID,
+		At:        Now(),
+		Changes:   Diff(before, after),
+	}
+
+	for _, employee := range []*models.Employee{before, after} {
+		if employee != nil {
+			entry.EmployeeID, entry.Version = employee.ID, employee.Version
+		}
+	}
+
+	return entry
+}
+
+// Query selects entries matching all of its conditions, zero values match
+// everything. Entries are read newest first.
+type Query struct {
+	EmployeeID int64
+	Actor      string
+	// Operations matches any of the operations.
+	Operations []string
+	// Since and Until bound the time of the change, Since included.
+	Since time.Time
+	Until time.Time
+	// Before reads the entries older than the one with this id, for paging.
+	Before int64
+	Limit  int
+}
+
+// ErrInvalidQuery reports a Query that can't select entries.
+var ErrInvalidQuery = errors.New("invalid audit query")
+
+func (q Query) Validate() error {
+	if q.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidQuery)
+	}
+
+	for _, operation := range q.Operations {
+		if !known(operation) {
+			return fmt.Errorf("%w: unknown operation %q", ErrInvalidQuery, operation)
+		}
+	}
+
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		return fmt.Errorf("%w: until must be after since", ErrInvalidQuery)
+	}
+
+	return nil
+}
+
+func known(operation string) bool {
+	return contains(Operations, operation)
+}
+
+func contains(operations []string, operation string) bool {
+	for _, op := range operations {
+		if op == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches reports whether the entry matches the query's conditions, its
+// paging aside.
+func (q Query) Matches(entry Entry) bool {
+	if q.EmployeeID != 0 && entry.EmployeeID != q.EmployeeID {
+		return false
+	}
+
+	if q.Actor != "" && entry.Actor != q.Actor {
+		return false
+	}
+
+	if len(q.Operations) > 0 && !contains(q.Operations, entry.Operation) {
+		return false
+	}
+
+	if !q.Since.IsZero() && entry.At.Before(q.Since) {
+		return false
+	}
+
+	return q.Until.IsZero() || entry.At.Before(q.Until)
+}
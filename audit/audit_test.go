@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := models.Employee{ID: 1, Name: "John", Position: "SDE", Salary: money.Major(1000), Currency: "USD", Version: 1}
+	after := before
+	after.Position = ""
+	after.Version = 2
+
+	changes := Diff(&before, &after)
+	assert.Equal(t, []Change{{Field: "position", Before: json.RawMessage(`"SDE"`), After: json.RawMessage(`""`)}}, changes)
+
+	// the version alone is no change
+	assert.Empty(t, Diff(&after, &after))
+
+	// no department or manager is null
+	after.DepartmentID = 3
+	after.ManagerID = 4
+	changes = Diff(&before, &after)
+	assert.Equal(t, Change{Field: "department_id", Before: null, After: json.RawMessage(`3`)}, changes[1])
+	assert.Equal(t, Change{Field: "manager_id", Before: null, After: json.RawMessage(`4`)}, changes[2])
+
+	// a creation lists every field, department_id, manager_id and deleted_at
+	// null on both sides
+	changes = Diff(nil, &before)
+	assert.Len(t, changes, 4)
+	assert.Equal(t, "null", string(changes[2].Before))
+	assert.Equal(t, "1000", string(changes[2].After))
+	assert.Equal(t, `"USD"`, string(changes[3].After))
+}
+
+func TestNewEntry(t *testing.T) {
+	before := models.Employee{ID: 1, Name: "John", Version: 3}
+
+	entry := NewEntry(context.Background(), OpPurge, &before, nil)
+	assert.Equal(t, int64(1), entry.EmployeeID)
+	assert.Equal(t, int64(3), entry.Version)
+	assert.Equal(t, SystemActor, entry.Actor)
+	assert.Empty(t, entry.RequestID)
+
+	ctx := WithCaller(context.Background(), Caller{Actor: "alice", RequestID: "req-1"})
+	entry = NewEntry(ctx, OpCreate, nil, &before)
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, time.UTC, entry.At.Location())
+}
+
+func TestQueryValidate(t *testing.T) {
+	now := time.Now()
+
+	assert.NoError(t, Query{Operations: []string{OpCreate, OpPurge}, Since: now, Until: now.Add(time.Second), Limit: 1}.Validate())
+	assert.ErrorIs(t, Query{}.Validate(), ErrInvalidQuery)
+	assert.ErrorIs(t, Query{Operations: []string{"rename"}, Limit: 1}.Validate(), ErrInvalidQuery)
+	assert.ErrorIs(t, Query{Since: now, Until: now, Limit: 1}.Validate(), ErrInvalidQuery)
+}
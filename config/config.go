@@ -0,0 +1,438 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/Assesment/money"
+)
+
+// Config holds everything needed to start the service. Values are resolved
+// in the order defaults < config file < environment < command line flags.
+type Config struct {
+	Addr            string
+	Store           string
+	DSN             string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	PingTimeout     time.Duration
+	DBReadTimeout   time.Duration
+	DBWriteTimeout  time.Duration
+	// Migrate applies pending schema migrations before serving.
+	Migrate bool
+	// Positions is the catalogue of allowed employee positions, empty
+	// allows any.
+	Positions []string
+	MaxSalary money.Amount
+	// Currency is the currency of employees created without one and the
+	// one reports are made in.
+	Currency string
+	// Rates converts the other salary currencies into Currency, only those
+	// with a rate are accepted.
+	Rates money.Rates
+	// CursorSecret signs list cursors so they stay valid across restarts and
+	// instances, a random per process key is used when empty.
+	CursorSecret string
+	// PurgeRetention is how long deleted employees are kept before the purge
+	// job removes them for good, zero disables the job.
+	PurgeRetention time.Duration
+	// PurgeInterval is how often the purge job runs.
+	PurgeInterval time.Duration
+	// ScheduleInterval is how often the scheduler makes the scheduled
+	// changes that came due.
+	ScheduleInterval time.Duration
+	// Args are the arguments left after the flags, the command's own flags
+	// among them.
+	Args []string
+}
+
+// fileConfig mirrors Config for the JSON config file, durations are written
+// as strings like "15s".
+type fileConfig struct {
+	Addr             string       `json:"addr"`
+	Store            string       `json:"store"`
+	DSN              string       `json:"dsn"`
+	ReadTimeout      string       `json:"read_timeout"`
+	WriteTimeout     string       `json:"write_timeout"`
+	IdleTimeout      string       `json:"idle_timeout"`
+	ShutdownTimeout  string       `json:"shutdown_timeout"`
+	PingTimeout      string       `json:"ping_timeout"`
+	DBReadTimeout    string       `json:"db_read_timeout"`
+	DBWriteTimeout   string       `json:"db_write_timeout"`
+	Migrate          *bool        `json:"migrate"`
+	Positions        []string     `json:"positions"`
+	MaxSalary        money.Amount `json:"max_salary"`
+	Currency         string       `json:"currency"`
+	Rates            money.Rates  `json:"rates"`
+	CursorSecret     string       `json:"cursor_secret"`
+	PurgeRetention   string       `json:"purge_retention"`
+	PurgeInterval    string       `json:"purge_interval"`
+	ScheduleInterval string       `json:"schedule_interval"`
+}
+
+const envPrefix = "EMPLOYEE_"
+
+// supported values for Config.Store
+const (
+	StoreSQL    = "sql"
+	StoreMemory = "memory"
+)
+
+func Default() Config {
+	return Config{
+		Addr:             ":8080",
+		Store:            StoreSQL,
+		ReadTimeout:      15 * time.Second,
+		WriteTimeout:     15 * time.Second,
+		IdleTimeout:      60 * time.Second,
+		ShutdownTimeout:  30 * time.Second,
+		PingTimeout:      5 * time.Second,
+		DBReadTimeout:    5 * time.Second,
+		DBWriteTimeout:   10 * time.Second,
+		PurgeRetention:   30 * 24 * time.Hour,
+		PurgeInterval:    time.Hour,
+		ScheduleInterval: time.Minute,
+		Currency:         money.DefaultCurrency,
+	}
+}
+
+// Load resolves the configuration from the given command line arguments
+// (without the program name), the environment and an optional config file
+// passed with -config or EMPLOYEE_CONFIG.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("employee", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path to a JSON config file")
+	addr := fs.String("addr", "", "listen address")
+	store := fs.String("store", "", "employee store: sql or memory")
+	dsn := fs.String("dsn", "", "database DSN")
+	readTimeout := fs.Duration("read-timeout", 0, "HTTP server read timeout")
+	writeTimeout := fs.Duration("write-timeout", 0, "HTTP server write timeout")
+	idleTimeout := fs.Duration("idle-timeout", 0, "HTTP server idle timeout")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "time allowed for in-flight requests to drain on shutdown")
+	pingTimeout := fs.Duration("ping-timeout", 0, "time allowed for the startup database ping")
+	dbReadTimeout := fs.Duration("db-read-timeout", 0, "upper bound for a single read query")
+	dbWriteTimeout := fs.Duration("db-write-timeout", 0, "upper bound for a single write statement")
+	migrate := fs.Bool("migrate", false, "apply pending schema migrations at startup")
+	positions := fs.String("positions", "", "comma separated catalogue of allowed positions")
+	maxSalary := fs.String("max-salary", "", "highest salary accepted")
+	currency := fs.String("currency", "", "ISO 4217 currency of new employees and reports")
+	rates := fs.String("rates", "", "comma separated conversion rates into -currency, like EUR=1.08")
+	cursorSecret := fs.String("cursor-secret", "", "key signing list cursors")
+	purgeRetention := fs.Duration("purge-retention", 0, "how long deleted employees are kept, 0 keeps them")
+	purgeInterval := fs.Duration("purge-interval", 0, "how often deleted employees past retention are purged")
+	scheduleInterval := fs.Duration("schedule-interval", 0, "how often scheduled changes that came due are made")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, err
+	}
+
+	if *configPath != "" {
+		err = cfg.applyFile(*configPath)
+		if err != nil {
+			return cfg, err
+		}
+	}
+
+	err = cfg.applyEnv()
+	if err != nil {
+		return cfg, err
+	}
+
+	// only flags given explicitly override file and environment values, the
+	// first invalid one is reported
+	fs.Visit(func(f *flag.Flag) {
+		var flagErr error
+
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "store":
+			cfg.Store = *store
+		case "dsn":
+			cfg.DSN = *dsn
+		case "read-timeout":
+			cfg.ReadTimeout = *readTimeout
+		case "write-timeout":
+			cfg.WriteTimeout = *writeTimeout
+		case "idle-timeout":
+			cfg.IdleTimeout = *idleTimeout
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = *shutdownTimeout
+		case "ping-timeout":
+			cfg.PingTimeout = *pingTimeout
+		case "db-read-timeout":
+			cfg.DBReadTimeout = *dbReadTimeout
+		case "db-write-timeout":
+			cfg.DBWriteTimeout = *dbWriteTimeout
+		case "migrate":
+			cfg.Migrate = *migrate
+		case "positions":
+			cfg.Positions = splitList(*positions)
+		case "max-salary":
+			flagErr = parseAmount(&cfg.MaxSalary, "-max-salary", *maxSalary)
+		case "currency":
+			cfg.Currency = strings.ToUpper(*currency)
+		case "rates":
+			cfg.Rates, flagErr = parseRates("-rates", *rates)
+		case "cursor-secret":
+			cfg.CursorSecret = *cursorSecret
+		case "purge-retention":
+			cfg.PurgeRetention = *purgeRetention
+		case "purge-interval":
+			cfg.PurgeInterval = *purgeInterval
+		case "schedule-interval":
+			cfg.ScheduleInterval = *scheduleInterval
+		}
+
+		if err == nil {
+			err = flagErr
+		}
+	})
+
+	if err != nil {
+		return cfg, err
+	}
+
+	if fs.NArg() > 0 {
+		cfg.Args = fs.Args()
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Positional returns the arguments left after the flags fs parsed from args,
+// commands use it on their own flags.
+// The flag package stops at the first argument that isn't a flag, so a flag
+// following it would be silently ignored: it is refused instead, unless a
+// "--" marked the rest as arguments.
+func Positional(fs *flag.FlagSet, args []string) ([]string, error) {
+	rest := fs.Args()
+	if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+		return rest, nil
+	}
+
+	for _, arg := range rest {
+		if len(arg) > 1 && strings.HasPrefix(arg, "-") {
+			err := fmt.Errorf("flag provided after the arguments: %s", arg)
+			fmt.Fprintln(fs.Output(), err)
+			fs.Usage()
+
+			return nil, err
+		}
+	}
+
+	return rest, nil
+}
+
+func (c Config) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("config: listen address is required")
+	}
+
+	switch c.Store {
+	case StoreMemory:
+	case StoreSQL:
+		if c.DSN == "" {
+			return fmt.Errorf("config: database DSN is required (-dsn or %sDSN)", envPrefix)
+		}
+	default:
+		return fmt.Errorf("config: unknown store %q", c.Store)
+	}
+
+	if c.MaxSalary < 0 {
+		return fmt.Errorf("config: max salary must not be negative")
+	}
+
+	if !money.Supported(c.Currency) {
+		return fmt.Errorf("config: unsupported currency %q", c.Currency)
+	}
+
+	if c.PurgeRetention < 0 {
+		return fmt.Errorf("config: purge retention must not be negative")
+	}
+
+	if c.PurgeRetention > 0 && c.PurgeInterval <= 0 {
+		return fmt.Errorf("config: purge interval must be positive")
+	}
+
+	if c.ScheduleInterval <= 0 {
+		return fmt.Errorf("config: schedule interval must be positive")
+	}
+
+	return nil
+}
+
+// Converter converts salaries into Currency with Rates.
+func (c Config) Converter() money.Converter {
+	return money.Converter{Base: c.Currency, Rates: c.Rates}
+}
+
+func (c *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: reading %s: %w", path, err)
+	}
+
+	var fc fileConfig
+	err = json.Unmarshal(data, &fc)
+	if err != nil {
+		return fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+
+	setString(&c.Addr, fc.Addr)
+	setString(&c.Store, fc.Store)
+	setString(&c.DSN, fc.DSN)
+	setString(&c.CursorSecret, fc.CursorSecret)
+
+	if fc.Migrate != nil {
+		c.Migrate = *fc.Migrate
+	}
+
+	if len(fc.Positions) > 0 {
+		c.Positions = fc.Positions
+	}
+
+	if fc.MaxSalary != 0 {
+		c.MaxSalary = fc.MaxSalary
+	}
+
+	if fc.Currency != "" {
+		c.Currency = strings.ToUpper(fc.Currency)
+	}
+
+	if len(fc.Rates) > 0 {
+		c.Rates = fc.Rates
+	}
+
+	return setDurations(map[*time.Duration]string{
+		&c.ReadTimeout:      fc.ReadTimeout,
+		&c.WriteTimeout:     fc.WriteTimeout,
+		&c.IdleTimeout:      fc.IdleTimeout,
+		&c.ShutdownTimeout:  fc.ShutdownTimeout,
+		&c.PingTimeout:      fc.PingTimeout,
+		&c.DBReadTimeout:    fc.DBReadTimeout,
+		&c.DBWriteTimeout:   fc.DBWriteTimeout,
+		&c.PurgeRetention:   fc.PurgeRetention,
+		&c.PurgeInterval:    fc.PurgeInterval,
+		&c.ScheduleInterval: fc.ScheduleInterval,
+	})
+}
+
+func (c *Config) applyEnv() error {
+	setString(&c.Addr, os.Getenv(envPrefix+"ADDR"))
+	setString(&c.Store, os.Getenv(envPrefix+"STORE"))
+	setString(&c.DSN, os.Getenv(envPrefix+"DSN"))
+	setString(&c.CursorSecret, os.Getenv(envPrefix+"CURSOR_SECRET"))
+
+	if value := os.Getenv(envPrefix + "MIGRATE"); value != "" {
+		migrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid %sMIGRATE %q: %w", envPrefix, value, err)
+		}
+
+		c.Migrate = migrate
+	}
+
+	if value := os.Getenv(envPrefix + "POSITIONS"); value != "" {
+		c.Positions = splitList(value)
+	}
+
+	if value := os.Getenv(envPrefix + "MAX_SALARY"); value != "" {
+		err := parseAmount(&c.MaxSalary, envPrefix+"MAX_SALARY", value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if value := os.Getenv(envPrefix + "CURRENCY"); value != "" {
+		c.Currency = strings.ToUpper(value)
+	}
+
+	if value := os.Getenv(envPrefix + "RATES"); value != "" {
+		rates, err := parseRates(envPrefix+"RATES", value)
+		if err != nil {
+			return err
+		}
+
+		c.Rates = rates
+	}
+
+	return setDurations(map[*time.Duration]string{
+		&c.ReadTimeout:      os.Getenv(envPrefix + "READ_TIMEOUT"),
+		&c.WriteTimeout:     os.Getenv(envPrefix + "WRITE_TIMEOUT"),
+		&c.IdleTimeout:      os.Getenv(envPrefix + "IDLE_TIMEOUT"),
+		&c.ShutdownTimeout:  os.Getenv(envPrefix + "SHUTDOWN_TIMEOUT"),
+		&c.PingTimeout:      os.Getenv(envPrefix + "PING_TIMEOUT"),
+		&c.DBReadTimeout:    os.Getenv(envPrefix + "DB_READ_TIMEOUT"),
+		&c.DBWriteTimeout:   os.Getenv(envPrefix + "DB_WRITE_TIMEOUT"),
+		&c.PurgeRetention:   os.Getenv(envPrefix + "PURGE_RETENTION"),
+		&c.PurgeInterval:    os.Getenv(envPrefix + "PURGE_INTERVAL"),
+		&c.ScheduleInterval: os.Getenv(envPrefix + "SCHEDULE_INTERVAL"),
+	})
+}
+
+func setDurations(values map[*time.Duration]string) error {
+	for dst, value := range values {
+		if value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("config: invalid duration %q: %w", value, err)
+		}
+
+		*dst = d
+	}
+
+	return nil
+}
+
+func parseAmount(dst *money.Amount, name, value string) error {
+	amount, err := money.Parse(value)
+	if err != nil {
+		return fmt.Errorf("config: invalid %s %q: %w", name, value, err)
+	}
+
+	*dst = amount
+
+	return nil
+}
+
+func parseRates(name, value string) (money.Rates, error) {
+	rates, err := money.ParseRates(value)
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid %s: %w", name, err)
+	}
+
+	return rates, nil
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func setString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
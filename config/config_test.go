@@ -0,0 +1,232 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	err := os.WriteFile(path, []byte(`{"addr": ":9000", "dsn": "file-dsn", "read_timeout": "3s", "idle_timeout": "90s"}`), 0o600)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected Config
+		wantErr  bool
+	}{
+		{
+			name: "Defaults with dsn flag",
+			args: []string{"-dsn", "flag-dsn"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "flag-dsn"
+				return c
+			}(),
+		},
+		{
+			name: "Config file values",
+			args: []string{"-config", path},
+			expected: func() Config {
+				c := Default()
+				c.Addr = ":9000"
+				c.DSN = "file-dsn"
+				c.ReadTimeout = 3 * time.Second
+				c.IdleTimeout = 90 * time.Second
+				return c
+			}(),
+		},
+		{
+			name: "Env overrides file and flags override env",
+			args: []string{"-config", path, "-addr", ":7000"},
+			env:  map[string]string{"EMPLOYEE_ADDR": ":8000", "EMPLOYEE_DSN": "env-dsn", "EMPLOYEE_WRITE_TIMEOUT": "7s"},
+			expected: func() Config {
+				c := Default()
+				c.Addr = ":7000"
+				c.DSN = "env-dsn"
+				c.ReadTimeout = 3 * time.Second
+				c.WriteTimeout = 7 * time.Second
+				c.IdleTimeout = 90 * time.Second
+				return c
+			}(),
+		},
+		{
+			name: "Memory store needs no dsn",
+			args: []string{"-store", "memory"},
+			expected: func() Config {
+				c := Default()
+				c.Store = StoreMemory
+				return c
+			}(),
+		},
+		{
+			name: "Positional args, migrate and cursor secret env",
+			args: []string{"-dsn", "x", "down", "2"},
+			env:  map[string]string{"EMPLOYEE_MIGRATE": "true", "EMPLOYEE_CURSOR_SECRET": "s3cret"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "x"
+				c.Migrate = true
+				c.CursorSecret = "s3cret"
+				c.Args = []string{"down", "2"}
+				return c
+			}(),
+		},
+		{
+			name: "Command flags stay in the args",
+			args: []string{"-dsn", "x", "data.csv", "-dry-run"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "x"
+				c.Args = []string{"data.csv", "-dry-run"}
+				return c
+			}(),
+		},
+		{
+			name: "Validation settings",
+			args: []string{"-dsn", "x", "-positions", "SDE, QA,,PM", "-max-salary", "50000"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "x"
+				c.Positions = []string{"SDE", "QA", "PM"}
+				c.MaxSalary = money.Major(50000)
+				return c
+			}(),
+		},
+		{
+			name: "Currency and rates",
+			args: []string{"-dsn", "x", "-currency", "eur"},
+			env:  map[string]string{"EMPLOYEE_RATES": "USD=0.92, gbp=1.17"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "x"
+				c.Currency = "EUR"
+				c.Rates = money.Rates{"USD": big.NewRat(92, 100), "GBP": big.NewRat(117, 100)}
+				return c
+			}(),
+		},
+		{
+			name:    "Unsupported currency",
+			args:    []string{"-dsn", "x", "-currency", "XBT"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid rates",
+			args:    []string{"-dsn", "x", "-rates", "EUR=-1"},
+			wantErr: true,
+		},
+		{
+			name: "Purge settings",
+			args: []string{"-dsn", "x", "-purge-interval", "10m"},
+			env:  map[string]string{"EMPLOYEE_PURGE_RETENTION": "168h"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "x"
+				c.PurgeRetention = 7 * 24 * time.Hour
+				c.PurgeInterval = 10 * time.Minute
+				return c
+			}(),
+		},
+		{
+			name:    "Purge without interval",
+			args:    []string{"-dsn", "x", "-purge-interval", "0s"},
+			wantErr: true,
+		},
+		{
+			name: "Schedule interval",
+			env:  map[string]string{"EMPLOYEE_DSN": "x", "EMPLOYEE_SCHEDULE_INTERVAL": "30s"},
+			expected: func() Config {
+				c := Default()
+				c.DSN = "x"
+				c.ScheduleInterval = 30 * time.Second
+				return c
+			}(),
+		},
+		{
+			name:    "Schedule without interval",
+			args:    []string{"-dsn", "x", "-schedule-interval", "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown store",
+			args:    []string{"-store", "redis"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing dsn",
+			args:    []string{},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid env duration",
+			args:    []string{"-dsn", "x"},
+			env:     map[string]string{"EMPLOYEE_READ_TIMEOUT": "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing config file",
+			args:    []string{"-config", filepath.Join(dir, "missing.json")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load(tc.args)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg)
+		})
+	}
+}
+
+func TestPositional(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "Flags then arguments", args: []string{"-v", "a", "b"}, expected: []string{"a", "b"}},
+		{name: "Flag after an argument", args: []string{"a", "-v"}, wantErr: true},
+		{name: "Dash is an argument", args: []string{"a", "-"}, expected: []string{"a", "-"}},
+		{name: "Arguments after a terminator", args: []string{"-v", "--", "a", "-v"}, expected: []string{"a", "-v"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fs.Bool("v", false, "verbose")
+			assert.NoError(t, fs.Parse(tc.args))
+
+			rest, err := Positional(fs, tc.args)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, rest)
+		})
+	}
+}
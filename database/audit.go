@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"example.com/m/Assesment/audit"
+)
+
+// record appends the entry to the audit log, in the transaction of the
+// change it describes.
+func (d Database) record(ctx context.Context, entry audit.Entry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.conn().ExecContext(ctx, d.rebind(AuditInsertQuery),
+		entry.EmployeeID, entry.Operation, entry.Actor, entry.RequestID, entry.At, entry.Version, string(changes))
+
+	return d.translate(err)
+}
+
+// AuditLog reads the entries selected by q, newest first.
+func (d Database) AuditLog(ctx context.Context, q audit.Query) ([]audit.Entry, error) {
+	err := q.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	where, args := auditWhere(q)
+	query := AuditQuery + where + " order by id desc limit ?"
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), append(args, q.Limit)...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var entries []audit.Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, d.translate(rows.Err())
+}
+
+// auditWhere renders the conditions of the query, times compared in UTC like
+// they are stored.
+func auditWhere(q audit.Query) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if q.EmployeeID != 0 {
+		conditions = append(conditions, "employee_id = ?")
+		args = append(args, q.EmployeeID)
+	}
+
+	if q.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, q.Actor)
+	}
+
+	if len(q.Operations) > 0 {
+		conditions = append(conditions, "operation in ("+strings.TrimSuffix(strings.Repeat("?, ", len(q.Operations)), ", ")+")")
+		for _, operation := range q.Operations {
+			args = append(args, operation)
+		}
+	}
+
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "changed_at >= ?")
+		args = append(args, q.Since.UTC())
+	}
+
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "changed_at < ?")
+		args = append(args, q.Until.UTC())
+	}
+
+	if q.Before != 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, q.Before)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " where " + strings.Join(conditions, " and "), args
+}
+
+func scanEntry(s scanner) (audit.Entry, error) {
+	var entry audit.Entry
+	var at time.Time
+	var changes string
+
+	err := s.Scan(&entry.ID, &entry.EmployeeID, &entry.Operation, &entry.Actor, &entry.RequestID, &at, &entry.Version, &changes)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.At = at.UTC()
+
+	return entry, json.Unmarshal([]byte(changes), &entry.Changes)
+}
+
+func (s *memoryState) record(entry audit.Entry) {
+	s.lastAuditID++
+	entry.ID = s.lastAuditID
+	s.audit = append(s.audit, entry)
+}
+
+func (s *memoryState) auditLog(q audit.Query) ([]audit.Entry, error) {
+	err := q.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []audit.Entry
+	for i := len(s.audit) - 1; i >= 0 && len(entries) < q.Limit; i-- {
+		entry := s.audit[i]
+		if (q.Before == 0 || entry.ID < q.Before) && q.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
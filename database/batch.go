@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+)
+
+// ItemError is the failure of the item at Index of a batch.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// chunkSize is the number of rows per multi-row statement, keeping its
+// placeholders well under every driver's limit.
+const chunkSize = 100
+
+// CreateMany inserts the employees in a transaction with multi-row inserts.
+// Generated ids are read back with "returning" where the dialect can,
+// otherwise they are taken as consecutive from the first one, which MySQL
+// only promises with some settings: without them the rows are inserted one
+// by one. Every creation is recorded in the audit log.
+func (d Database) CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	if len(employees) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	ids := make([]int64, 0, len(employees))
+
+	err := d.inTx(ctx, func(tx Database) error {
+		err := tx.checkDepartments(ctx, employees)
+		if err != nil {
+			return err
+		}
+
+		err = tx.lockManagers(ctx, employees)
+		if err != nil {
+			return err
+		}
+
+		consecutive, err := tx.consecutiveIDs(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !consecutive {
+			for _, employee := range employees {
+				id, err := tx.create(ctx, employee)
+				if err != nil {
+					return err
+				}
+
+				ids = append(ids, id)
+			}
+
+			return nil
+		}
+
+		for start := 0; start < len(employees); start += chunkSize {
+			chunk := employees[start:min(start+chunkSize, len(employees))]
+
+			chunkIDs, err := tx.insertChunk(ctx, chunk)
+			if err != nil {
+				return err
+			}
+
+			for n, id := range chunkIDs {
+				stored := created(chunk[n], id)
+
+				err = tx.changed(ctx, audit.OpCreate, nil, &stored)
+				if err != nil {
+					return err
+				}
+			}
+
+			ids = append(ids, chunkIDs...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (d Database) insertChunk(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	rowsSQL := make([]string, 0, len(employees))
+	args := make([]interface{}, 0, 6*len(employees))
+
+	for _, employee := range employees {
+		rowsSQL = append(rowsSQL, createManyRow)
+		args = append(args, employee.Name, employee.Position, employee.Salary, employee.Currency, nullableID(employee.DepartmentID), nullableID(employee.ManagerID))
+	}
+
+	query := CreateManyQuery + strings.Join(rowsSQL, ", ")
+	if !d.dialect().Returning() {
+		return d.insertConsecutive(ctx, query, args, len(employees))
+	}
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query+" returning id"), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	if len(ids) != len(employees) {
+		return nil, fmt.Errorf("inserted %d employees, read back %d ids", len(employees), len(ids))
+	}
+
+	// rows get their ids in the order of the values list, but "returning"
+	// doesn't promise to list them in that order
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+// consecutiveIDs reports whether the rows of a multi-row insert get
+// consecutive ids, always true where they are read back with "returning".
+// MySQL promises it when innodb_autoinc_lock_mode is 0 or 1, not with the
+// interleaved mode 2 that MySQL 8 defaults to, and auto_increment_increment
+// is 1.
+func (d Database) consecutiveIDs(ctx context.Context) (bool, error) {
+	if d.dialect().Returning() {
+		return true, nil
+	}
+
+	var lockMode, increment int64
+
+	err := d.conn().QueryRowContext(ctx, AutoIncrementQuery).Scan(&lockMode, &increment)
+	if err != nil {
+		return false, d.translate(err)
+	}
+
+	return lockMode <= 1 && increment == 1, nil
+}
+
+// insertConsecutive runs a multi-row insert of n employees where the driver
+// only reports the first id generated, the others following it as
+// consecutiveIDs checked.
+func (d Database) insertConsecutive(ctx context.Context, query string, args []interface{}, n int) ([]int64, error) {
+	result, err := d.conn().ExecContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if affected != int64(n) {
+		return nil, fmt.Errorf("inserted %d employees, wrote %d rows", n, affected)
+	}
+
+	first, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = first + int64(i)
+	}
+
+	return ids, nil
+}
+
+// DeleteMany marks the employees deleted with a single statement in a
+// transaction, after reading and locking them all. Employees others still
+// report to fail with ErrHasReports unless those are deleted too.
+func (d Database) DeleteMany(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := checkDistinct(ids)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	list, args := idList(ids)
+
+	return d.inTx(ctx, func(tx Database) error {
+		existing, err := tx.getManyForUpdate(ctx, ExistingQuery+list, args...)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[int64]models.Employee, len(existing))
+		for _, employee := range existing {
+			current[employee.ID] = employee
+		}
+
+		for i, id := range ids {
+			if _, ok := current[id]; !ok {
+				return &ItemError{Index: i, Err: ErrNotFound}
+			}
+		}
+
+		// the employees may report to one another, not others to them
+		managing, err := tx.managing(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		if managing >= 0 {
+			return &ItemError{Index: managing, Err: ErrHasReports}
+		}
+
+		at := deletionTime()
+		result, err := tx.conn().ExecContext(ctx, tx.rebind(DeleteManyQuery+list), append([]interface{}{at}, args...)...)
+		if err != nil {
+			return tx.translate(err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		// another transaction deleted some of them after the check
+		if affected != int64(len(ids)) {
+			return ErrConflict
+		}
+
+		for _, id := range ids {
+			before := current[id]
+			deleted := markedDeleted(before, at)
+
+			err = tx.changed(ctx, audit.OpDelete, &before, &deleted)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// getManyForUpdate reads the employees selected by query, locking their rows
+// until the transaction ends where the dialect can.
+func (d Database) getManyForUpdate(ctx context.Context, query string, args ...interface{}) ([]models.Employee, error) {
+	if d.dialect().LockRows() {
+		query = query + lockClause
+	}
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		employee, err := scanEmployee(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		employees = append(employees, employee)
+	}
+
+	return employees, d.translate(rows.Err())
+}
+
+// idList renders "(?, ?, ...)" for the ids with their arguments.
+func idList(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")", args
+}
+
+// checkDistinct reports the first id listed twice.
+func checkDistinct(ids []int64) error {
+	seen := make(map[int64]bool, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			return &ItemError{Index: i, Err: ErrDuplicate}
+		}
+
+		seen[id] = true
+	}
+
+	return nil
+}
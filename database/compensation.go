@@ -0,0 +1,476 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+)
+
+// groupings of CompensationReport
+const (
+	ReportByPosition = "position"
+	ReportByReason   = "reason"
+)
+
+// ReportOptions select the approved compensation changes a report
+// aggregates, grouped By position or reason. Since and Until bound their
+// effective time, Since included, zero values match everything.
+type ReportOptions struct {
+	By    string
+	Since time.Time
+	Until time.Time
+}
+
+// ProposeCompensation stores a proposed salary change, from the employee's
+// current salary and position read and locked in the same transaction.
+func (d Database) ProposeCompensation(ctx context.Context, change models.CompensationChange) (models.CompensationChange, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	err := d.inTx(ctx, func(tx Database) error {
+		current, err := tx.getForUpdate(ctx, GetQuery, change.EmployeeID)
+		if err != nil {
+			return err
+		}
+
+		err = checkPrecision(current, raise(change))
+		if err != nil {
+			return err
+		}
+
+		change = proposed(ctx, change, current)
+		change.ID, err = tx.insertID(ctx, CompensationInsertQuery, change.EmployeeID, change.Position, change.CurrentSalary,
+			change.Salary, change.Currency, change.Reason, change.EffectiveAt, change.Status, change.ProposedBy, change.ProposedAt, change.Note)
+
+		return err
+	})
+
+	return change, err
+}
+
+// proposed is the change as proposed by the caller of ctx for the employee
+// as it is now.
+func proposed(ctx context.Context, change models.CompensationChange, current models.Employee) models.CompensationChange {
+	change.Position = current.Position
+	change.CurrentSalary = current.Salary
+	change.Currency = current.Currency
+	change.EffectiveAt = scheduleTime(change.EffectiveAt)
+	change.Status = models.StatusProposed
+	change.ProposedBy = audit.CallerFrom(ctx).Actor
+	change.ProposedAt = audit.Now()
+
+	return change
+}
+
+func (d Database) Compensation(ctx context.Context, id int64, status string) ([]models.CompensationChange, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	query := CompensationQuery + " where employee_id = ?"
+	args := []interface{}{id}
+
+	if status != "" {
+		query += " and status = ?"
+		args = append(args, status)
+	}
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query+" order by id desc"), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var changes []models.CompensationChange
+	for rows.Next() {
+		change, err := scanCompensation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, d.translate(rows.Err())
+}
+
+func scanCompensation(s scanner) (models.CompensationChange, error) {
+	var change models.CompensationChange
+	var decidedAt sql.NullTime
+	var scheduleID sql.NullInt64
+
+	err := s.Scan(&change.ID, &change.EmployeeID, &change.Position, &change.CurrentSalary, &change.Salary, &change.Currency, &change.Reason,
+		&change.EffectiveAt, &change.Status, &change.ProposedBy, &change.ProposedAt, &change.DecidedBy, &decidedAt, &scheduleID, &change.Note)
+	change.EffectiveAt = change.EffectiveAt.UTC()
+	change.ProposedAt = change.ProposedAt.UTC()
+
+	if decidedAt.Valid {
+		t := decidedAt.Time.UTC()
+		change.DecidedAt = &t
+	}
+
+	if scheduleID.Valid {
+		change.ScheduleID = &scheduleID.Int64
+	}
+
+	return change, err
+}
+
+// ApproveCompensation approves the proposed change as the caller of ctx in
+// one transaction, with the proposal and the employee locked. A change
+// effective by now is made at once, valid from its effective time where the
+// history allows, and a later one is scheduled, linked to the change so it
+// can't be cancelled on its own.
+func (d Database) ApproveCompensation(ctx context.Context, id, changeID int64, now time.Time) (models.CompensationChange, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	var change models.CompensationChange
+
+	err := d.inTx(ctx, func(tx Database) error {
+		var err error
+		change, err = tx.getProposal(ctx, id, changeID)
+		if err != nil {
+			return err
+		}
+
+		current, err := tx.getForUpdate(ctx, GetQuery, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkApprovable(ctx, change, current)
+		if err != nil {
+			return err
+		}
+
+		if change.EffectiveAt.After(now) {
+			scheduleID, err := tx.insertScheduled(ctx, salaryChange(ctx, change))
+			if err != nil {
+				return err
+			}
+
+			change.ScheduleID = &scheduleID
+		}
+
+		change, err = tx.decide(ctx, change, models.StatusApproved)
+		if err != nil || change.ScheduleID != nil {
+			return err
+		}
+
+		_, err = tx.updateAt(ctx, current, raise(change), change.EffectiveAt)
+
+		return err
+	})
+
+	return change, err
+}
+
+// RejectCompensation rejects the proposed change as the caller of ctx.
+func (d Database) RejectCompensation(ctx context.Context, id, changeID int64) (models.CompensationChange, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	var change models.CompensationChange
+
+	err := d.inTx(ctx, func(tx Database) error {
+		var err error
+		change, err = tx.getProposal(ctx, id, changeID)
+		if err != nil {
+			return err
+		}
+
+		if change.Status != models.StatusProposed {
+			return ErrDecided
+		}
+
+		change, err = tx.decide(ctx, change, models.StatusRejected)
+
+		return err
+	})
+
+	return change, err
+}
+
+// getProposal reads the change of the employee, locked until the transaction
+// ends where the dialect can.
+func (d Database) getProposal(ctx context.Context, id, changeID int64) (models.CompensationChange, error) {
+	query := CompensationQuery + " where id = ? and employee_id = ?"
+	if d.dialect().LockRows() {
+		query = query + lockClause
+	}
+
+	change, err := scanCompensation(d.conn().QueryRowContext(ctx, d.rebind(query), changeID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return change, ErrProposalNotFound
+	}
+
+	return change, d.translate(err)
+}
+
+// decide records the decision of the caller of ctx on the proposed change.
+func (d Database) decide(ctx context.Context, change models.CompensationChange, status string) (models.CompensationChange, error) {
+	change = decided(ctx, change, status)
+
+	err := d.execOne(ctx, CompensationDecideQuery, change.Status, change.DecidedBy, *change.DecidedAt,
+		nullInt(change.ScheduleID), change.ID, models.StatusProposed)
+
+	return change, err
+}
+
+// checkApprovable reports why the caller of ctx can't approve the change of
+// the employee as it is now.
+func checkApprovable(ctx context.Context, change models.CompensationChange, current models.Employee) error {
+	if change.Status != models.StatusProposed {
+		return ErrDecided
+	}
+
+	if audit.CallerFrom(ctx).Actor == change.ProposedBy {
+		return ErrSelfApproval
+	}
+
+	if current.Salary != change.CurrentSalary || current.Currency != change.Currency {
+		return ErrStaleProposal
+	}
+
+	return nil
+}
+
+func decided(ctx context.Context, change models.CompensationChange, status string) models.CompensationChange {
+	at := audit.Now()
+
+	change.Status = status
+	change.DecidedBy = audit.CallerFrom(ctx).Actor
+	change.DecidedAt = &at
+
+	return change
+}
+
+// raise is the employee change making the compensation change.
+func raise(change models.CompensationChange) models.EmployeeChanges {
+	salary := change.Salary
+	return models.EmployeeChanges{Salary: &salary}
+}
+
+// salaryChange is the compensation change scheduled by its approver, the
+// caller of ctx.
+func salaryChange(ctx context.Context, change models.CompensationChange) models.ScheduledChange {
+	caller := audit.CallerFrom(ctx)
+
+	return models.ScheduledChange{
+		EmployeeID:  change.EmployeeID,
+		EffectiveAt: change.EffectiveAt,
+		Changes:     raise(change),
+		Actor:       caller.Actor,
+		RequestID:   caller.RequestID,
+	}
+}
+
+// reportColumn is the column a report groups by.
+func reportColumn(by string) (string, error) {
+	switch by {
+	case ReportByPosition, "":
+		return "position", nil
+	case ReportByReason:
+		return "reason", nil
+	}
+
+	return "", fmt.Errorf("unknown compensation report grouping %q", by)
+}
+
+// CompensationReport totals the approved changes selected by opts by group
+// and currency, in that order.
+func (d Database) CompensationReport(ctx context.Context, opts ReportOptions) ([]models.RaiseSummary, error) {
+	column, err := reportColumn(opts.By)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	query := fmt.Sprintf(CompensationReportQuery, column) + " where status = ?"
+	args := []interface{}{models.StatusApproved}
+
+	if !opts.Since.IsZero() {
+		query += " and effective_at >= ?"
+		args = append(args, opts.Since.UTC())
+	}
+
+	if !opts.Until.IsZero() {
+		query += " and effective_at < ?"
+		args = append(args, opts.Until.UTC())
+	}
+
+	query += " group by " + column + ", currency order by " + column + ", currency"
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var summaries []models.RaiseSummary
+	for rows.Next() {
+		var summary models.RaiseSummary
+		var totalPercent sql.NullFloat64
+
+		err = rows.Scan(&summary.Group, &summary.Currency, &summary.Changes, &summary.TotalRaise, &summary.Percents, &totalPercent)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.TotalPercent = totalPercent.Float64
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, d.translate(rows.Err())
+}
+
+func (s *memoryState) proposeCompensation(ctx context.Context, change models.CompensationChange) (models.CompensationChange, error) {
+	current, err := s.get(change.EmployeeID)
+	if err != nil {
+		return change, err
+	}
+
+	err = checkPrecision(current, raise(change))
+	if err != nil {
+		return change, err
+	}
+
+	s.lastCompensationID++
+	change = proposed(ctx, change, current)
+	change.ID = s.lastCompensationID
+	s.compensation = append(s.compensation, change)
+
+	return change, nil
+}
+
+func (s *memoryState) compensationOf(id int64, status string) []models.CompensationChange {
+	var changes []models.CompensationChange
+	for i := len(s.compensation) - 1; i >= 0; i-- {
+		change := s.compensation[i]
+		if change.EmployeeID == id && (status == "" || change.Status == status) {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// proposal is the index of the change of the employee in s.compensation.
+func (s *memoryState) proposal(id, changeID int64) (int, error) {
+	for i, change := range s.compensation {
+		if change.ID == changeID && change.EmployeeID == id {
+			return i, nil
+		}
+	}
+
+	return 0, ErrProposalNotFound
+}
+
+func (s *memoryState) approveCompensation(ctx context.Context, id, changeID int64, now time.Time) (models.CompensationChange, error) {
+	i, err := s.proposal(id, changeID)
+	if err != nil {
+		return models.CompensationChange{}, err
+	}
+
+	change := s.compensation[i]
+
+	current, err := s.get(id)
+	if err != nil {
+		return change, err
+	}
+
+	err = checkApprovable(ctx, change, current)
+	if err != nil {
+		return change, err
+	}
+
+	change = decided(ctx, change, models.StatusApproved)
+
+	if change.EffectiveAt.After(now) {
+		scheduled, err := s.schedule(ctx, salaryChange(ctx, change))
+		if err != nil {
+			return change, err
+		}
+
+		change.ScheduleID = &scheduled.ID
+	} else {
+		s.updateAt(ctx, current, raise(change), change.EffectiveAt)
+	}
+
+	s.compensation[i] = change
+
+	return change, nil
+}
+
+func (s *memoryState) rejectCompensation(ctx context.Context, id, changeID int64) (models.CompensationChange, error) {
+	i, err := s.proposal(id, changeID)
+	if err != nil {
+		return models.CompensationChange{}, err
+	}
+
+	if s.compensation[i].Status != models.StatusProposed {
+		return s.compensation[i], ErrDecided
+	}
+
+	s.compensation[i] = decided(ctx, s.compensation[i], models.StatusRejected)
+
+	return s.compensation[i], nil
+}
+
+func (s *memoryState) compensationReport(opts ReportOptions) ([]models.RaiseSummary, error) {
+	column, err := reportColumn(opts.By)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []models.RaiseSummary
+	for _, change := range s.compensation {
+		if change.Status != models.StatusApproved ||
+			(!opts.Since.IsZero() && change.EffectiveAt.Before(opts.Since)) ||
+			(!opts.Until.IsZero() && !change.EffectiveAt.Before(opts.Until)) {
+			continue
+		}
+
+		group := change.Position
+		if column == "reason" {
+			group = change.Reason
+		}
+
+		i := 0
+		for i < len(summaries) && (summaries[i].Group != group || summaries[i].Currency != change.Currency) {
+			i++
+		}
+
+		if i == len(summaries) {
+			summaries = append(summaries, models.RaiseSummary{Group: group, Currency: change.Currency})
+		}
+
+		summaries[i].Changes++
+		summaries[i].TotalRaise += change.Salary - change.CurrentSalary
+
+		if percent, ok := change.PercentChange(); ok {
+			summaries[i].Percents++
+			summaries[i].TotalPercent += percent
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Group != summaries[j].Group {
+			return summaries[i].Group < summaries[j].Group
+		}
+
+		return summaries[i].Currency < summaries[j].Currency
+	})
+
+	return summaries, nil
+}
@@ -0,0 +1,1868 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// conformance fixtures shared by every implementation of Employee
+var (
+	john = models.Employee{Name: "John Doe", Position: "SDE", Salary: money.Major(30000), Currency: "USD"}
+	jane = models.Employee{Name: "Jane Roe", Position: "QA", Salary: money.Major(40000), Currency: "USD"}
+	jim  = models.Employee{Name: "Jim Poe", Position: "PM", Salary: money.Major(50000), Currency: "EUR"}
+
+	// caller makes every change of the scenario
+	caller = audit.Caller{Actor: "alice", RequestID: "req-1"}
+
+	// raiseAt is when the scheduled raise takes effect
+	raiseAt = time.Date(2100, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// approver decides the compensation changes caller proposes, effective
+	// from meritAt unless scheduled
+	approver = audit.Caller{Actor: "bob", RequestID: "req-2"}
+	meritAt  = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// withID is e as freshly stored under id.
+func withID(e models.Employee, id int64) models.Employee {
+	e.ID = id
+	e.Version = models.InitialVersion
+	return e
+}
+
+// withVersion is e after its version moved on to version.
+func withVersion(e models.Employee, version int64) models.Employee {
+	e.Version = version
+	return e
+}
+
+// testConformance runs the same scenario against any Store implementation
+// starting from an empty store.
+func testConformance(t *testing.T, store Store) {
+	ctx := audit.WithCaller(context.Background(), caller)
+
+	t.Run("Create assigns increasing ids", func(t *testing.T) {
+		for i, e := range []models.Employee{john, jane, jim} {
+			id, err := store.Create(ctx, e)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(i+1), id)
+		}
+	})
+
+	t.Run("Get returns the stored employee", func(t *testing.T) {
+		resp, err := store.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, withID(john, 1), resp)
+	})
+
+	t.Run("Update only changes provided fields", func(t *testing.T) {
+		expected := withVersion(withID(john, 1), 2)
+		expected.Position = "SDE-2"
+
+		// the written employee comes back with the update
+		resp, err := store.Update(ctx, 1, models.EmployeeChanges{Position: ptr("SDE-2")})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, resp)
+
+		resp, err = store.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, resp)
+	})
+
+	t.Run("GetAll pages by offset in id order", func(t *testing.T) {
+		page, err := store.GetAll(ctx, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, ids(page))
+
+		page, err = store.GetAll(ctx, 2, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(page))
+
+		page, err = store.GetAll(ctx, 3, 2)
+		assert.NoError(t, err)
+		assert.Empty(t, page)
+	})
+
+	t.Run("List pages by keyset in both directions", func(t *testing.T) {
+		page, err := store.List(ctx, ListOptions{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, ids(page.Employees))
+		assert.True(t, page.More)
+		assert.Nil(t, page.Total)
+
+		page, err = store.List(ctx, ListOptions{After: &Keyset{ID: 2}, Limit: 2, Count: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(page.Employees))
+		assert.False(t, page.More)
+		assert.Equal(t, ptr(int64(3)), page.Total)
+
+		page, err = store.List(ctx, ListOptions{Before: &Keyset{ID: 3}, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2}, ids(page.Employees))
+		assert.True(t, page.More)
+
+		_, err = store.List(ctx, ListOptions{After: &Keyset{ID: 1}, Before: &Keyset{ID: 3}, Limit: 1})
+		assert.ErrorIs(t, err, ErrInvalidListOptions)
+
+		_, err = store.List(ctx, ListOptions{Sort: []SortKey{{Field: "version"}}, Limit: 1})
+		assert.ErrorIs(t, err, ErrInvalidListOptions)
+	})
+
+	t.Run("List filters and sorts", func(t *testing.T) {
+		bySalary := []SortKey{{Field: "salary", Desc: true}}
+		dollars := Filter{Currencies: []string{"USD"}}
+
+		page, err := store.List(ctx, ListOptions{Filter: dollars, Sort: bySalary, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2}, ids(page.Employees))
+		assert.True(t, page.More)
+
+		// the keyset carries the sort values of the last employee
+		page, err = store.List(ctx, ListOptions{Filter: dollars, Sort: bySalary, After: ptr(KeysetOf(page.Employees[0])), Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1}, ids(page.Employees))
+		assert.False(t, page.More)
+
+		page, err = store.List(ctx, ListOptions{Filter: Filter{Positions: []string{"qa", "pm"}, Currencies: []string{"EUR"}, MinSalary: ptr(money.Major(45000))}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(page.Employees))
+
+		// amounts of different currencies don't compare
+		_, err = store.List(ctx, ListOptions{Sort: bySalary, Limit: 10})
+		assert.ErrorIs(t, err, ErrInvalidListOptions)
+
+		_, err = store.List(ctx, ListOptions{Filter: Filter{MinSalary: ptr(money.Major(45000))}, Limit: 10})
+		assert.ErrorIs(t, err, ErrInvalidListOptions)
+
+		page, err = store.List(ctx, ListOptions{Filter: Filter{Currencies: []string{"EUR"}}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(page.Employees))
+
+		page, err = store.List(ctx, ListOptions{Filter: Filter{NamePrefix: "J", NameContains: "o"}, Sort: []SortKey{{Field: "name"}}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2, 3, 1}, ids(page.Employees))
+
+		// like wildcards in a filter are literal
+		page, err = store.List(ctx, ListOptions{Filter: Filter{NameContains: "%"}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Employees)
+	})
+
+	t.Run("Delete removes the employee", func(t *testing.T) {
+		err := store.Delete(ctx, 2, 0)
+		assert.NoError(t, err)
+
+		page, err := store.GetAll(ctx, 1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 3}, ids(page))
+	})
+
+	t.Run("Missing employee is reported as not found", func(t *testing.T) {
+		_, err := store.Get(ctx, 2)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		_, err = store.Update(ctx, 2, models.EmployeeChanges{Name: ptr("Nobody")})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		_, err = store.Update(ctx, 2, models.EmployeeChanges{})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		err = store.Delete(ctx, 2, 0)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Update writes zero values that are present", func(t *testing.T) {
+		cleared := models.Employee{ID: 3, Name: jim.Name, Currency: jim.Currency, Version: 2}
+
+		resp, err := store.Update(ctx, 3, models.EmployeeChanges{Position: ptr(""), Salary: ptr(money.Amount(0))})
+		assert.NoError(t, err)
+		assert.Equal(t, cleared, resp)
+
+		resp, err = store.Get(ctx, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, cleared, resp)
+
+		// an empty change set only checks the employee exists
+		resp, err = store.Update(ctx, 3, models.EmployeeChanges{})
+		assert.NoError(t, err)
+		assert.Equal(t, cleared, resp)
+	})
+
+	t.Run("Conditional writes compare the version", func(t *testing.T) {
+		// employee 1 is at version 2 after the earlier update
+		_, err := store.Update(ctx, 1, models.EmployeeChanges{Name: ptr("Johnny"), IfVersion: 1})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+		assert.ErrorIs(t, err, ErrConflict)
+
+		resp, err := store.Update(ctx, 1, models.EmployeeChanges{Name: ptr("Johnny"), IfVersion: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), resp.Version)
+
+		_, err = store.Update(ctx, 1, models.EmployeeChanges{IfVersion: 2})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		err = store.Delete(ctx, 1, 2)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		err = store.Delete(ctx, 1, 3)
+		assert.NoError(t, err)
+
+		_, err = store.Get(ctx, 1)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Transactions commit or roll back as a whole", func(t *testing.T) {
+		var id int64
+		err := store.WithTx(ctx, func(tx Employee) error {
+			var err error
+			id, err = tx.Create(ctx, jane)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), id)
+
+		resp, err := store.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, withID(jane, id), resp)
+
+		errRollback := errors.New("rollback")
+		err = store.WithTx(ctx, func(tx Employee) error {
+			// a nested call joins the transaction and is rolled back with it
+			return tx.WithTx(ctx, func(tx Employee) error {
+				_, err := tx.Update(ctx, 3, models.EmployeeChanges{Name: ptr("Rolled back")})
+				assert.NoError(t, err)
+
+				return errRollback
+			})
+		})
+		assert.ErrorIs(t, err, errRollback)
+
+		resp, err = store.Get(ctx, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, jim.Name, resp.Name)
+	})
+
+	t.Run("Export streams every matching employee in order", func(t *testing.T) {
+		var exported []models.Employee
+		opts := ExportOptions{Sort: []SortKey{{Field: "position"}}}
+
+		err := store.Export(ctx, opts, func(e models.Employee) error {
+			exported = append(exported, e)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3, 4}, ids(exported))
+
+		errStop := errors.New("stop")
+		err = store.Export(ctx, opts, func(e models.Employee) error { return errStop })
+		assert.ErrorIs(t, err, errStop)
+
+		err = store.Export(ctx, ExportOptions{Sort: []SortKey{{Field: "version"}}}, func(e models.Employee) error { return nil })
+		assert.ErrorIs(t, err, ErrInvalidListOptions)
+
+		err = store.Export(ctx, ExportOptions{Sort: []SortKey{{Field: "salary"}}}, func(e models.Employee) error { return nil })
+		assert.ErrorIs(t, err, ErrInvalidListOptions)
+	})
+
+	t.Run("Batches write all or nothing", func(t *testing.T) {
+		created, err := store.CreateMany(ctx, []models.Employee{john, jim})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{5, 6}, created)
+
+		resp, err := store.Get(ctx, 6)
+		assert.NoError(t, err)
+		assert.Equal(t, withID(jim, 6), resp)
+
+		var itemErr *ItemError
+		err = store.DeleteMany(ctx, []int64{5, 99})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 1, itemErr.Index)
+
+		err = store.DeleteMany(ctx, []int64{5, 5})
+		assert.ErrorIs(t, err, ErrDuplicate)
+
+		err = store.DeleteMany(ctx, []int64{5, 6})
+		assert.NoError(t, err)
+
+		_, err = store.Get(ctx, 5)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Deleted employees are kept until purged", func(t *testing.T) {
+		// employees 1, 2, 5 and 6 were deleted along the way
+		page, err := store.List(ctx, ListOptions{Filter: Filter{IncludeDeleted: true}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, ids(page.Employees))
+		assert.NotNil(t, page.Employees[1].DeletedAt)
+		assert.Nil(t, page.Employees[2].DeletedAt)
+
+		// the deletion moved employee 2 on to version 2
+		_, err = store.Restore(ctx, 2, 1)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		restored, err := store.Restore(ctx, 2, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, withVersion(withID(jane, 2), 3), restored)
+
+		resp, err := store.Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, restored, resp)
+
+		_, err = store.Restore(ctx, 2, 0)
+		assert.ErrorIs(t, err, ErrNotDeleted)
+		assert.ErrorIs(t, err, ErrConflict)
+
+		_, err = store.Restore(ctx, 99, 0)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// only employees deleted before the given time are purged
+		purged, err := store.Purge(ctx, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+
+		purged, err = store.Purge(ctx, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), purged)
+
+		_, err = store.Restore(ctx, 1, 0)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Changes are recorded in the audit log", func(t *testing.T) {
+		// writes that failed or were rolled back left no entry
+		entries, err := store.AuditLog(ctx, audit.Query{EmployeeID: 2, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{audit.OpRestore, audit.OpDelete, audit.OpCreate}, operations(entries))
+
+		for i, entry := range entries {
+			assert.Equal(t, int64(3-i), entry.Version)
+			assert.Equal(t, caller.Actor, entry.Actor)
+			assert.Equal(t, caller.RequestID, entry.RequestID)
+		}
+
+		assert.Equal(t, "deleted_at", entries[0].Changes[0].Field)
+		assert.Equal(t, "null", string(entries[0].Changes[0].After))
+
+		older, err := store.AuditLog(ctx, audit.Query{EmployeeID: 2, Before: entries[0].ID, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, entries[1:2], older)
+
+		third, cleared := withID(jim, 3), models.Employee{ID: 3, Name: jim.Name, Currency: jim.Currency, Version: 2}
+
+		updates, err := store.AuditLog(ctx, audit.Query{EmployeeID: 3, Operations: []string{audit.OpUpdate}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, updates, 1)
+		assert.Equal(t, audit.Diff(&third, &cleared), updates[0].Changes)
+
+		purges, err := store.AuditLog(ctx, audit.Query{
+			Actor:      caller.Actor,
+			Operations: []string{audit.OpPurge},
+			Since:      time.Now().Add(-time.Hour),
+			Limit:      10,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{audit.OpPurge, audit.OpPurge, audit.OpPurge}, operations(purges))
+		// employee 1 was purged first, at the version its deletion left
+		assert.Equal(t, int64(1), purges[2].EmployeeID)
+		assert.Equal(t, int64(4), purges[2].Version)
+
+		entries, err = store.AuditLog(ctx, audit.Query{Actor: "bob", Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+
+		_, err = store.AuditLog(ctx, audit.Query{})
+		assert.ErrorIs(t, err, audit.ErrInvalidQuery)
+	})
+
+	t.Run("Scheduled changes become current when due", func(t *testing.T) {
+		raise := models.EmployeeChanges{Salary: ptr(money.Major(45000))}
+
+		_, err := store.Schedule(ctx, models.ScheduledChange{EmployeeID: 99, EffectiveAt: raiseAt, Changes: raise})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		change, err := store.Schedule(ctx, models.ScheduledChange{EmployeeID: 4, EffectiveAt: raiseAt, Changes: raise})
+		assert.NoError(t, err)
+		assert.Equal(t, caller.Actor, change.Actor)
+		assert.Equal(t, caller.RequestID, change.RequestID)
+
+		move, err := store.Schedule(ctx, models.ScheduledChange{
+			EmployeeID:  4,
+			EffectiveAt: raiseAt.Add(time.Hour),
+			Changes:     models.EmployeeChanges{Position: ptr("PM")},
+		})
+		assert.NoError(t, err)
+
+		scheduled, err := store.Scheduled(ctx, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ScheduledChange{change, move}, scheduled)
+
+		assert.NoError(t, store.CancelScheduled(ctx, 4, move.ID))
+		assert.ErrorIs(t, store.CancelScheduled(ctx, 4, move.ID), ErrChangeNotFound)
+
+		// the job runs without a caller
+		applied, err := store.ApplyScheduled(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, Applied{}, applied)
+
+		fourth := withID(jane, 4)
+		raised := withVersion(fourth, 2)
+		raised.Salary = money.Major(45000)
+
+		applied, err = store.ApplyScheduled(context.Background(), raiseAt.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, Applied{Employees: []models.Employee{raised}}, applied)
+
+		resp, err := store.Get(ctx, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, raised, resp)
+
+		scheduled, err = store.Scheduled(ctx, 4)
+		assert.NoError(t, err)
+		assert.Empty(t, scheduled)
+
+		// the raise is recorded as made by whoever scheduled it
+		entries, err := store.AuditLog(ctx, audit.Query{EmployeeID: 4, Operations: []string{audit.OpUpdate}, Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, caller.Actor, entries[0].Actor)
+
+		// and takes effect in the history when it was due, not when applied
+		versions, err := store.History(ctx, 4)
+		assert.NoError(t, err)
+		assert.Len(t, versions, 2)
+		assert.Equal(t, fourth, versions[0].Employee)
+		assert.True(t, raiseAt.Equal(*versions[0].ValidTo))
+		assert.Equal(t, raised, versions[1].Employee)
+		assert.True(t, raiseAt.Equal(versions[1].ValidFrom))
+		assert.Nil(t, versions[1].ValidTo)
+
+		asOf, err := store.GetAsOf(ctx, 4, raiseAt.Add(-time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, fourth, asOf)
+
+		asOf, err = store.GetAsOf(ctx, 4, raiseAt)
+		assert.NoError(t, err)
+		assert.Equal(t, raised, asOf)
+
+		_, err = store.GetAsOf(ctx, 4, versions[0].ValidFrom.Add(-time.Microsecond))
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// purged employees take their history with them
+		_, err = store.History(ctx, 1)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		asOf, err = store.GetAsOf(ctx, 2, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, withVersion(withID(jane, 2), 3), asOf)
+	})
+
+	t.Run("Compensation changes need approval", func(t *testing.T) {
+		approving := audit.WithCaller(context.Background(), approver)
+		now := time.Now()
+
+		_, err := store.ProposeCompensation(ctx, models.CompensationChange{EmployeeID: 99, Salary: 1, Reason: models.ReasonMerit, EffectiveAt: meritAt})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		merit, err := store.ProposeCompensation(ctx, models.CompensationChange{EmployeeID: 2, Salary: money.Major(44000), Reason: models.ReasonMerit, EffectiveAt: meritAt})
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusProposed, merit.Status)
+		assert.Equal(t, jane.Position, merit.Position)
+		assert.Equal(t, jane.Salary, merit.CurrentSalary)
+		assert.Equal(t, caller.Actor, merit.ProposedBy)
+
+		percent, ok := merit.PercentChange()
+		assert.True(t, ok)
+		assert.InDelta(t, 10, percent, 1e-9)
+
+		promotion, err := store.ProposeCompensation(ctx, models.CompensationChange{EmployeeID: 2, Salary: money.Major(50000), Reason: models.ReasonPromotion, EffectiveAt: meritAt})
+		assert.NoError(t, err)
+
+		_, err = store.ApproveCompensation(ctx, 2, merit.ID, now)
+		assert.ErrorIs(t, err, ErrSelfApproval)
+
+		approved, err := store.ApproveCompensation(approving, 2, merit.ID, now)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusApproved, approved.Status)
+		assert.Equal(t, approver.Actor, approved.DecidedBy)
+		assert.NotNil(t, approved.DecidedAt)
+
+		raised := withVersion(withID(jane, 2), 4)
+		raised.Salary = money.Major(44000)
+
+		resp, err := store.Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, raised, resp)
+
+		_, err = store.ApproveCompensation(approving, 2, merit.ID, now)
+		assert.ErrorIs(t, err, ErrDecided)
+		assert.ErrorIs(t, err, ErrConflict)
+
+		// the promotion was proposed from the salary before the raise
+		_, err = store.ApproveCompensation(approving, 2, promotion.ID, now)
+		assert.ErrorIs(t, err, ErrStaleProposal)
+
+		rejected, err := store.RejectCompensation(approving, 2, promotion.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusRejected, rejected.Status)
+
+		_, err = store.RejectCompensation(approving, 2, 99)
+		assert.ErrorIs(t, err, ErrProposalNotFound)
+
+		// a change effective later is scheduled, as made by its approver
+		later, err := store.ProposeCompensation(ctx, models.CompensationChange{EmployeeID: 2, Salary: money.Major(48000), Reason: models.ReasonPromotion, EffectiveAt: raiseAt})
+		assert.NoError(t, err)
+
+		approvedLater, err := store.ApproveCompensation(approving, 2, later.ID, now)
+		assert.NoError(t, err)
+
+		scheduled, err := store.Scheduled(ctx, 2)
+		assert.NoError(t, err)
+		assert.Len(t, scheduled, 1)
+		assert.Equal(t, money.Major(48000), *scheduled[0].Changes.Salary)
+		assert.Equal(t, approver.Actor, scheduled[0].Actor)
+		assert.Equal(t, &scheduled[0].ID, approvedLater.ScheduleID)
+
+		// the approved change can't be dropped behind its approver's back
+		err = store.CancelScheduled(ctx, 2, scheduled[0].ID)
+		assert.ErrorIs(t, err, ErrCompensationScheduled)
+		assert.ErrorIs(t, err, ErrConflict)
+
+		changes, err := store.Compensation(ctx, 2, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{later.ID, promotion.ID, merit.ID}, compensationIDs(changes))
+
+		changes, err = store.Compensation(ctx, 2, models.StatusApproved)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{later.ID, merit.ID}, compensationIDs(changes))
+
+		report, err := store.CompensationReport(ctx, ReportOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, report, 1)
+		assert.Equal(t, jane.Position, report[0].Group)
+		assert.Equal(t, jane.Currency, report[0].Currency)
+		assert.Equal(t, int64(2), report[0].Changes)
+		assert.Equal(t, money.Major(8000), report[0].TotalRaise)
+		assert.Equal(t, int64(2), report[0].Percents)
+		assert.InDelta(t, 10+400.0/44, report[0].TotalPercent, 1e-9)
+
+		report, err = store.CompensationReport(ctx, ReportOptions{By: ReportByReason, Until: raiseAt})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.RaiseSummary{{Group: models.ReasonMerit, Currency: jane.Currency, Changes: 1, TotalRaise: money.Major(4000), Percents: 1, TotalPercent: 10}}, report)
+
+		_, err = store.CompensationReport(ctx, ReportOptions{By: "salary"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Departments are referenced by employees", func(t *testing.T) {
+		engineering, err := store.CreateDepartment(ctx, models.Department{Name: "Engineering"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), engineering)
+
+		_, err = store.CreateDepartment(ctx, models.Department{Name: "Engineering"})
+		assert.ErrorIs(t, err, ErrDepartmentExists)
+		assert.ErrorIs(t, err, ErrDuplicate)
+
+		sales, err := store.CreateDepartment(ctx, models.Department{Name: "Sales"})
+		assert.NoError(t, err)
+
+		engineer := jim
+		engineer.DepartmentID = engineering
+		id, err := store.Create(ctx, engineer)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), id)
+
+		resp, err := store.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, withID(engineer, id), resp)
+
+		unknown := john
+		unknown.DepartmentID = 99
+		_, err = store.Create(ctx, unknown)
+		assert.ErrorIs(t, err, ErrUnknownDepartment)
+		assert.ErrorIs(t, err, ErrConstraint)
+
+		var itemErr *ItemError
+		_, err = store.CreateMany(ctx, []models.Employee{john, unknown})
+		assert.ErrorIs(t, err, ErrUnknownDepartment)
+		assert.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 1, itemErr.Index)
+
+		_, err = store.Update(ctx, id, models.EmployeeChanges{DepartmentID: ptr(int64(99))})
+		assert.ErrorIs(t, err, ErrUnknownDepartment)
+
+		moved, err := store.Update(ctx, id, models.EmployeeChanges{DepartmentID: &sales})
+		assert.NoError(t, err)
+		assert.Equal(t, sales, moved.DepartmentID)
+		assert.Equal(t, int64(2), moved.Version)
+
+		page, err := store.List(ctx, ListOptions{Filter: Filter{Departments: []int64{sales}}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{id}, ids(page.Employees))
+
+		page, err = store.List(ctx, ListOptions{Filter: Filter{Departments: []int64{engineering}}, Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Employees)
+
+		// a move is checked when scheduled and again when made
+		_, err = store.Schedule(ctx, models.ScheduledChange{EmployeeID: id, EffectiveAt: meritAt, Changes: models.EmployeeChanges{DepartmentID: ptr(int64(99))}})
+		assert.ErrorIs(t, err, ErrUnknownDepartment)
+
+		back, err := store.Schedule(ctx, models.ScheduledChange{EmployeeID: id, EffectiveAt: meritAt, Changes: models.EmployeeChanges{DepartmentID: &engineering}})
+		assert.NoError(t, err)
+		assert.Equal(t, &engineering, back.Changes.DepartmentID)
+
+		// a department can't be deleted while an employee is in it
+		err = store.DeleteDepartment(ctx, sales)
+		assert.ErrorIs(t, err, ErrDepartmentInUse)
+		assert.ErrorIs(t, err, ErrConflict)
+
+		err = store.DeleteDepartment(ctx, engineering)
+		assert.NoError(t, err)
+
+		applied, err := store.ApplyScheduled(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, applied.Employees)
+
+		if assert.Len(t, applied.Dropped, 1) {
+			assert.Equal(t, back, applied.Dropped[0].Change)
+			assert.ErrorIs(t, applied.Dropped[0].Err, ErrUnknownDepartment)
+		}
+
+		_, err = store.GetDepartment(ctx, engineering)
+		assert.ErrorIs(t, err, ErrDepartmentNotFound)
+
+		err = store.DeleteDepartment(ctx, engineering)
+		assert.ErrorIs(t, err, ErrDepartmentNotFound)
+
+		renamed := models.Department{ID: sales, Name: "Sales EMEA"}
+		stored, err := store.UpdateDepartment(ctx, renamed)
+		assert.NoError(t, err)
+		assert.Equal(t, renamed, stored)
+
+		departments, err := store.ListDepartments(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Department{renamed}, departments)
+
+		// the other employees are in no department
+		summaries, err := store.DepartmentSummaries(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, summaries, models.DepartmentSummary{DepartmentID: sales, Currency: jim.Currency, Headcount: 1, Payroll: jim.Salary})
+		for _, summary := range summaries {
+			assert.Contains(t, []int64{0, sales}, summary.DepartmentID)
+		}
+	})
+
+	t.Run("Managers form a tree", func(t *testing.T) {
+		bossID, err := store.Create(ctx, john)
+		assert.NoError(t, err)
+
+		lead := jane
+		lead.ManagerID = bossID
+		leadID, err := store.Create(ctx, lead)
+		assert.NoError(t, err)
+
+		dev := jim
+		dev.ManagerID = leadID
+		devID, err := store.Create(ctx, dev)
+		assert.NoError(t, err)
+
+		unknown := john
+		unknown.ManagerID = 99
+		_, err = store.Create(ctx, unknown)
+		assert.ErrorIs(t, err, ErrUnknownManager)
+		assert.ErrorIs(t, err, ErrConstraint)
+
+		// nobody ends up reporting to themselves, even through others
+		_, err = store.Update(ctx, bossID, models.EmployeeChanges{ManagerID: &devID})
+		assert.ErrorIs(t, err, ErrManagerCycle)
+
+		_, err = store.Update(ctx, bossID, models.EmployeeChanges{ManagerID: &bossID})
+		assert.ErrorIs(t, err, ErrManagerCycle)
+
+		reports, err := store.Reports(ctx, bossID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Employee{withID(lead, leadID)}, reports)
+
+		reports, err = store.Reports(ctx, devID)
+		assert.NoError(t, err)
+		assert.Empty(t, reports)
+
+		_, err = store.Reports(ctx, 99)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		chain, err := store.Chain(ctx, devID)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{leadID, bossID}, ids(chain))
+
+		subtree, err := store.Subtree(ctx, bossID)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Employee{withID(lead, leadID), withID(dev, devID)}, subtree)
+
+		// an employee others report to is only deleted with them
+		err = store.Delete(ctx, leadID, 0)
+		assert.ErrorIs(t, err, ErrHasReports)
+		assert.ErrorIs(t, err, ErrConflict)
+
+		var itemErr *ItemError
+		err = store.DeleteMany(ctx, []int64{devID, bossID})
+		assert.ErrorIs(t, err, ErrHasReports)
+		assert.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 1, itemErr.Index)
+
+		err = store.DeleteMany(ctx, []int64{devID, leadID})
+		assert.NoError(t, err)
+
+		err = store.Delete(ctx, bossID, 0)
+		assert.NoError(t, err)
+
+		// its manager gone, the lead comes back reporting to nobody
+		restored, err := store.Restore(ctx, leadID, 0)
+		assert.NoError(t, err)
+		assert.Zero(t, restored.ManagerID)
+		assert.Equal(t, int64(3), restored.Version)
+
+		_, err = store.Chain(ctx, bossID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// a manager is checked when scheduled and again when made
+		_, err = store.Schedule(ctx, models.ScheduledChange{EmployeeID: leadID, EffectiveAt: meritAt, Changes: models.EmployeeChanges{ManagerID: ptr(int64(99))}})
+		assert.ErrorIs(t, err, ErrUnknownManager)
+
+		peerID, err := store.Create(ctx, jim)
+		assert.NoError(t, err)
+
+		under, err := store.Schedule(ctx, models.ScheduledChange{EmployeeID: peerID, EffectiveAt: meritAt, Changes: models.EmployeeChanges{ManagerID: &leadID}})
+		assert.NoError(t, err)
+
+		_, err = store.Update(ctx, leadID, models.EmployeeChanges{ManagerID: &peerID})
+		assert.NoError(t, err)
+
+		applied, err := store.ApplyScheduled(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, applied.Employees)
+
+		if assert.Len(t, applied.Dropped, 1) {
+			assert.Equal(t, under, applied.Dropped[0].Change)
+			assert.ErrorIs(t, applied.Dropped[0].Err, ErrManagerCycle)
+		}
+	})
+
+	t.Run("Salaries fit their currency", func(t *testing.T) {
+		yen := models.Employee{Name: "Yuki Sato", Position: "SDE", Salary: money.Major(1000), Currency: "JPY"}
+		id, err := store.Create(ctx, yen)
+		assert.NoError(t, err)
+
+		// a salary or a currency alone is checked against the other one
+		cents := money.Amount(100050)
+		_, err = store.Update(ctx, id, models.EmployeeChanges{Salary: &cents})
+		assert.ErrorIs(t, err, ErrSalaryPrecision)
+		assert.ErrorIs(t, err, ErrConstraint)
+
+		usd, jpy := "USD", "JPY"
+		updated, err := store.Update(ctx, id, models.EmployeeChanges{Salary: &cents, Currency: &usd})
+		assert.NoError(t, err)
+		assert.Equal(t, cents, updated.Salary)
+
+		_, err = store.Update(ctx, id, models.EmployeeChanges{Currency: &jpy})
+		assert.ErrorIs(t, err, ErrSalaryPrecision)
+
+		_, err = store.Schedule(ctx, models.ScheduledChange{EmployeeID: id, EffectiveAt: raiseAt, Changes: models.EmployeeChanges{Currency: &jpy}})
+		assert.ErrorIs(t, err, ErrSalaryPrecision)
+
+		// a change that fit when scheduled is dropped, and reported, once
+		// the currency changed under it
+		raise, err := store.Schedule(ctx, models.ScheduledChange{EmployeeID: id, EffectiveAt: meritAt, Changes: models.EmployeeChanges{Salary: ptr(money.Amount(200025))}})
+		assert.NoError(t, err)
+
+		_, err = store.Update(ctx, id, models.EmployeeChanges{Salary: ptr(money.Major(1000)), Currency: &jpy})
+		assert.NoError(t, err)
+
+		applied, err := store.ApplyScheduled(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, applied.Employees)
+
+		if assert.Len(t, applied.Dropped, 1) {
+			assert.Equal(t, raise, applied.Dropped[0].Change)
+			assert.ErrorIs(t, applied.Dropped[0].Err, ErrSalaryPrecision)
+		}
+
+		scheduled, err := store.Scheduled(ctx, id)
+		assert.NoError(t, err)
+		assert.Empty(t, scheduled)
+
+		resp, err := store.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, money.Major(1000), resp.Salary)
+	})
+
+	t.Run("Cancelled context is reported", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := store.Get(cancelled, 1)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func ids(employees []models.Employee) []int64 {
+	var result []int64
+	for _, e := range employees {
+		result = append(result, e.ID)
+	}
+
+	return result
+}
+
+func compensationIDs(changes []models.CompensationChange) []int64 {
+	var result []int64
+	for _, change := range changes {
+		result = append(result, change.ID)
+	}
+
+	return result
+}
+
+func operations(entries []audit.Entry) []string {
+	var operations []string
+	for _, entry := range entries {
+		operations = append(operations, entry.Operation)
+	}
+
+	return operations
+}
+
+func TestMemoryConformance(t *testing.T) {
+	testConformance(t, NewMemory())
+}
+
+func TestDatabaseConformance(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectConformance(mock, MySQL)
+
+	testConformance(t, Database{DB: db})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectConformance sets the expectations replaying what a real table would
+// answer to the conformance scenario, with the queries of dialect.
+func expectConformance(mock sqlmock.Sqlmock, dialect Dialect) {
+	columns := []string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}
+	row := func(rows *sqlmock.Rows, e models.Employee) *sqlmock.Rows {
+		var deletedAt driver.Value
+		if e.DeletedAt != nil {
+			deletedAt = *e.DeletedAt
+		}
+
+		return rows.AddRow(e.ID, e.Name, e.Position, e.Salary, e.Currency, nullableID(e.DepartmentID), nullableID(e.ManagerID), e.Version, deletedAt)
+	}
+
+	getQuery := dialect.Rebind(GetQuery)
+	getAllQuery := dialect.Rebind(GetAllQuery)
+
+	// locked reads a select locking its rows where the dialect can
+	locked := func(query string) string {
+		if dialect.LockRows() {
+			query += lockClause
+		}
+
+		return dialect.Rebind(query)
+	}
+
+	// log is what the audit table holds, read back by the audit step
+	var log []audit.Entry
+	recordedAt := time.Now().UTC()
+
+	// expectChangeBy replays the audit insert and history writes of a change
+	// from before to after made by the given caller
+	expectChangeBy := func(by audit.Caller, operation string, before, after *models.Employee) {
+		entry := audit.NewEntry(audit.WithCaller(context.Background(), by), operation, before, after)
+		entry.ID = int64(len(log) + 1)
+		entry.At = recordedAt
+		log = append(log, entry)
+
+		changes, _ := json.Marshal(entry.Changes)
+		var changesArg driver.Value = string(changes)
+		if operation == audit.OpDelete {
+			// the deletion time is the store's
+			changesArg = sqlmock.AnyArg()
+		}
+
+		mock.ExpectExec(dialect.Rebind(AuditInsertQuery)).
+			WithArgs(entry.EmployeeID, operation, by.Actor, by.RequestID, sqlmock.AnyArg(), entry.Version, changesArg).
+			WillReturnResult(sqlmock.NewResult(entry.ID, 1))
+
+		if live(before) {
+			mock.ExpectExec(dialect.Rebind(HistoryCloseQuery)).WithArgs(sqlmock.AnyArg(), before.ID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		if live(after) {
+			mock.ExpectExec(dialect.Rebind(HistoryInsertQuery)).
+				WithArgs(after.ID, after.Version, after.Name, after.Position, after.Salary, after.Currency, nullableID(after.DepartmentID), nullableID(after.ManagerID), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+	}
+
+	expectChange := func(operation string, before, after *models.Employee) {
+		expectChangeBy(caller, operation, before, after)
+	}
+
+	expectInsert := func(id int64, e models.Employee) {
+		if dialect.LastInsertID() {
+			mock.ExpectExec(dialect.Rebind(CreateQuery)).
+				WithArgs(e.Name, e.Position, e.Salary, e.Currency, nullableID(e.DepartmentID), nullableID(e.ManagerID)).
+				WillReturnResult(sqlmock.NewResult(id, 1))
+		} else {
+			mock.ExpectQuery(dialect.Rebind(CreateQuery+" returning id")).
+				WithArgs(e.Name, e.Position, e.Salary, e.Currency, nullableID(e.DepartmentID), nullableID(e.ManagerID)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		}
+
+		stored := withID(e, id)
+		expectChange(audit.OpCreate, nil, &stored)
+	}
+
+	expectCreate := func(id int64, e models.Employee) {
+		mock.ExpectBegin()
+		expectInsert(id, e)
+		mock.ExpectCommit()
+	}
+
+	expectGet := func(id int64, e *models.Employee) {
+		rows := sqlmock.NewRows(columns)
+		if e != nil {
+			rows = row(rows, *e)
+		}
+
+		mock.ExpectQuery(getQuery).WithArgs(id).WillReturnRows(rows)
+	}
+
+	// expectLocked replays the locked read opening a write transaction, of
+	// the employee stored as current, nil when missing
+	expectLocked := func(query string, id int64, current *models.Employee) {
+		rows := sqlmock.NewRows(columns)
+		if current != nil {
+			rows = row(rows, *current)
+		}
+
+		mock.ExpectQuery(locked(query)).WithArgs(id).WillReturnRows(rows)
+	}
+
+	// expectWrite replays an update of a row locked before it, read back as
+	// after
+	expectWrite := func(query string, args []driver.Value, after models.Employee) {
+		if dialect.Returning() {
+			mock.ExpectQuery(dialect.Rebind(query + returningColumns)).WithArgs(args...).
+				WillReturnRows(row(sqlmock.NewRows(columns), after))
+			return
+		}
+
+		mock.ExpectExec(dialect.Rebind(query)).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(getQuery).WithArgs(after.ID).WillReturnRows(row(sqlmock.NewRows(columns), after))
+	}
+
+	// expectUpdate replays an update of the employee stored as before, nil
+	// when missing, into after, nil when the update is refused.
+	expectUpdate := func(query string, args []driver.Value, before, after *models.Employee, inTx bool) {
+		if !inTx {
+			mock.ExpectBegin()
+		}
+
+		expectLocked(GetQuery, args[len(args)-1].(int64), before)
+
+		if after != nil {
+			expectWrite(query, args, *after)
+			expectChange(audit.OpUpdate, before, after)
+		}
+
+		if inTx {
+			return
+		}
+
+		if after != nil {
+			mock.ExpectCommit()
+		} else {
+			mock.ExpectRollback()
+		}
+	}
+
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deleted := func(e models.Employee, version int64) models.Employee {
+		e = withVersion(e, version)
+		e.DeletedAt = &deletedAt
+		return e
+	}
+
+	// expectReporting replays looking for the employees reporting to ids,
+	// finding none
+	expectReporting := func(ids ...int64) {
+		list, _ := idList(ids)
+		args := make([]driver.Value, 0, len(ids))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+
+		mock.ExpectQuery(dialect.Rebind(ReportingQuery + list)).WithArgs(args...).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "manager_id"}))
+	}
+
+	// expectDelete replays the delete of the employee stored as before, nil
+	// when missing, refused unless written
+	expectDelete := func(id int64, before *models.Employee, written bool) {
+		mock.ExpectBegin()
+		expectLocked(GetQuery, id, before)
+
+		if !written {
+			mock.ExpectRollback()
+			return
+		}
+
+		expectReporting(id)
+		mock.ExpectExec(dialect.Rebind(DeleteQuery)).WithArgs(sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 1))
+		after := deleted(*before, before.Version+1)
+		expectChange(audit.OpDelete, before, &after)
+		mock.ExpectCommit()
+	}
+
+	// expectRestore replays the restore of the employee stored as stored, nil
+	// when missing, into restored, nil when refused
+	expectRestore := func(id int64, stored, restored *models.Employee) {
+		mock.ExpectBegin()
+		expectLocked(GetAnyQuery, id, stored)
+
+		if restored == nil {
+			mock.ExpectRollback()
+			return
+		}
+
+		mock.ExpectExec(dialect.Rebind(RestoreQuery)).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectChange(audit.OpRestore, stored, restored)
+		mock.ExpectCommit()
+	}
+
+	// expectAuditLog replays a read of the audit log with the conditions in
+	// where, answered from the entries recorded so far that q selects
+	expectAuditLog := func(where string, args []driver.Value, q audit.Query) {
+		rows := sqlmock.NewRows([]string{"id", "employee_id", "operation", "actor", "request_id", "changed_at", "version", "changes"})
+		for i, n := len(log)-1, 0; i >= 0 && n < q.Limit; i-- {
+			entry := log[i]
+			if (q.Before == 0 || entry.ID < q.Before) && q.Matches(entry) {
+				changes, _ := json.Marshal(entry.Changes)
+				rows.AddRow(entry.ID, entry.EmployeeID, entry.Operation, entry.Actor, entry.RequestID, entry.At, entry.Version, string(changes))
+				n++
+			}
+		}
+
+		mock.ExpectQuery(dialect.Rebind(AuditQuery + where + " order by id desc limit ?")).
+			WithArgs(append(args, q.Limit)...).
+			WillReturnRows(rows)
+	}
+
+	for i, e := range []models.Employee{john, jane, jim} {
+		expectCreate(int64(i+1), e)
+	}
+
+	stored := withID(john, 1)
+	expectGet(1, &stored)
+
+	updated := withVersion(withID(john, 1), 2)
+	updated.Position = "SDE-2"
+
+	expectUpdate("update employee set position = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{"SDE-2", int64(1)}, &stored, &updated, false)
+	expectGet(1, &updated)
+
+	second, third := withID(jane, 2), withID(jim, 3)
+
+	mock.ExpectQuery(getAllQuery).
+		WithArgs(2, 0).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), updated), second))
+
+	mock.ExpectQuery(getAllQuery).
+		WithArgs(2, 2).
+		WillReturnRows(row(sqlmock.NewRows(columns), third))
+
+	mock.ExpectQuery(getAllQuery).
+		WithArgs(2, 4).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery + " where deleted_at is null order by id limit ?")).
+		WithArgs(3).
+		WillReturnRows(row(row(row(sqlmock.NewRows(columns), updated), second), third))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and ((id > ?)) order by id limit ?")).
+		WithArgs(int64(2), 3).
+		WillReturnRows(row(sqlmock.NewRows(columns), third))
+
+	mock.ExpectQuery(CountQuery + " where deleted_at is null").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and ((id < ?)) order by id desc limit ?")).
+		WithArgs(int64(3), 2).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), second), updated))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and currency in (?) order by salary desc, id limit ?")).
+		WithArgs("USD", 2).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), second), updated))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and currency in (?) and ((salary < ?) or (salary = ? and id > ?)) order by salary desc, id limit ?")).
+		WithArgs("USD", jane.Salary, jane.Salary, int64(2), 2).
+		WillReturnRows(row(sqlmock.NewRows(columns), updated))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and lower(position) in (?, ?) and currency in (?) and salary >= ? order by id limit ?")).
+		WithArgs("qa", "pm", "EUR", money.Major(45000), 11).
+		WillReturnRows(row(sqlmock.NewRows(columns), third))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and currency in (?) order by id limit ?")).
+		WithArgs("EUR", 11).
+		WillReturnRows(row(sqlmock.NewRows(columns), third))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and lower(name) like ? escape '!' and lower(name) like ? escape '!' order by "+dialect.ByteOrder("name")+", id limit ?")).
+		WithArgs("j%", "%o%", 11).
+		WillReturnRows(row(row(row(sqlmock.NewRows(columns), second), third), updated))
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery+" where deleted_at is null and lower(name) like ? escape '!' order by id limit ?")).
+		WithArgs("%!%%", 11).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	expectDelete(2, &second, true)
+
+	mock.ExpectQuery(getAllQuery).
+		WithArgs(10, 0).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), updated), third))
+
+	expectGet(2, nil)
+	expectUpdate("update employee set name = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{"Nobody", int64(2)}, nil, nil, false)
+	expectGet(2, nil)
+	expectDelete(2, nil, false)
+
+	cleared := models.Employee{ID: 3, Name: jim.Name, Currency: jim.Currency, Version: 2}
+
+	expectUpdate("update employee set position = ?, salary = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{"", int64(0), int64(3)}, &third, &cleared, false)
+	expectGet(3, &cleared)
+	expectGet(3, &cleared)
+
+	renamed := withVersion(updated, 3)
+	renamed.Name = "Johnny"
+
+	rename := "update employee set name = ?, version = version + 1 where id = ? and deleted_at is null"
+
+	expectUpdate(rename, []driver.Value{"Johnny", int64(1)}, &updated, nil, false)
+	expectUpdate(rename, []driver.Value{"Johnny", int64(1)}, &updated, &renamed, false)
+	expectGet(1, &renamed)
+	expectDelete(1, &renamed, false)
+	expectDelete(1, &renamed, true)
+	expectGet(1, nil)
+
+	fourth := withID(jane, 4)
+
+	expectCreate(4, jane)
+	expectGet(4, &fourth)
+
+	rolledBack := withVersion(cleared, 3)
+	rolledBack.Name = "Rolled back"
+
+	mock.ExpectBegin()
+	expectUpdate("update employee set name = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{"Rolled back", int64(3)}, &cleared, &rolledBack, true)
+	mock.ExpectRollback()
+	log = log[:len(log)-1]
+	expectGet(3, &cleared)
+
+	exportQuery := dialect.Rebind(ListQuery + " where deleted_at is null order by " + dialect.ByteOrder("position") + ", id")
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(exportQuery).WillReturnRows(row(row(sqlmock.NewRows(columns), cleared), fourth))
+	}
+
+	fifth, sixth := withID(john, 5), withID(jim, 6)
+
+	mock.ExpectBegin()
+	if dialect.Returning() {
+		mock.ExpectQuery(dialect.Rebind(CreateManyQuery+"(?, ?, ?, ?, ?, ?, 1), (?, ?, ?, ?, ?, ?, 1) returning id")).
+			WithArgs(john.Name, john.Position, john.Salary, john.Currency, nil, nil, jim.Name, jim.Position, jim.Salary, jim.Currency, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6).AddRow(5))
+		expectChange(audit.OpCreate, nil, &fifth)
+		expectChange(audit.OpCreate, nil, &sixth)
+	} else {
+		mock.ExpectQuery(AutoIncrementQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"lock_mode", "increment"}).AddRow(1, 1))
+		mock.ExpectExec(dialect.Rebind(CreateManyQuery+"(?, ?, ?, ?, ?, ?, 1), (?, ?, ?, ?, ?, ?, 1)")).
+			WithArgs(john.Name, john.Position, john.Salary, john.Currency, nil, nil, jim.Name, jim.Position, jim.Salary, jim.Currency, nil, nil).
+			WillReturnResult(sqlmock.NewResult(5, 2))
+		expectChange(audit.OpCreate, nil, &fifth)
+		expectChange(audit.OpCreate, nil, &sixth)
+	}
+	mock.ExpectCommit()
+
+	expectGet(6, &sixth)
+
+	existing := locked(ExistingQuery + "(?, ?)")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(existing).WithArgs(int64(5), int64(99)).
+		WillReturnRows(row(sqlmock.NewRows(columns), fifth))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(existing).WithArgs(int64(5), int64(6)).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), fifth), sixth))
+	expectReporting(5, 6)
+	mock.ExpectExec(dialect.Rebind(DeleteManyQuery+"(?, ?)")).WithArgs(sqlmock.AnyArg(), int64(5), int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	for _, e := range []models.Employee{fifth, sixth} {
+		after := deleted(e, 2)
+		expectChange(audit.OpDelete, &e, &after)
+	}
+	mock.ExpectCommit()
+
+	expectGet(5, nil)
+
+	deletedFirst, deletedSecond := deleted(renamed, 4), deleted(second, 2)
+	deletedFifth, deletedSixth := deleted(fifth, 2), deleted(sixth, 2)
+
+	mock.ExpectQuery(dialect.Rebind(ListQuery + " order by id limit ?")).
+		WithArgs(11).
+		WillReturnRows(row(row(row(row(row(row(sqlmock.NewRows(columns),
+			deletedFirst), deletedSecond), cleared), fourth), deletedFifth), deletedSixth))
+
+	restored := withVersion(second, 3)
+
+	expectRestore(2, &deletedSecond, nil)
+	expectRestore(2, &deletedSecond, &restored)
+	expectGet(2, &restored)
+	expectRestore(2, &restored, nil)
+	expectRestore(99, nil, nil)
+
+	purgeable := locked(PurgeableQuery)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(purgeable).WithArgs(sqlmock.AnyArg(), purgeChunkSize).WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(purgeable).WithArgs(sqlmock.AnyArg(), purgeChunkSize).
+		WillReturnRows(row(row(row(sqlmock.NewRows(columns), deletedFirst), deletedFifth), deletedSixth))
+	mock.ExpectExec(dialect.Rebind(PurgeQuery+"(?, ?, ?)")).WithArgs(int64(1), int64(5), int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(dialect.Rebind(HistoryPurgeQuery+"(?, ?, ?)")).WithArgs(int64(1), int64(5), int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(dialect.Rebind(SchedulePurgeQuery+"(?, ?, ?)")).WithArgs(int64(1), int64(5), int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(dialect.Rebind(CompensationPurgeQuery+"(?, ?, ?)")).WithArgs(int64(1), int64(5), int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, e := range []models.Employee{deletedFirst, deletedFifth, deletedSixth} {
+		expectChange(audit.OpPurge, &e, nil)
+	}
+	mock.ExpectCommit()
+
+	expectRestore(1, nil, nil)
+
+	// entry ids follow the replayed log, where restoring employee 2 is the
+	// 14th change
+	expectAuditLog(" where employee_id = ?", []driver.Value{int64(2)}, audit.Query{EmployeeID: 2, Limit: 10})
+	expectAuditLog(" where employee_id = ? and id < ?", []driver.Value{int64(2), int64(14)}, audit.Query{EmployeeID: 2, Before: 14, Limit: 1})
+	expectAuditLog(" where employee_id = ? and operation in (?)", []driver.Value{int64(3), audit.OpUpdate},
+		audit.Query{EmployeeID: 3, Operations: []string{audit.OpUpdate}, Limit: 10})
+	expectAuditLog(" where actor = ? and operation in (?) and changed_at >= ?", []driver.Value{caller.Actor, audit.OpPurge, sqlmock.AnyArg()},
+		audit.Query{Actor: caller.Actor, Operations: []string{audit.OpPurge}, Limit: 10})
+	expectAuditLog(" where actor = ?", []driver.Value{"bob"}, audit.Query{Actor: "bob", Limit: 10})
+
+	scheduleColumns := []string{"id", "employee_id", "effective_at", "name", "position", "salary", "currency", "department_id", "manager_id", "actor", "request_id"}
+	raise, move := raiseAt, raiseAt.Add(time.Hour)
+
+	// expectSchedule replays scheduling a change of the employee stored as
+	// current, nil when missing, stored under id
+	expectSchedule := func(current *models.Employee, id int64, args ...driver.Value) {
+		mock.ExpectBegin()
+		expectLocked(GetQuery, int64(4), current)
+
+		if current == nil {
+			mock.ExpectRollback()
+			return
+		}
+
+		args = append(args, caller.Actor, caller.RequestID)
+		if dialect.LastInsertID() {
+			mock.ExpectExec(dialect.Rebind(ScheduleInsertQuery)).WithArgs(args...).
+				WillReturnResult(sqlmock.NewResult(id, 1))
+		} else {
+			mock.ExpectQuery(dialect.Rebind(ScheduleInsertQuery + " returning id")).WithArgs(args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		}
+
+		mock.ExpectCommit()
+	}
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, int64(99), nil)
+	mock.ExpectRollback()
+
+	expectSchedule(&fourth, 1, int64(4), raise, nil, nil, money.Major(45000), nil, nil, nil)
+	expectSchedule(&fourth, 2, int64(4), move, nil, "PM", nil, nil, nil, nil)
+
+	mock.ExpectQuery(dialect.Rebind(ScheduledQuery)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow(int64(1), int64(4), raise, nil, nil, int64(money.Major(45000)), nil, nil, nil, caller.Actor, caller.RequestID).
+			AddRow(int64(2), int64(4), move, nil, "PM", nil, nil, nil, nil, caller.Actor, caller.RequestID))
+
+	// expectCancel replays cancelling the scheduled change, linked to as many
+	// approved compensation changes, deleting affected rows
+	expectCancel := func(changeID, linked, affected int64) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(dialect.Rebind(CompensationScheduledQuery)).WithArgs(changeID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(linked))
+
+		if linked > 0 {
+			mock.ExpectRollback()
+			return
+		}
+
+		mock.ExpectExec(dialect.Rebind(CancelScheduledQuery)).WithArgs(changeID, int64(4)).WillReturnResult(sqlmock.NewResult(0, affected))
+
+		if affected == 0 {
+			mock.ExpectRollback()
+		} else {
+			mock.ExpectCommit()
+		}
+	}
+
+	expectCancel(2, 0, 1)
+	expectCancel(2, 0, 0)
+
+	due := locked(DueQuery)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(due).WithArgs(sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows(scheduleColumns))
+	mock.ExpectCommit()
+
+	raised := withVersion(fourth, 2)
+	raised.Salary = money.Major(45000)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(due).WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow(int64(1), int64(4), raise, nil, nil, int64(money.Major(45000)), nil, nil, nil, caller.Actor, caller.RequestID))
+	mock.ExpectExec(dialect.Rebind(AppliedQuery)).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLocked(GetQuery, int64(4), &fourth)
+	mock.ExpectQuery(dialect.Rebind(HistoryOpenQuery)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"valid_from"}).AddRow(recordedAt))
+	expectWrite("update employee set salary = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{money.Major(45000), int64(4)}, raised)
+	expectChange(audit.OpUpdate, &fourth, &raised)
+	mock.ExpectCommit()
+
+	expectGet(4, &raised)
+	mock.ExpectQuery(dialect.Rebind(ScheduledQuery)).WithArgs(int64(4)).WillReturnRows(sqlmock.NewRows(scheduleColumns))
+
+	expectAuditLog(" where employee_id = ? and operation in (?)", []driver.Value{int64(4), audit.OpUpdate},
+		audit.Query{EmployeeID: 4, Operations: []string{audit.OpUpdate}, Limit: 1})
+
+	historyColumns := []string{"employee_id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "valid_from", "valid_to"}
+	version := func(rows *sqlmock.Rows, e models.Employee, validFrom time.Time, validTo driver.Value) *sqlmock.Rows {
+		return rows.AddRow(e.ID, e.Name, e.Position, e.Salary, e.Currency, nullableID(e.DepartmentID), nullableID(e.ManagerID), e.Version, validFrom, validTo)
+	}
+
+	mock.ExpectQuery(dialect.Rebind(HistoryQuery)).WithArgs(int64(4)).
+		WillReturnRows(version(version(sqlmock.NewRows(historyColumns), fourth, recordedAt, raise), raised, raise, nil))
+
+	asOf := dialect.Rebind(AsOfQuery)
+	mock.ExpectQuery(asOf).WithArgs(int64(4), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(version(sqlmock.NewRows(historyColumns), fourth, recordedAt, raise))
+	mock.ExpectQuery(asOf).WithArgs(int64(4), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(version(sqlmock.NewRows(historyColumns), raised, raise, nil))
+	mock.ExpectQuery(asOf).WithArgs(int64(4), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(historyColumns))
+
+	mock.ExpectQuery(dialect.Rebind(HistoryQuery)).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(historyColumns))
+	mock.ExpectQuery(asOf).WithArgs(int64(2), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(version(sqlmock.NewRows(historyColumns), restored, recordedAt, nil))
+
+	compensationColumns := []string{"id", "employee_id", "position", "current_salary", "salary", "currency", "reason",
+		"effective_at", "status", "proposed_by", "proposed_at", "decided_by", "decided_at", "schedule_id", "note"}
+	compensation := func(c models.CompensationChange) *sqlmock.Rows {
+		var decidedAt, scheduleID driver.Value
+		if c.DecidedAt != nil {
+			decidedAt = *c.DecidedAt
+		}
+
+		if c.ScheduleID != nil {
+			scheduleID = *c.ScheduleID
+		}
+
+		return sqlmock.NewRows(compensationColumns).AddRow(c.ID, c.EmployeeID, c.Position, c.CurrentSalary, c.Salary, c.Currency, c.Reason,
+			c.EffectiveAt, c.Status, c.ProposedBy, recordedAt, c.DecidedBy, decidedAt, scheduleID, c.Note)
+	}
+
+	proposal := func(id int64, current models.Employee, salary money.Amount, reason string, effectiveAt time.Time) models.CompensationChange {
+		return models.CompensationChange{ID: id, EmployeeID: current.ID, Position: current.Position, CurrentSalary: current.Salary,
+			Salary: salary, Currency: current.Currency, Reason: reason, EffectiveAt: effectiveAt, Status: models.StatusProposed, ProposedBy: caller.Actor}
+	}
+
+	approvedBy := func(c models.CompensationChange, status string) models.CompensationChange {
+		c.Status, c.DecidedBy, c.DecidedAt = status, approver.Actor, &recordedAt
+		return c
+	}
+
+	// expectPropose replays proposing the change for the employee stored as
+	// current, nil when missing
+	expectPropose := func(id int64, current *models.Employee, c models.CompensationChange) {
+		mock.ExpectBegin()
+		expectLocked(GetQuery, id, current)
+
+		if current == nil {
+			mock.ExpectRollback()
+			return
+		}
+
+		args := []driver.Value{c.EmployeeID, c.Position, c.CurrentSalary, c.Salary, c.Currency, c.Reason, c.EffectiveAt,
+			models.StatusProposed, caller.Actor, sqlmock.AnyArg(), ""}
+		if dialect.LastInsertID() {
+			mock.ExpectExec(dialect.Rebind(CompensationInsertQuery)).WithArgs(args...).
+				WillReturnResult(sqlmock.NewResult(c.ID, 1))
+		} else {
+			mock.ExpectQuery(dialect.Rebind(CompensationInsertQuery + " returning id")).WithArgs(args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(c.ID))
+		}
+
+		mock.ExpectCommit()
+	}
+
+	getProposal := locked(CompensationQuery + " where id = ? and employee_id = ?")
+	decide := dialect.Rebind(CompensationDecideQuery)
+
+	// expectProposal replays the locked read of a proposal, nil when missing
+	expectProposal := func(changeID int64, c *models.CompensationChange) {
+		rows := sqlmock.NewRows(compensationColumns)
+		if c != nil {
+			rows = compensation(*c)
+		}
+
+		mock.ExpectQuery(getProposal).WithArgs(changeID, int64(2)).WillReturnRows(rows)
+	}
+
+	expectDecide := func(changeID int64, status string, scheduleID driver.Value) {
+		mock.ExpectExec(decide).WithArgs(status, approver.Actor, sqlmock.AnyArg(), scheduleID, changeID, models.StatusProposed).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	merit := proposal(1, restored, money.Major(44000), models.ReasonMerit, meritAt)
+	promotion := proposal(2, restored, money.Major(50000), models.ReasonPromotion, meritAt)
+
+	expectPropose(99, nil, models.CompensationChange{})
+	expectPropose(2, &restored, merit)
+	expectPropose(2, &restored, promotion)
+
+	// proposed by the approver's own hand
+	mock.ExpectBegin()
+	expectProposal(1, &merit)
+	expectLocked(GetQuery, 2, &restored)
+	mock.ExpectRollback()
+
+	meritRaised := withVersion(restored, 4)
+	meritRaised.Salary = money.Major(44000)
+
+	mock.ExpectBegin()
+	expectProposal(1, &merit)
+	expectLocked(GetQuery, 2, &restored)
+	expectDecide(1, models.StatusApproved, nil)
+	mock.ExpectQuery(dialect.Rebind(HistoryOpenQuery)).WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"valid_from"}).AddRow(recordedAt))
+	expectWrite("update employee set salary = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{money.Major(44000), int64(2)}, meritRaised)
+	expectChangeBy(approver, audit.OpUpdate, &restored, &meritRaised)
+	mock.ExpectCommit()
+
+	expectGet(2, &meritRaised)
+
+	approvedMerit := approvedBy(merit, models.StatusApproved)
+
+	for _, c := range []*models.CompensationChange{&approvedMerit, &promotion} {
+		mock.ExpectBegin()
+		expectProposal(c.ID, c)
+		expectLocked(GetQuery, 2, &meritRaised)
+		mock.ExpectRollback()
+	}
+
+	mock.ExpectBegin()
+	expectProposal(2, &promotion)
+	expectDecide(2, models.StatusRejected, nil)
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(getProposal).WithArgs(int64(99), int64(2)).WillReturnRows(sqlmock.NewRows(compensationColumns))
+	mock.ExpectRollback()
+
+	later := proposal(3, meritRaised, money.Major(48000), models.ReasonPromotion, raiseAt)
+	expectPropose(2, &meritRaised, later)
+
+	mock.ExpectBegin()
+	expectProposal(3, &later)
+	expectLocked(GetQuery, 2, &meritRaised)
+	scheduleArgs := []driver.Value{int64(2), raiseAt, nil, nil, money.Major(48000), nil, nil, nil, approver.Actor, approver.RequestID}
+	if dialect.LastInsertID() {
+		mock.ExpectExec(dialect.Rebind(ScheduleInsertQuery)).WithArgs(scheduleArgs...).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+	} else {
+		mock.ExpectQuery(dialect.Rebind(ScheduleInsertQuery + " returning id")).WithArgs(scheduleArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	}
+	expectDecide(3, models.StatusApproved, int64(3))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(dialect.Rebind(ScheduledQuery)).WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow(int64(3), int64(2), raiseAt, nil, nil, int64(money.Major(48000)), nil, nil, nil, approver.Actor, approver.RequestID))
+	expectCancel(3, 1, 0)
+
+	approvedLater := approvedBy(later, models.StatusApproved)
+	approvedLater.ScheduleID = ptr(int64(3))
+	rejectedPromotion := approvedBy(promotion, models.StatusRejected)
+
+	listed := func(changes ...models.CompensationChange) *sqlmock.Rows {
+		rows := sqlmock.NewRows(compensationColumns)
+		for _, c := range changes {
+			var scheduleID driver.Value
+			if c.ScheduleID != nil {
+				scheduleID = *c.ScheduleID
+			}
+
+			rows.AddRow(c.ID, c.EmployeeID, c.Position, c.CurrentSalary, c.Salary, c.Currency, c.Reason,
+				c.EffectiveAt, c.Status, c.ProposedBy, recordedAt, c.DecidedBy, *c.DecidedAt, scheduleID, c.Note)
+		}
+
+		return rows
+	}
+
+	mock.ExpectQuery(dialect.Rebind(CompensationQuery + " where employee_id = ? order by id desc")).WithArgs(int64(2)).
+		WillReturnRows(listed(approvedLater, rejectedPromotion, approvedMerit))
+	mock.ExpectQuery(dialect.Rebind(CompensationQuery+" where employee_id = ? and status = ? order by id desc")).
+		WithArgs(int64(2), models.StatusApproved).
+		WillReturnRows(listed(approvedLater, approvedMerit))
+
+	reportColumns := []string{"group", "currency", "count", "total_raise", "percents", "total_percent"}
+
+	mock.ExpectQuery(dialect.Rebind(fmt.Sprintf(CompensationReportQuery, "position") + " where status = ? group by position, currency order by position, currency")).
+		WithArgs(models.StatusApproved).
+		WillReturnRows(sqlmock.NewRows(reportColumns).AddRow(jane.Position, jane.Currency, int64(2), int64(money.Major(8000)), int64(2), 10+400.0/44))
+	mock.ExpectQuery(dialect.Rebind(fmt.Sprintf(CompensationReportQuery, "reason")+" where status = ? and effective_at < ? group by reason, currency order by reason, currency")).
+		WithArgs(models.StatusApproved, raiseAt).
+		WillReturnRows(sqlmock.NewRows(reportColumns).AddRow(models.ReasonMerit, jane.Currency, int64(1), int64(money.Major(4000)), int64(1), 10.0))
+
+	// expectDepartmentInsert replays the insert of a department, failing
+	// with err when set
+	expectDepartmentInsert := func(name string, id int64, err error) {
+		if dialect.LastInsertID() {
+			e := mock.ExpectExec(dialect.Rebind(DepartmentInsertQuery)).WithArgs(name)
+			if err != nil {
+				e.WillReturnError(err)
+			} else {
+				e.WillReturnResult(sqlmock.NewResult(id, 1))
+			}
+
+			return
+		}
+
+		e := mock.ExpectQuery(dialect.Rebind(DepartmentInsertQuery + " returning id")).WithArgs(name)
+		if err != nil {
+			e.WillReturnError(err)
+		} else {
+			e.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		}
+	}
+
+	var duplicate error = &mysql.MySQLError{Number: 1062}
+	if dialect.Name() == Postgres.Name() {
+		duplicate = &pq.Error{Code: "23505"}
+	}
+
+	departmentColumns := []string{"id", "name"}
+	department := func(id int64, name string) *sqlmock.Rows {
+		return sqlmock.NewRows(departmentColumns).AddRow(id, name)
+	}
+
+	expectDepartmentInsert("Engineering", 1, nil)
+	expectDepartmentInsert("Engineering", 0, duplicate)
+	expectDepartmentInsert("Sales", 2, nil)
+
+	engineer := jim
+	engineer.DepartmentID = 1
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(1)).WillReturnRows(department(1, "Engineering"))
+	expectInsert(7, engineer)
+	mock.ExpectCommit()
+	expectGet(7, ptr(withID(engineer, 7)))
+
+	// an unknown department refuses creates and updates
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(99)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(99)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 7, ptr(withID(engineer, 7)))
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(99)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectRollback()
+
+	moved := withVersion(withID(engineer, 7), 2)
+	moved.DepartmentID = 2
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 7, ptr(withID(engineer, 7)))
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(2)).WillReturnRows(department(2, "Sales"))
+	expectWrite("update employee set department_id = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{int64(2), int64(7)}, moved)
+	expectChange(audit.OpUpdate, ptr(withID(engineer, 7)), &moved)
+	mock.ExpectCommit()
+
+	byDepartment := dialect.Rebind(ListQuery + " where deleted_at is null and (department_id in (?)) order by id limit ?")
+	mock.ExpectQuery(byDepartment).WithArgs(int64(2), 11).WillReturnRows(row(sqlmock.NewRows(columns), moved))
+	mock.ExpectQuery(byDepartment).WithArgs(int64(1), 11).WillReturnRows(sqlmock.NewRows(columns))
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 7, &moved)
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(99)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 7, &moved)
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(1)).WillReturnRows(department(1, "Engineering"))
+	scheduleArgs = []driver.Value{int64(7), meritAt, nil, nil, nil, nil, int64(1), nil, caller.Actor, caller.RequestID}
+	if dialect.LastInsertID() {
+		mock.ExpectExec(dialect.Rebind(ScheduleInsertQuery)).WithArgs(scheduleArgs...).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+	} else {
+		mock.ExpectQuery(dialect.Rebind(ScheduleInsertQuery + " returning id")).WithArgs(scheduleArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	}
+	mock.ExpectCommit()
+
+	// deleting counts the employees in the locked department
+	used := dialect.Rebind(DepartmentUsedQuery)
+	mock.ExpectBegin()
+	mock.ExpectQuery(locked(DepartmentQuery)).WithArgs(int64(2)).WillReturnRows(department(2, "Sales"))
+	mock.ExpectQuery(used).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(locked(DepartmentQuery)).WithArgs(int64(1)).WillReturnRows(department(1, "Engineering"))
+	mock.ExpectQuery(used).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(dialect.Rebind(DepartmentDeleteQuery)).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// the move is dropped once its department is gone
+	mock.ExpectBegin()
+	mock.ExpectQuery(due).WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow(int64(4), int64(7), meritAt, nil, nil, nil, nil, int64(1), nil, caller.Actor, caller.RequestID))
+	mock.ExpectExec(dialect.Rebind(AppliedQuery)).WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLocked(GetQuery, 7, &moved)
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectCommit()
+	mock.ExpectQuery(dialect.Rebind(DepartmentQuery)).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectBegin()
+	mock.ExpectQuery(locked(DepartmentQuery)).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(departmentColumns))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(locked(DepartmentQuery)).WithArgs(int64(2)).WillReturnRows(department(2, "Sales"))
+	mock.ExpectExec(dialect.Rebind(DepartmentUpdateQuery)).WithArgs("Sales EMEA", int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(dialect.Rebind(DepartmentsQuery)).WillReturnRows(department(2, "Sales EMEA"))
+
+	mock.ExpectQuery(dialect.Rebind(DepartmentSummaryQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"department_id", "currency", "headcount", "payroll"}).
+			AddRow(int64(0), jane.Currency, int64(2), int64(money.Major(80000))).
+			AddRow(int64(2), jim.Currency, int64(1), int64(jim.Salary)))
+
+	// creates and updates lock the manager the employee is made to report
+	// to, updates read its chain of managers
+	boss, lead, dev := withID(john, 8), withID(jane, 9), withID(jim, 10)
+	lead.ManagerID, dev.ManagerID = 8, 9
+	expectCreate(8, john)
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 8, &boss)
+	expectInsert(9, lead)
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 9, &lead)
+	expectInsert(10, dev)
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 99, nil)
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 8, &boss)
+	expectLocked(GetQuery, 10, &dev)
+	mock.ExpectQuery(dialect.Rebind(ChainQuery)).WithArgs(int64(10)).
+		WillReturnRows(row(row(row(sqlmock.NewRows(columns), dev), lead), boss))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 8, &boss)
+	mock.ExpectRollback()
+
+	// the reads start from the employee asked about
+	reportsQuery := dialect.Rebind(ReportsQuery)
+	mock.ExpectQuery(reportsQuery).WithArgs(int64(8), int64(8)).WillReturnRows(row(row(sqlmock.NewRows(columns), boss), lead))
+	mock.ExpectQuery(reportsQuery).WithArgs(int64(10), int64(10)).WillReturnRows(row(sqlmock.NewRows(columns), dev))
+	mock.ExpectQuery(reportsQuery).WithArgs(int64(99), int64(99)).WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(dialect.Rebind(ChainQuery)).WithArgs(int64(10)).
+		WillReturnRows(row(row(row(sqlmock.NewRows(columns), dev), lead), boss))
+	mock.ExpectQuery(dialect.Rebind(SubtreeQuery)).WithArgs(int64(8)).
+		WillReturnRows(row(row(row(sqlmock.NewRows(columns), boss), lead), dev))
+
+	reportingColumns := []string{"id", "manager_id"}
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 9, &lead)
+	mock.ExpectQuery(dialect.Rebind(ReportingQuery + "(?)")).WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows(reportingColumns).AddRow(int64(10), int64(9)))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(existing).WithArgs(int64(10), int64(8)).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), dev), boss))
+	mock.ExpectQuery(dialect.Rebind(ReportingQuery+"(?, ?)")).WithArgs(int64(10), int64(8)).
+		WillReturnRows(sqlmock.NewRows(reportingColumns).AddRow(int64(9), int64(8)))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(existing).WithArgs(int64(10), int64(9)).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), dev), lead))
+	mock.ExpectQuery(dialect.Rebind(ReportingQuery+"(?, ?)")).WithArgs(int64(10), int64(9)).
+		WillReturnRows(sqlmock.NewRows(reportingColumns).AddRow(int64(10), int64(9)))
+	mock.ExpectExec(dialect.Rebind(DeleteManyQuery+"(?, ?)")).WithArgs(sqlmock.AnyArg(), int64(10), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	deletedDev, deletedLead := deleted(dev, 2), deleted(lead, 2)
+	expectChange(audit.OpDelete, &dev, &deletedDev)
+	expectChange(audit.OpDelete, &lead, &deletedLead)
+	mock.ExpectCommit()
+
+	expectDelete(8, &boss, true)
+
+	restoredLead := withVersion(lead, 3)
+	restoredLead.ManagerID = 0
+	mock.ExpectBegin()
+	expectLocked(GetAnyQuery, 9, &deletedLead)
+	expectLocked(GetQuery, 8, nil)
+	mock.ExpectExec(dialect.Rebind(RestoreUnmanagedQuery)).WithArgs(int64(9)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectChange(audit.OpRestore, &deletedLead, &restoredLead)
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(dialect.Rebind(ChainQuery)).WithArgs(int64(8)).WillReturnRows(sqlmock.NewRows(columns))
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 9, &restoredLead)
+	expectLocked(GetQuery, 99, nil)
+	mock.ExpectRollback()
+
+	peer := withID(jim, 11)
+	expectCreate(11, jim)
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 11, &peer)
+	expectLocked(GetQuery, 9, &restoredLead)
+	mock.ExpectQuery(dialect.Rebind(ChainQuery)).WithArgs(int64(9)).WillReturnRows(row(sqlmock.NewRows(columns), restoredLead))
+	scheduleArgs = []driver.Value{int64(11), meritAt, nil, nil, nil, nil, nil, int64(9), caller.Actor, caller.RequestID}
+	if dialect.LastInsertID() {
+		mock.ExpectExec(dialect.Rebind(ScheduleInsertQuery)).WithArgs(scheduleArgs...).
+			WillReturnResult(sqlmock.NewResult(5, 1))
+	} else {
+		mock.ExpectQuery(dialect.Rebind(ScheduleInsertQuery + " returning id")).WithArgs(scheduleArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	}
+	mock.ExpectCommit()
+
+	managedLead := withVersion(restoredLead, 4)
+	managedLead.ManagerID = 11
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 9, &restoredLead)
+	expectLocked(GetQuery, 11, &peer)
+	mock.ExpectQuery(dialect.Rebind(ChainQuery)).WithArgs(int64(11)).WillReturnRows(row(sqlmock.NewRows(columns), peer))
+	expectWrite("update employee set manager_id = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{int64(11), int64(9)}, managedLead)
+	expectChange(audit.OpUpdate, &restoredLead, &managedLead)
+	mock.ExpectCommit()
+
+	// by then the peer would report to someone under it
+	mock.ExpectBegin()
+	mock.ExpectQuery(due).WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow(int64(5), int64(11), meritAt, nil, nil, nil, nil, nil, int64(9), caller.Actor, caller.RequestID))
+	mock.ExpectExec(dialect.Rebind(AppliedQuery)).WithArgs(int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLocked(GetQuery, 11, &peer)
+	expectLocked(GetQuery, 9, &managedLead)
+	mock.ExpectQuery(dialect.Rebind(ChainQuery)).WithArgs(int64(9)).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), managedLead), peer))
+	mock.ExpectCommit()
+
+	yen := models.Employee{Name: "Yuki Sato", Position: "SDE", Salary: money.Major(1000), Currency: "JPY"}
+	expectCreate(12, yen)
+	yen = withID(yen, 12)
+
+	cents := money.Amount(100050)
+	expectUpdate("update employee set salary = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{cents, int64(12)}, &yen, nil, false)
+
+	usd := withVersion(yen, 2)
+	usd.Salary, usd.Currency = cents, "USD"
+	expectUpdate("update employee set salary = ?, currency = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{cents, "USD", int64(12)}, &yen, &usd, false)
+
+	expectUpdate("update employee set currency = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{"JPY", int64(12)}, &usd, nil, false)
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 12, &usd)
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	expectLocked(GetQuery, 12, &usd)
+	scheduleArgs = []driver.Value{int64(12), meritAt, nil, nil, money.Amount(200025), nil, nil, nil, caller.Actor, caller.RequestID}
+	if dialect.LastInsertID() {
+		mock.ExpectExec(dialect.Rebind(ScheduleInsertQuery)).WithArgs(scheduleArgs...).
+			WillReturnResult(sqlmock.NewResult(6, 1))
+	} else {
+		mock.ExpectQuery(dialect.Rebind(ScheduleInsertQuery + " returning id")).WithArgs(scheduleArgs...).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	}
+	mock.ExpectCommit()
+
+	yenAgain := withVersion(yen, 3)
+	expectUpdate("update employee set salary = ?, currency = ?, version = version + 1 where id = ? and deleted_at is null",
+		[]driver.Value{money.Major(1000), "JPY", int64(12)}, &usd, &yenAgain, false)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(due).WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow(int64(6), int64(12), meritAt, nil, nil, int64(200025), nil, nil, nil, caller.Actor, caller.RequestID))
+	mock.ExpectExec(dialect.Rebind(AppliedQuery)).WithArgs(int64(6)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLocked(GetQuery, 12, &yenAgain)
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(dialect.Rebind(ScheduledQuery)).WithArgs(int64(12)).WillReturnRows(sqlmock.NewRows(scheduleColumns))
+	expectGet(12, &yenAgain)
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+)
+
+// deletionTime is the time deleted employees are marked with, in UTC and
+// to the second so every backend stores and compares it alike.
+func deletionTime() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// Restore clears the employee's deletion in a transaction, after reading and
+// locking the deleted row. An employee whose manager is gone meanwhile comes
+// back reporting to nobody.
+func (d Database) Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	var employee models.Employee
+
+	err := d.inTx(ctx, func(tx Database) error {
+		current, err := tx.getForUpdate(ctx, GetAnyQuery, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkRestorable(current, ifVersion)
+		if err != nil {
+			return err
+		}
+
+		gone, err := tx.managerGone(ctx, current)
+		if err != nil {
+			return err
+		}
+
+		employee = current
+
+		query := RestoreQuery
+		if gone {
+			query = RestoreUnmanagedQuery
+			employee.ManagerID = 0
+		}
+
+		err = tx.execOne(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		employee.DeletedAt = nil
+		employee.Version++
+
+		return tx.changed(ctx, audit.OpRestore, &current, &employee)
+	})
+
+	return employee, err
+}
+
+// checkRestorable reports why current can't be restored at ifVersion.
+func checkRestorable(current models.Employee, ifVersion int64) error {
+	err := checkVersion(current, ifVersion)
+	if err != nil {
+		return err
+	}
+
+	if current.DeletedAt == nil {
+		return ErrNotDeleted
+	}
+
+	return nil
+}
+
+// purgeChunkSize is the number of employees Purge removes per transaction.
+const purgeChunkSize = 500
+
+// Purge deletes for good the employees deleted before the given time, a
+// chunk per transaction each with its own write timeout, until none is left.
+// Their history, scheduled and compensation changes go with them, each is
+// recorded in the audit log.
+func (d Database) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+
+	for {
+		n, err := d.purgeChunk(ctx, before.UTC())
+		purged += n
+
+		if err != nil || n < purgeChunkSize {
+			return purged, err
+		}
+	}
+}
+
+// purgeChunk removes the next chunk of employees deleted before the given
+// time, returning how many it removed.
+func (d Database) purgeChunk(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	var purged int64
+
+	err := d.inTx(ctx, func(tx Database) error {
+		employees, err := tx.getManyForUpdate(ctx, PurgeableQuery, before, purgeChunkSize)
+		if err != nil || len(employees) == 0 {
+			return err
+		}
+
+		ids := make([]int64, 0, len(employees))
+		for _, employee := range employees {
+			ids = append(ids, employee.ID)
+		}
+
+		list, args := idList(ids)
+
+		result, err := tx.conn().ExecContext(ctx, tx.rebind(PurgeQuery+list), args...)
+		if err != nil {
+			return tx.translate(err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected != int64(len(ids)) {
+			return ErrConflict
+		}
+
+		for _, query := range []string{HistoryPurgeQuery, SchedulePurgeQuery, CompensationPurgeQuery} {
+			_, err = tx.conn().ExecContext(ctx, tx.rebind(query+list), args...)
+			if err != nil {
+				return tx.translate(err)
+			}
+		}
+
+		for _, employee := range employees {
+			err = tx.changed(ctx, audit.OpPurge, &employee, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		purged = int64(len(employees))
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// markedDeleted is the employee as a delete at the given time leaves it.
+func markedDeleted(employee models.Employee, at time.Time) models.Employee {
+	employee.DeletedAt = &at
+	employee.Version++
+
+	return employee
+}
+
+func (s *memoryState) restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	current, ok := s.employees[id]
+	if !ok {
+		return current, ErrNotFound
+	}
+
+	err := checkRestorable(current, ifVersion)
+	if err != nil {
+		return current, err
+	}
+
+	restored := current
+	if s.knownManager(current.ManagerID) != nil {
+		restored.ManagerID = 0
+	}
+
+	restored.DeletedAt = nil
+	restored.Version++
+	s.employees[id] = restored
+	s.changed(ctx, audit.OpRestore, &current, &restored)
+
+	return restored, nil
+}
+
+func (s *memoryState) purge(ctx context.Context, before time.Time) int64 {
+	var ids []int64
+	for id, employee := range s.employees {
+		if employee.DeletedAt != nil && employee.DeletedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	purged := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		employee := s.employees[id]
+		delete(s.employees, id)
+		delete(s.history, id)
+		s.changed(ctx, audit.OpPurge, &employee, nil)
+		purged[id] = true
+	}
+
+	// like the foreign key of employee.manager_id, the deleted employees
+	// that reported to a purged one are left reporting to nobody
+	for id, employee := range s.employees {
+		if purged[employee.ManagerID] {
+			employee.ManagerID = 0
+			s.employees[id] = employee
+		}
+	}
+
+	var scheduled []models.ScheduledChange
+	for _, change := range s.scheduled {
+		if _, ok := s.employees[change.EmployeeID]; ok {
+			scheduled = append(scheduled, change)
+		}
+	}
+
+	s.scheduled = scheduled
+
+	var compensation []models.CompensationChange
+	for _, change := range s.compensation {
+		if _, ok := s.employees[change.EmployeeID]; ok {
+			compensation = append(compensation, change)
+		}
+	}
+
+	s.compensation = compensation
+
+	return int64(len(ids))
+}
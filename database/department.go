@@ -0,0 +1,347 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	"example.com/m/Assesment/models"
+)
+
+// nullableID is the column value of an optional reference, null for zero.
+func nullableID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
+
+// departmentError tells a taken department name from other duplicates.
+func departmentError(err error) error {
+	if errors.Is(err, ErrDuplicate) {
+		return wrap(ErrDepartmentExists, err)
+	}
+
+	return err
+}
+
+func (d Database) CreateDepartment(ctx context.Context, department models.Department) (int64, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	id, err := d.insertID(ctx, DepartmentInsertQuery, department.Name)
+
+	return id, departmentError(err)
+}
+
+func (d Database) GetDepartment(ctx context.Context, id int64) (models.Department, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	return d.getDepartment(ctx, DepartmentQuery, id)
+}
+
+// getDepartment reads the department with query, ErrDepartmentNotFound when
+// there is none.
+func (d Database) getDepartment(ctx context.Context, query string, id int64) (models.Department, error) {
+	var department models.Department
+
+	err := d.conn().QueryRowContext(ctx, d.rebind(query), id).Scan(&department.ID, &department.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return department, ErrDepartmentNotFound
+	}
+
+	return department, d.translate(err)
+}
+
+// lockDepartment reads the department, locking its row until the transaction
+// ends where the dialect can.
+func (d Database) lockDepartment(ctx context.Context, id int64) (models.Department, error) {
+	query := DepartmentQuery
+	if d.dialect().LockRows() {
+		query = query + lockClause
+	}
+
+	return d.getDepartment(ctx, query, id)
+}
+
+func (d Database) ListDepartments(ctx context.Context) ([]models.Department, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(DepartmentsQuery))
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	departments := []models.Department{}
+	for rows.Next() {
+		var department models.Department
+		err = rows.Scan(&department.ID, &department.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		departments = append(departments, department)
+	}
+
+	return departments, d.translate(rows.Err())
+}
+
+// UpdateDepartment writes the new name after reading and locking the row,
+// a statement leaving the name as it was affects no row on MySQL.
+func (d Database) UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	err := d.inTx(ctx, func(tx Database) error {
+		current, err := tx.lockDepartment(ctx, department.ID)
+		if err != nil || current.Name == department.Name {
+			return err
+		}
+
+		_, err = tx.conn().ExecContext(ctx, tx.rebind(DepartmentUpdateQuery), department.Name, department.ID)
+
+		return departmentError(tx.translate(err))
+	})
+
+	return department, err
+}
+
+// DeleteDepartment counts the employees in the department in the
+// transaction removing it, the foreign key of employee.department_id catches
+// any put in it meanwhile.
+func (d Database) DeleteDepartment(ctx context.Context, id int64) error {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	return d.inTx(ctx, func(tx Database) error {
+		_, err := tx.lockDepartment(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		var employees int64
+		err = tx.conn().QueryRowContext(ctx, tx.rebind(DepartmentUsedQuery), id).Scan(&employees)
+		if err != nil {
+			return tx.translate(err)
+		}
+
+		if employees > 0 {
+			return ErrDepartmentInUse
+		}
+
+		_, err = tx.conn().ExecContext(ctx, tx.rebind(DepartmentDeleteQuery), id)
+		err = tx.translate(err)
+		if errors.Is(err, ErrConstraint) {
+			return wrap(ErrDepartmentInUse, err)
+		}
+
+		return err
+	})
+}
+
+func (d Database) DepartmentSummaries(ctx context.Context) ([]models.DepartmentSummary, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(DepartmentSummaryQuery))
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var summaries []models.DepartmentSummary
+	for rows.Next() {
+		var summary models.DepartmentSummary
+		err = rows.Scan(&summary.DepartmentID, &summary.Currency, &summary.Headcount, &summary.Payroll)
+		if err != nil {
+			return nil, err
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, d.translate(rows.Err())
+}
+
+// checkDepartment reports ErrUnknownDepartment unless id is zero or names a
+// department.
+func (d Database) checkDepartment(ctx context.Context, id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	_, err := d.getDepartment(ctx, DepartmentQuery, id)
+	if errors.Is(err, ErrDepartmentNotFound) {
+		return ErrUnknownDepartment
+	}
+
+	return err
+}
+
+// checkMove checks the department changes move current to, when they move
+// it at all.
+func (d Database) checkMove(ctx context.Context, current models.Employee, changes models.EmployeeChanges) error {
+	if changes.DepartmentID == nil || *changes.DepartmentID == current.DepartmentID {
+		return nil
+	}
+
+	return d.checkDepartment(ctx, *changes.DepartmentID)
+}
+
+// checkDepartments checks the department of every employee, once each,
+// failing with an *ItemError naming the first employee in an unknown one.
+func (d Database) checkDepartments(ctx context.Context, employees []models.Employee) error {
+	checked := make(map[int64]bool)
+	for i, employee := range employees {
+		if checked[employee.DepartmentID] {
+			continue
+		}
+
+		err := d.checkDepartment(ctx, employee.DepartmentID)
+		if errors.Is(err, ErrUnknownDepartment) {
+			return &ItemError{Index: i, Err: err}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		checked[employee.DepartmentID] = true
+	}
+
+	return nil
+}
+
+func (s *memoryState) createDepartment(department models.Department) (int64, error) {
+	for _, existing := range s.departments {
+		if existing.Name == department.Name {
+			return 0, ErrDepartmentExists
+		}
+	}
+
+	s.lastDepartmentID++
+	department.ID = s.lastDepartmentID
+	s.departments[department.ID] = department
+
+	return department.ID, nil
+}
+
+func (s *memoryState) getDepartment(id int64) (models.Department, error) {
+	department, ok := s.departments[id]
+	if !ok {
+		return department, ErrDepartmentNotFound
+	}
+
+	return department, nil
+}
+
+func (s *memoryState) listDepartments() []models.Department {
+	departments := make([]models.Department, 0, len(s.departments))
+	for _, department := range s.departments {
+		departments = append(departments, department)
+	}
+
+	sort.Slice(departments, func(i, j int) bool { return departments[i].ID < departments[j].ID })
+
+	return departments
+}
+
+func (s *memoryState) updateDepartment(department models.Department) (models.Department, error) {
+	_, err := s.getDepartment(department.ID)
+	if err != nil {
+		return department, err
+	}
+
+	for _, existing := range s.departments {
+		if existing.ID != department.ID && existing.Name == department.Name {
+			return department, ErrDepartmentExists
+		}
+	}
+
+	s.departments[department.ID] = department
+
+	return department, nil
+}
+
+func (s *memoryState) deleteDepartment(id int64) error {
+	_, err := s.getDepartment(id)
+	if err != nil {
+		return err
+	}
+
+	for _, employee := range s.employees {
+		if employee.DepartmentID == id {
+			return ErrDepartmentInUse
+		}
+	}
+
+	delete(s.departments, id)
+
+	return nil
+}
+
+func (s *memoryState) departmentSummaries() []models.DepartmentSummary {
+	type group struct {
+		departmentID int64
+		currency     string
+	}
+
+	totals := make(map[group]*models.DepartmentSummary)
+	for _, employee := range s.employees {
+		if employee.DeletedAt != nil {
+			continue
+		}
+
+		g := group{employee.DepartmentID, employee.Currency}
+		if totals[g] == nil {
+			totals[g] = &models.DepartmentSummary{DepartmentID: g.departmentID, Currency: g.currency}
+		}
+
+		totals[g].Headcount++
+		totals[g].Payroll += employee.Salary
+	}
+
+	var summaries []models.DepartmentSummary
+	for _, summary := range totals {
+		summaries = append(summaries, *summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].DepartmentID != summaries[j].DepartmentID {
+			return summaries[i].DepartmentID < summaries[j].DepartmentID
+		}
+
+		return summaries[i].Currency < summaries[j].Currency
+	})
+
+	return summaries
+}
+
+// checkDepartment is Database.checkDepartment for the employees held in
+// memory.
+func (s *memoryState) checkDepartment(id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	if _, ok := s.departments[id]; !ok {
+		return ErrUnknownDepartment
+	}
+
+	return nil
+}
+
+func (s *memoryState) checkMove(current models.Employee, changes models.EmployeeChanges) error {
+	if changes.DepartmentID == nil || *changes.DepartmentID == current.DepartmentID {
+		return nil
+	}
+
+	return s.checkDepartment(*changes.DepartmentID)
+}
@@ -1,112 +1,369 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"strings"
+	"time"
 
+	"example.com/m/Assesment/audit"
 	"example.com/m/Assesment/models"
 )
 
+// Timeouts bounds how long a single operation may run on top of whatever
+// deadline the caller's context already carries. Zero means no extra bound.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
 type Database struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Dialect  Dialect
+	Timeouts Timeouts
+
+	// tx is set on the Database handed to WithTx callbacks
+	tx *sql.Tx
+}
+
+func New(db *sql.DB, dialect Dialect, timeouts Timeouts) Database {
+	return Database{DB: db, Dialect: dialect, Timeouts: timeouts}
 }
 
-func New(db *sql.DB) Database {
-	return Database{DB: db}
+// rebind adapts a query written with "?" placeholders to the dialect in use,
+// MySQL when none was set.
+func (d Database) rebind(query string) string {
+	return d.dialect().Rebind(query)
 }
 
-func (d Database) Create(employee models.Employee) (int64, error) {
+func (d Database) dialect() Dialect {
+	if d.Dialect == nil {
+		return MySQL
+	}
+
+	return d.Dialect
+}
+
+// Create inserts the employee and records its creation in the audit log, in
+// one transaction. The manager's row is locked so it can't be deleted before
+// the employee reporting to it is committed.
+func (d Database) Create(ctx context.Context, employee models.Employee) (int64, error) {
 	var id int64
 
-	query := CreateQuery
-	result, err := d.DB.Exec(query, employee.Name, employee.Position, employee.Salary)
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	err := d.inTx(ctx, func(tx Database) error {
+		err := tx.checkDepartment(ctx, employee.DepartmentID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.lockManager(ctx, employee.ManagerID)
+		if err != nil {
+			return err
+		}
+
+		id, err = tx.create(ctx, employee)
+
+		return err
+	})
+
+	return id, err
+}
+
+// create inserts the employee and records its creation, once its department
+// and manager were checked.
+func (d Database) create(ctx context.Context, employee models.Employee) (int64, error) {
+	id, err := d.insert(ctx, employee)
 	if err != nil {
 		return id, err
 	}
 
-	id, err = result.LastInsertId()
+	stored := created(employee, id)
+
+	return id, d.changed(ctx, audit.OpCreate, nil, &stored)
+}
+
+func (d Database) insert(ctx context.Context, employee models.Employee) (int64, error) {
+	return d.insertID(ctx, CreateQuery, employee.Name, employee.Position, employee.Salary, employee.Currency, nullableID(employee.DepartmentID), nullableID(employee.ManagerID))
+}
+
+// insertID runs an insert and returns the id generated for its row.
+func (d Database) insertID(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var id int64
+
+	if !d.dialect().LastInsertID() {
+		err := d.conn().QueryRowContext(ctx, d.rebind(query+" returning id"), args...).Scan(&id)
+
+		return id, d.translate(err)
+	}
+
+	result, err := d.conn().ExecContext(ctx, d.rebind(query), args...)
 	if err != nil {
-		return id, err
+		return id, d.translate(err)
 	}
 
-	return id, err
+	return result.LastInsertId()
+}
+
+// Update writes the fields present in changes and returns the employee as
+// stored. The row is read and locked first in the transaction of the update,
+// which settles the version check when changes.IfVersion is set and gives the
+// audit log its before image. An empty change set only checks that the
+// employee exists at that version, and isn't recorded.
+func (d Database) Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	if changes.Empty() {
+		current, err := d.Get(ctx, id)
+		if err != nil {
+			return current, err
+		}
+
+		return current, checkVersion(current, changes.IfVersion)
+	}
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	var employee models.Employee
+
+	err := d.inTx(ctx, func(tx Database) error {
+		current, err := tx.getForUpdate(ctx, GetQuery, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(current, changes.IfVersion)
+		if err != nil {
+			return err
+		}
+
+		err = checkPrecision(current, changes)
+		if err != nil {
+			return err
+		}
+
+		err = tx.checkMove(ctx, current, changes)
+		if err != nil {
+			return err
+		}
+
+		err = tx.checkReporting(ctx, current, changes)
+		if err != nil {
+			return err
+		}
+
+		employee, err = tx.updateRow(ctx, id, changes)
+		if err != nil {
+			return err
+		}
+
+		return tx.changed(ctx, audit.OpUpdate, &current, &employee)
+	})
+
+	return employee, err
+}
+
+// getForUpdate reads the employee with query, locking its row until the
+// transaction ends where the dialect can.
+func (d Database) getForUpdate(ctx context.Context, query string, id int64) (models.Employee, error) {
+	if d.dialect().LockRows() {
+		query = query + lockClause
+	}
+
+	return d.scanRow(d.conn().QueryRowContext(ctx, d.rebind(query), id))
+}
+
+// execOne runs a statement expected to write the row read before it.
+func (d Database) execOne(ctx context.Context, query string, args ...interface{}) error {
+	result, err := d.conn().ExecContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return d.translate(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// the row changed since it was read, only possible where it wasn't locked
+	if affected == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// checkPrecision reports ErrSalaryPrecision when changes set the salary or
+// the currency of current to a pair that doesn't fit, the change set only
+// holding what changes.
+func checkPrecision(current models.Employee, changes models.EmployeeChanges) error {
+	if changes.Salary == nil && changes.Currency == nil {
+		return nil
+	}
+
+	employee := changes.Apply(current)
+	if !employee.Salary.Fits(employee.Currency) {
+		return ErrSalaryPrecision
+	}
+
+	return nil
+}
+
+// updateRow writes changes over the employee read and locked before it in
+// the transaction, and returns the row as stored: read back with "returning"
+// where the dialect can, read again under the lock otherwise.
+func (d Database) updateRow(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	query, args := updateQuery(id, changes)
+
+	if !d.dialect().Returning() {
+		err := d.execOne(ctx, query, args...)
+		if err != nil {
+			return models.Employee{}, err
+		}
+
+		return d.scanRow(d.conn().QueryRowContext(ctx, d.rebind(GetQuery), id))
+	}
+
+	employee, err := d.scanRow(d.conn().QueryRowContext(ctx, d.rebind(query+returningColumns), args...))
+	// the row changed since it was read, only possible where it wasn't locked
+	if errors.Is(err, ErrNotFound) {
+		return employee, ErrConflict
+	}
+
+	return employee, err
 }
 
-func (d Database) Update(employee models.Employee, id int64) error {
-	// building query to accomodate partial update
-	query := "update employee set "
+// updateQuery builds the update statement for the fields present in the
+// change set.
+func updateQuery(id int64, changes models.EmployeeChanges) (string, []interface{}) {
+	var sets []string
 	var args []interface{}
 
-	if employee.Name != "" {
-		query = query + "name = ?,"
-		args = append(args, employee.Name)
+	if changes.Name != nil {
+		sets = append(sets, "name = ?")
+		args = append(args, *changes.Name)
 	}
 
-	if employee.Position != "" {
-		query = query + "position = ?,"
-		args = append(args, employee.Position)
+	if changes.Position != nil {
+		sets = append(sets, "position = ?")
+		args = append(args, *changes.Position)
 	}
 
-	if employee.Salary != 0 {
-		query = query + "salary = ?,"
-		args = append(args, employee.Salary)
+	if changes.Salary != nil {
+		sets = append(sets, "salary = ?")
+		args = append(args, *changes.Salary)
 	}
 
-	query = strings.TrimSuffix(query, ",")
-	query = query + " where id = ?"
+	if changes.Currency != nil {
+		sets = append(sets, "currency = ?")
+		args = append(args, *changes.Currency)
+	}
+
+	if changes.DepartmentID != nil {
+		sets = append(sets, "department_id = ?")
+		args = append(args, nullableID(*changes.DepartmentID))
+	}
+
+	if changes.ManagerID != nil {
+		sets = append(sets, "manager_id = ?")
+		args = append(args, nullableID(*changes.ManagerID))
+	}
+
+	sets = append(sets, "version = version + 1")
+
+	query := "update employee set " + strings.Join(sets, ", ") + " where id = ? and deleted_at is null"
 	args = append(args, id)
 
-	_, err := d.DB.Exec(query, args...)
-	if err != nil {
-		return err
+	return query, args
+}
+
+// scanRow reads a single employee row, ErrNotFound when there is none.
+func (d Database) scanRow(row *sql.Row) (models.Employee, error) {
+	employee, err := scanEmployee(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return employee, ErrNotFound
 	}
 
-	return err
+	return employee, d.translate(err)
 }
 
-func (d Database) Get(id int64) (models.Employee, error) {
+// scanner is a *sql.Row or *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEmployee reads the columns selected by GetQuery and ListQuery.
+func scanEmployee(s scanner) (models.Employee, error) {
 	var employee models.Employee
+	var departmentID, managerID sql.NullInt64
+	var deletedAt sql.NullTime
 
-	rows, err := d.DB.Query(GetQuery, id)
-	if err != nil {
-		return employee, err
+	err := s.Scan(&employee.ID, &employee.Name, &employee.Position, &employee.Salary, &employee.Currency, &departmentID, &managerID, &employee.Version, &deletedAt)
+	employee.DepartmentID = departmentID.Int64
+	employee.ManagerID = managerID.Int64
+	if deletedAt.Valid {
+		t := deletedAt.Time.UTC()
+		employee.DeletedAt = &t
 	}
 
-	defer rows.Close()
+	return employee, err
+}
+
+func (d Database) Get(ctx context.Context, id int64) (models.Employee, error) {
+	var employee models.Employee
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
 
-	if rows.Err() != nil {
-		return employee, err
+	rows, err := d.conn().QueryContext(ctx, d.rebind(GetQuery), id)
+	if err != nil {
+		return employee, d.translate(err)
 	}
 
+	defer rows.Close()
+
+	found := false
 	for rows.Next() {
-		err = rows.Scan(&employee.ID, &employee.Name, &employee.Position, &employee.Salary)
+		employee, err = scanEmployee(rows)
 		if err != nil {
 			return employee, err
 		}
+
+		found = true
 	}
 
-	return employee, err
+	// surfaces cancellation or deadline hit while iterating
+	err = rows.Err()
+	if err != nil {
+		return employee, d.translate(err)
+	}
+
+	if !found {
+		return employee, ErrNotFound
+	}
+
+	return employee, nil
 }
 
-func (d Database) GetAll(page, pageLimit int) ([]models.Employee, error) {
+func (d Database) GetAll(ctx context.Context, page, pageLimit int) ([]models.Employee, error) {
 	var employee []models.Employee
 
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
 	offset := (page - 1) * pageLimit
 
-	rows, err := d.DB.Query(GetAllQuery, pageLimit, offset)
+	rows, err := d.conn().QueryContext(ctx, d.rebind(GetAllQuery), pageLimit, offset)
 	if err != nil {
-		return employee, err
+		return employee, d.translate(err)
 	}
 
 	defer rows.Close()
 
-	if rows.Err() != nil {
-		return employee, err
-	}
-
 	for rows.Next() {
-		var e models.Employee
-		err = rows.Scan(&e.ID, &e.Name, &e.Position, &e.Salary)
+		e, err := scanEmployee(rows)
 		if err != nil {
 			return employee, err
 		}
@@ -114,14 +371,61 @@ func (d Database) GetAll(page, pageLimit int) ([]models.Employee, error) {
 		employee = append(employee, e)
 	}
 
-	return employee, err
+	return employee, d.translate(rows.Err())
 }
 
-func (d Database) Delete(id int64) error {
-	_, err := d.DB.Exec(DeleteQuery, id)
-	if err != nil {
-		return err
+// Delete marks the employee deleted, it stays in the table until purged. An
+// employee others still report to can't be deleted.
+func (d Database) Delete(ctx context.Context, id int64, ifVersion int64) error {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	return d.inTx(ctx, func(tx Database) error {
+		current, err := tx.getForUpdate(ctx, GetQuery, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(current, ifVersion)
+		if err != nil {
+			return err
+		}
+
+		managing, err := tx.managing(ctx, []int64{id})
+		if err != nil {
+			return err
+		}
+
+		if managing >= 0 {
+			return ErrHasReports
+		}
+
+		at := deletionTime()
+		err = tx.execOne(ctx, DeleteQuery, at, id)
+		if err != nil {
+			return err
+		}
+
+		deleted := markedDeleted(current, at)
+
+		return tx.changed(ctx, audit.OpDelete, &current, &deleted)
+	})
+}
+
+// checkVersion reports ErrVersionMismatch unless ifVersion is zero or the
+// employee's version.
+func checkVersion(current models.Employee, ifVersion int64) error {
+	if ifVersion != 0 && current.Version != ifVersion {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
 
-	return err
+	return context.WithTimeout(ctx, timeout)
 }
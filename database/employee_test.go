@@ -1,52 +1,96 @@
 package database
 
 import (
+	"context"
+	"database/sql/driver"
 	"errors"
 	"testing"
+	"time"
 
+	"example.com/m/Assesment/audit"
 	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 )
 
+// expectAuditInsert expects the audit entry of a change by the system actor.
+func expectAuditInsert(mock sqlmock.Sqlmock, id int64, operation string, version int64) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(AuditInsertQuery).
+		WithArgs(id, operation, audit.SystemActor, "", sqlmock.AnyArg(), version, sqlmock.AnyArg())
+}
+
+// expectVersionClosed and expectVersionOpened expect the history writes of a
+// change, closing the employee's current version and opening the next one.
+func expectVersionClosed(mock sqlmock.Sqlmock, id int64) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(HistoryCloseQuery).WithArgs(sqlmock.AnyArg(), id)
+}
+
+func expectVersionOpened(mock sqlmock.Sqlmock, e models.Employee) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(HistoryInsertQuery).
+		WithArgs(e.ID, e.Version, e.Name, e.Position, e.Salary, e.Currency, nil, nil, sqlmock.AnyArg())
+}
+
 func TestCreate(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	assert.NoError(t, err)
 	defer db.Close()
 
 	database := Database{DB: db}
+	ctx := context.Background()
 
-	employee := models.Employee{Name: "John Doe", Position: "Software Engineer", Salary: 70000}
+	employee := models.Employee{Name: "John Doe", Position: "Software Engineer", Salary: money.Major(70000), Currency: "USD"}
 
-	// success case
+	// success case, recorded in the same transaction
+	mock.ExpectBegin()
 	mock.ExpectExec(CreateQuery).
-		WithArgs(employee.Name, employee.Position, employee.Salary).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAuditInsert(mock, 1, audit.OpCreate, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectVersionOpened(mock, created(employee, 1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	_, err = database.Create(employee)
+	_, err = database.Create(ctx, employee)
 	if err != nil {
 		t.Error(err)
 	}
 
 	// lastInsertID error case
+	mock.ExpectBegin()
 	mock.ExpectExec(CreateQuery).
-		WithArgs(employee.Name, employee.Position, employee.Salary).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
 		WillReturnResult(sqlmock.NewErrorResult(errors.New("test error")))
+	mock.ExpectRollback()
 
-	_, err = database.Create(employee)
+	_, err = database.Create(ctx, employee)
 	if err == nil {
 		t.Error(err)
 	}
 
 	// error from db case
+	mock.ExpectBegin()
 	mock.ExpectExec(CreateQuery).
-		WithArgs(employee.Name, employee.Position, employee.Salary).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
 		WillReturnError(errors.New("test error"))
+	mock.ExpectRollback()
 
-	_, err = database.Create(employee)
+	_, err = database.Create(ctx, employee)
 	if err == nil {
 		t.Error(err)
 	}
+
+	// a change that can't be recorded isn't made
+	mock.ExpectBegin()
+	mock.ExpectExec(CreateQuery).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	expectAuditInsert(mock, 2, audit.OpCreate, 1).WillReturnError(errors.New("test error"))
+	mock.ExpectRollback()
+
+	_, err = database.Create(ctx, employee)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGet(t *testing.T) {
@@ -55,15 +99,16 @@ func TestGet(t *testing.T) {
 	defer db.Close()
 
 	database := Database{DB: db}
+	ctx := context.Background()
 
-	employee := models.Employee{ID: 1, Name: "John Doe", Position: "Software Engineer", Salary: 70000}
+	employee := models.Employee{ID: 1, Name: "John Doe", Position: "Software Engineer", Salary: money.Major(70000), Currency: "USD"}
 
 	// success case
 	mock.ExpectQuery(GetQuery).
 		WithArgs(employee.ID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary"}).AddRow(employee.ID, employee.Name, employee.Position, employee.Salary))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}).AddRow(employee.ID, employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, employee.Version, nil))
 
-	resp, err := database.Get(employee.ID)
+	resp, err := database.Get(ctx, employee.ID)
 	if err != nil {
 		t.Error(err)
 	}
@@ -75,7 +120,7 @@ func TestGet(t *testing.T) {
 		WithArgs(employee.ID).
 		WillReturnError(errors.New("test error"))
 
-	_, err = database.Get(employee.ID)
+	_, err = database.Get(ctx, employee.ID)
 	if err == nil {
 		t.Error(err)
 	}
@@ -83,9 +128,9 @@ func TestGet(t *testing.T) {
 	// rowscan error case
 	mock.ExpectQuery(GetQuery).
 		WithArgs(employee.ID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary"}).AddRow("apple", employee.Name, employee.Position, employee.Salary))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}).AddRow("apple", employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, employee.Version, nil))
 
-	_, err = database.Get(employee.ID)
+	_, err = database.Get(ctx, employee.ID)
 	if err == nil {
 		t.Error(err)
 	}
@@ -97,19 +142,20 @@ func TestGetAll(t *testing.T) {
 	defer db.Close()
 
 	database := Database{DB: db}
+	ctx := context.Background()
 
 	page := 1
 	pageLimit := 5
 	offset := (page - 1) * pageLimit
-	employee := models.Employee{ID: 1, Name: "John Doe", Position: "Software Engineer", Salary: 70000}
+	employee := models.Employee{ID: 1, Name: "John Doe", Position: "Software Engineer", Salary: money.Major(70000), Currency: "USD"}
 	resp := []models.Employee{employee}
 
 	// success case
 	mock.ExpectQuery(GetAllQuery).
 		WithArgs(pageLimit, offset).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary"}).AddRow(employee.ID, employee.Name, employee.Position, employee.Salary))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}).AddRow(employee.ID, employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, employee.Version, nil))
 
-	result, err := database.GetAll(page, pageLimit)
+	result, err := database.GetAll(ctx, page, pageLimit)
 	if err != nil {
 		t.Error(err)
 	}
@@ -121,7 +167,7 @@ func TestGetAll(t *testing.T) {
 		WithArgs(pageLimit, offset).
 		WillReturnError(errors.New("test error"))
 
-	_, err = database.GetAll(page, pageLimit)
+	_, err = database.GetAll(ctx, page, pageLimit)
 	if err == nil {
 		t.Error(err)
 	}
@@ -129,9 +175,9 @@ func TestGetAll(t *testing.T) {
 	// rowscan error case
 	mock.ExpectQuery(GetAllQuery).
 		WithArgs(pageLimit, offset).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary"}).AddRow("apple", employee.Name, employee.Position, employee.Salary))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}).AddRow("apple", employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, employee.Version, nil))
 
-	_, err = database.GetAll(page, pageLimit)
+	_, err = database.GetAll(ctx, page, pageLimit)
 	if err == nil {
 		t.Error(err)
 	}
@@ -143,28 +189,51 @@ func TestDelete(t *testing.T) {
 	defer db.Close()
 
 	database := Database{DB: db}
+	ctx := context.Background()
 
 	var id int64 = 1
+	columns := []string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}
+	lockQuery := GetQuery + " for update"
 
 	// success case
-	mock.ExpectExec(DeleteQuery).
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(id, "John Doe", "SDE", money.Major(10000), "USD", nil, nil, 1, nil))
+	mock.ExpectQuery(ReportingQuery + "(?)").
 		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "manager_id"}))
+	mock.ExpectExec(DeleteQuery).
+		WithArgs(sqlmock.AnyArg(), id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAuditInsert(mock, id, audit.OpDelete, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectVersionClosed(mock, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	err = database.Delete(id)
+	err = database.Delete(ctx, id, 0)
 	if err != nil {
 		t.Error(err)
 	}
 
 	// error from db case
-	mock.ExpectExec(DeleteQuery).
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(id, "John Doe", "SDE", money.Major(10000), "USD", nil, nil, 1, nil))
+	mock.ExpectQuery(ReportingQuery + "(?)").
 		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "manager_id"}))
+	mock.ExpectExec(DeleteQuery).
+		WithArgs(sqlmock.AnyArg(), id).
 		WillReturnError(errors.New("test error"))
+	mock.ExpectRollback()
 
-	err = database.Delete(id)
+	err = database.Delete(ctx, id, 0)
 	if err == nil {
 		t.Error(err)
 	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdate(t *testing.T) {
@@ -173,27 +242,254 @@ func TestUpdate(t *testing.T) {
 	defer db.Close()
 
 	database := Database{DB: db}
+	ctx := context.Background()
 
 	var id int64 = 1
-	employee := models.Employee{Name: "John Doe", Position: "SDE-2", Salary: 20000}
-
-	// success case
-	mock.ExpectExec("update employee set name = ?,position = ?,salary = ? where id = ?").
-		WithArgs(employee.Name, employee.Position, employee.Salary, id).
+	columns := []string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}
+	current := models.Employee{ID: id, Name: "John Doe", Position: "SDE", Salary: money.Major(10000), Currency: "USD", Version: 1}
+	employee := models.Employee{ID: id, Name: "John Doe", Position: "SDE-2", Salary: money.Major(20000), Currency: "USD", Version: 2}
+	lockQuery := GetQuery + " for update"
+	updateQuery := "update employee set name = ?, position = ?, salary = ?, currency = ?, department_id = ?, manager_id = ?, version = version + 1 where id = ? and deleted_at is null"
+
+	// success case, the row is locked, read back once written and its audit
+	// entry written in the transaction of the update
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(current.ID, current.Name, current.Position, current.Salary, current.Currency, nil, nil, current.Version, nil))
+	mock.ExpectExec(updateQuery).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(GetQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(employee.ID, employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, employee.Version, nil))
+	mock.ExpectExec(AuditInsertQuery).
+		WithArgs(id, audit.OpUpdate, audit.SystemActor, "", sqlmock.AnyArg(), employee.Version,
+			`[{"field":"position","before":"SDE","after":"SDE-2"},{"field":"salary","before":10000,"after":20000}]`).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectVersionClosed(mock, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectVersionOpened(mock, employee).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	err = database.Update(employee, id)
-	if err != nil {
-		t.Error(err)
-	}
+	resp, err := database.Update(ctx, id, models.ChangesFrom(employee))
+	assert.NoError(t, err)
+	assert.Equal(t, employee, resp)
 
-	// error from db case
-	mock.ExpectExec("update employee set name = ?,position = ?,salary = ? where id = ?").
-		WithArgs(employee.Name, employee.Position, employee.Salary, id).
+	// error from db case rolls back
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(current.ID, current.Name, current.Position, current.Salary, current.Currency, nil, nil, current.Version, nil))
+	mock.ExpectExec(updateQuery).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, id).
 		WillReturnError(errors.New("test error"))
+	mock.ExpectRollback()
 
-	err = database.Update(employee, id)
+	_, err = database.Update(ctx, id, models.ChangesFrom(employee))
 	if err == nil {
 		t.Error(err)
 	}
+
+	// only present fields are written, zero values included
+	salary := money.Amount(0)
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(current.ID, current.Name, current.Position, current.Salary, current.Currency, nil, nil, current.Version, nil))
+	mock.ExpectExec("update employee set salary = ?, version = version + 1 where id = ? and deleted_at is null").
+		WithArgs(salary, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(GetQuery).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(current.ID, current.Name, current.Position, salary, current.Currency, nil, nil, int64(2), nil))
+	expectAuditInsert(mock, id, audit.OpUpdate, 2).WillReturnResult(sqlmock.NewResult(2, 1))
+	expectVersionClosed(mock, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectVersionOpened(mock, models.Employee{ID: id, Name: current.Name, Position: current.Position, Salary: salary, Currency: current.Currency, Version: 2}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	resp, err = database.Update(ctx, id, models.EmployeeChanges{Salary: &salary})
+	assert.NoError(t, err)
+	assert.Equal(t, salary, resp.Salary)
+	assert.Equal(t, int64(2), resp.Version)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancellation(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	employee := models.Employee{ID: 1, Name: "John Doe", Position: "Software Engineer", Salary: money.Major(70000), Currency: "USD"}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"}).AddRow(employee.ID, employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, employee.Version, nil)
+	}
+
+	// caller cancelled before the query was sent
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	database := Database{DB: db}
+
+	_, err = database.Get(ctx, employee.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = database.Delete(ctx, employee.ID, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// per-operation timeouts cut off slow queries, database/sql rolls back
+	// the transactions they cancel
+	database = New(db, MySQL, Timeouts{Read: 10 * time.Millisecond, Write: 10 * time.Millisecond})
+
+	mock.ExpectQuery(GetQuery).
+		WithArgs(employee.ID).
+		WillDelayFor(time.Second).
+		WillReturnRows(rows())
+
+	_, err = database.Get(context.Background(), employee.ID)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(GetAllQuery).
+		WithArgs(5, 0).
+		WillDelayFor(time.Second).
+		WillReturnRows(rows())
+
+	_, err = database.GetAll(context.Background(), 1, 5)
+	assert.Error(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(CreateQuery).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
+		WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	_, err = database.Create(context.Background(), employee)
+	assert.Error(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(GetQuery + " for update").
+		WithArgs(employee.ID).
+		WillDelayFor(time.Second).
+		WillReturnRows(rows())
+
+	err = database.Delete(context.Background(), employee.ID, 0)
+	assert.Error(t, err)
+
+	// a generous timeout lets the query through
+	database = New(db, MySQL, Timeouts{Read: time.Second})
+
+	mock.ExpectQuery(GetQuery).
+		WithArgs(employee.ID).
+		WillReturnRows(rows())
+
+	resp, err := database.Get(context.Background(), employee.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, employee, resp)
+}
+
+func TestCreateManyConsecutiveIDs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	database := New(db, MySQL, Timeouts{})
+	ctx := context.Background()
+
+	employees := []models.Employee{
+		{Name: "John Doe", Position: "SDE", Salary: money.Major(10000), Currency: "USD"},
+		{Name: "Jane Doe", Position: "QA", Salary: money.Major(20000), Currency: "USD"},
+	}
+	lockMode := func(mode int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"lock_mode", "increment"}).AddRow(mode, 1)
+	}
+
+	// consecutive lock mode, one insert whose ids follow the first
+	mock.ExpectBegin()
+	mock.ExpectQuery(AutoIncrementQuery).WillReturnRows(lockMode(1))
+	mock.ExpectExec(CreateManyQuery+"(?, ?, ?, ?, ?, ?, 1), (?, ?, ?, ?, ?, ?, 1)").
+		WithArgs(employees[0].Name, employees[0].Position, employees[0].Salary, employees[0].Currency, nil, nil,
+			employees[1].Name, employees[1].Position, employees[1].Salary, employees[1].Currency, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 2))
+	for i, employee := range employees {
+		expectAuditInsert(mock, int64(7+i), audit.OpCreate, 1).WillReturnResult(sqlmock.NewResult(int64(1+i), 1))
+		expectVersionOpened(mock, created(employee, int64(7+i))).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	ids, err := database.CreateMany(ctx, employees)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{7, 8}, ids)
+
+	// interleaved lock mode, the rows are inserted one by one
+	mock.ExpectBegin()
+	mock.ExpectQuery(AutoIncrementQuery).WillReturnRows(lockMode(2))
+	for i, employee := range employees {
+		mock.ExpectExec(CreateQuery).
+			WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
+			WillReturnResult(sqlmock.NewResult(int64(10+3*i), 1))
+		expectAuditInsert(mock, int64(10+3*i), audit.OpCreate, 1).WillReturnResult(sqlmock.NewResult(int64(3+i), 1))
+		expectVersionOpened(mock, created(employee, int64(10+3*i))).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	ids, err = database.CreateMany(ctx, employees)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{10, 13}, ids)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeByChunks(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	database := New(db, MySQL, Timeouts{Write: time.Second})
+	before := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	purgeableQuery := PurgeableQuery + lockClause
+
+	// a full chunk is committed on its own and another one follows, until a
+	// chunk comes back short
+	expectChunk := func(from, n int64) {
+		rows := sqlmock.NewRows([]string{"id", "name", "position", "salary", "currency", "department_id", "manager_id", "version", "deleted_at"})
+		var args []driver.Value
+		for id := from; id < from+n; id++ {
+			rows.AddRow(id, "John Doe", "SDE", money.Major(10000), "USD", nil, nil, 2, before.Add(-time.Hour))
+			args = append(args, id)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(purgeableQuery).WithArgs(before, purgeChunkSize).WillReturnRows(rows)
+		if n > 0 {
+			list, _ := idList(make([]int64, n))
+			mock.ExpectExec(PurgeQuery + list).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, n))
+			mock.ExpectExec(HistoryPurgeQuery + list).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, n))
+			mock.ExpectExec(SchedulePurgeQuery + list).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(CompensationPurgeQuery + list).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 0))
+			for id := from; id < from+n; id++ {
+				expectAuditInsert(mock, id, audit.OpPurge, 2).WillReturnResult(sqlmock.NewResult(id, 1))
+			}
+		}
+		mock.ExpectCommit()
+	}
+
+	expectChunk(1, purgeChunkSize)
+	expectChunk(purgeChunkSize+1, 2)
+
+	purged, err := database.Purge(context.Background(), before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(purgeChunkSize+2), purged)
+
+	// a failing chunk keeps the ones committed before it
+	expectChunk(1, purgeChunkSize)
+	mock.ExpectBegin()
+	mock.ExpectQuery(purgeableQuery).WithArgs(before, purgeChunkSize).WillReturnError(errors.New("test error"))
+	mock.ExpectRollback()
+
+	purged, err = database.Purge(context.Background(), before)
+	assert.Error(t, err)
+	assert.Equal(t, int64(purgeChunkSize), purged)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by implementations of Employee, drivers' errors are wrapped
+// so callers can match them with errors.Is.
+var (
+	ErrNotFound    = errors.New("employee not found")
+	ErrDuplicate   = errors.New("duplicate employee")
+	ErrConstraint  = errors.New("constraint violation")
+	ErrConflict    = errors.New("conflicting concurrent change")
+	ErrUnavailable = errors.New("database unavailable")
+
+	// ErrChangeNotFound is a scheduled change missing or already applied.
+	ErrChangeNotFound = errors.New("scheduled change not found")
+	// ErrProposalNotFound is a compensation change missing for the
+	// employee.
+	ErrProposalNotFound = errors.New("compensation change not found")
+	// ErrSelfApproval refuses the approval of a compensation change by
+	// whoever proposed it.
+	ErrSelfApproval = errors.New("compensation change approved by its proposer")
+	// ErrDepartmentNotFound is a department missing.
+	ErrDepartmentNotFound = errors.New("department not found")
+
+	// ErrVersionMismatch is the conflict of a conditional change whose
+	// expected version is no longer current.
+	ErrVersionMismatch = fmt.Errorf("%w: version mismatch", ErrConflict)
+	// ErrNotDeleted is the conflict of restoring an employee that isn't
+	// deleted.
+	ErrNotDeleted = fmt.Errorf("%w: employee is not deleted", ErrConflict)
+	// ErrDecided is the conflict of deciding a compensation change twice.
+	ErrDecided = fmt.Errorf("%w: compensation change already decided", ErrConflict)
+	// ErrStaleProposal is the conflict of approving a compensation change
+	// proposed from a salary that is no longer current.
+	ErrStaleProposal = fmt.Errorf("%w: salary changed since the proposal", ErrConflict)
+	// ErrCompensationScheduled is the conflict of cancelling the scheduled
+	// change an approved compensation change waits in.
+	ErrCompensationScheduled = fmt.Errorf("%w: scheduled by an approved compensation change", ErrConflict)
+	// ErrSalaryPrecision is an employee whose salary would have more
+	// decimals than its currency allows, the change setting one of them.
+	ErrSalaryPrecision = fmt.Errorf("%w: salary finer than its currency", ErrConstraint)
+	// ErrDepartmentInUse is the conflict of deleting a department some
+	// employee is in, deleted ones included until purged.
+	ErrDepartmentInUse = fmt.Errorf("%w: department has employees", ErrConflict)
+	// ErrDepartmentExists is a department named like another.
+	ErrDepartmentExists = fmt.Errorf("%w: department name taken", ErrDuplicate)
+	// ErrUnknownDepartment is an employee put in a department that doesn't
+	// exist.
+	ErrUnknownDepartment = fmt.Errorf("%w: unknown department", ErrConstraint)
+	// ErrHasReports is the conflict of deleting an employee others still
+	// report to.
+	ErrHasReports = fmt.Errorf("%w: employee has direct reports", ErrConflict)
+	// ErrUnknownManager is an employee made to report to one that doesn't
+	// exist or is deleted.
+	ErrUnknownManager = fmt.Errorf("%w: unknown manager", ErrConstraint)
+	// ErrManagerCycle is an employee made to report to itself or to one of
+	// the employees under it.
+	ErrManagerCycle = fmt.Errorf("%w: reporting cycle", ErrConstraint)
+)
+
+// wrap marks err as one of the errors above while keeping the driver error
+// reachable with errors.As.
+func wrap(kind, err error) error {
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// translate classifies an error returned by a query: first by the dialect's
+// error codes, then by the failures every driver shares.
+func (d Database) translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	err = d.dialect().TranslateError(err)
+
+	switch {
+	case errors.Is(err, ErrDuplicate), errors.Is(err, ErrConstraint),
+		errors.Is(err, ErrConflict), errors.Is(err, ErrUnavailable):
+		return err
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, driver.ErrBadConn):
+		// the query timeout or a dropped connection, worth retrying later
+		return wrap(ErrUnavailable, err)
+	}
+
+	return err
+}
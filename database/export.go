@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"example.com/m/Assesment/models"
+)
+
+// ExportOptions selects the employees of an export: those matching Filter,
+// in the order of Sort, by id when empty.
+type ExportOptions struct {
+	Filter Filter
+	Sort   []SortKey
+}
+
+func (o ExportOptions) Validate() error {
+	err := validateSort(o.Sort)
+	if err != nil {
+		return err
+	}
+
+	return validateSalary(o.Filter, o.Sort)
+}
+
+func (o ExportOptions) orderKeys() []SortKey {
+	return ListOptions{Sort: o.Sort}.orderKeys()
+}
+
+// Export streams the employees to fn straight from the cursor of a single
+// query, which reads one consistent snapshot of the table. It is bounded by
+// ctx only, not by the read timeout, as exports of the whole table may take
+// long.
+func (d Database) Export(ctx context.Context, opts ExportOptions, fn func(models.Employee) error) error {
+	err := opts.Validate()
+	if err != nil {
+		return err
+	}
+
+	conditions, args := opts.Filter.conditions()
+
+	query := ListQuery
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+
+	query += " order by " + d.orderBy(opts.orderKeys())
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return d.translate(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return err
+		}
+
+		err = fn(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.translate(rows.Err())
+}
+
+// export is Export over the employees held outside SQL.
+func (s *memoryState) export(opts ExportOptions) ([]models.Employee, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []models.Employee
+	for _, employee := range s.employees {
+		if opts.Filter.matches(employee) {
+			matching = append(matching, employee)
+		}
+	}
+
+	keys := opts.orderKeys()
+	sort.Slice(matching, func(i, j int) bool {
+		return compareKeysets(KeysetOf(matching[i]), KeysetOf(matching[j]), keys) < 0
+	})
+
+	return matching, nil
+}
+
+// streamTo hands the employees to fn until it fails or ctx is done.
+func streamTo(ctx context.Context, employees []models.Employee, fn func(models.Employee) error) error {
+	for _, employee := range employees {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn(employee)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"example.com/m/Assesment/models"
+)
+
+func (d Database) Reports(ctx context.Context, id int64) ([]models.Employee, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	employees, err := d.getMany(ctx, ReportsQuery, id, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the manager is read with its reports, in id order
+	reports := make([]models.Employee, 0, len(employees))
+	found := false
+	for _, employee := range employees {
+		if employee.ID == id {
+			found = true
+			continue
+		}
+
+		reports = append(reports, employee)
+	}
+
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return reports, nil
+}
+
+func (d Database) Chain(ctx context.Context, id int64) ([]models.Employee, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	return d.below(ctx, ChainQuery, id)
+}
+
+func (d Database) Subtree(ctx context.Context, id int64) ([]models.Employee, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	return d.below(ctx, SubtreeQuery, id)
+}
+
+// below reads the employees of a recursive query by depth, leaving out the
+// employee it starts from at depth zero, ErrNotFound when there is none.
+func (d Database) below(ctx context.Context, query string, id int64) ([]models.Employee, error) {
+	employees, err := d.getMany(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(employees) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return employees[1:], nil
+}
+
+// getMany reads the employees selected by query.
+func (d Database) getMany(ctx context.Context, query string, args ...interface{}) ([]models.Employee, error) {
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		employee, err := scanEmployee(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		employees = append(employees, employee)
+	}
+
+	return employees, d.translate(rows.Err())
+}
+
+// lockManager reports ErrUnknownManager unless id is zero or names an
+// employee that isn't deleted, whose row it locks until the transaction ends
+// where the dialect can. Deleting the manager waits for the lock, then finds
+// the employee made to report to it.
+func (d Database) lockManager(ctx context.Context, id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	_, err := d.getForUpdate(ctx, GetQuery, id)
+	if errors.Is(err, ErrNotFound) {
+		return ErrUnknownManager
+	}
+
+	return err
+}
+
+// lockManagers locks the manager of every employee, once each, failing with
+// an *ItemError naming the first employee with an unknown one.
+func (d Database) lockManagers(ctx context.Context, employees []models.Employee) error {
+	locked := make(map[int64]bool)
+	for i, employee := range employees {
+		if locked[employee.ManagerID] {
+			continue
+		}
+
+		err := d.lockManager(ctx, employee.ManagerID)
+		if errors.Is(err, ErrUnknownManager) {
+			return &ItemError{Index: i, Err: err}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		locked[employee.ManagerID] = true
+	}
+
+	return nil
+}
+
+// checkManager locks the new manager of employee id and reports
+// ErrManagerCycle when it is the employee itself or under it. The chain is
+// read once the manager is locked, so a concurrent change of the managers
+// above it either commits first and is seen, or waits for this one.
+func (d Database) checkManager(ctx context.Context, id, managerID int64) error {
+	if managerID == id {
+		return ErrManagerCycle
+	}
+
+	err := d.lockManager(ctx, managerID)
+	if err != nil || managerID == 0 {
+		return err
+	}
+
+	chain, err := d.getMany(ctx, ChainQuery, managerID)
+	if err != nil {
+		return err
+	}
+
+	for _, manager := range chain {
+		if manager.ID == id {
+			return ErrManagerCycle
+		}
+	}
+
+	return nil
+}
+
+// checkReporting checks the manager changes make current report to, when
+// they change it at all.
+func (d Database) checkReporting(ctx context.Context, current models.Employee, changes models.EmployeeChanges) error {
+	if changes.ManagerID == nil || *changes.ManagerID == current.ManagerID {
+		return nil
+	}
+
+	return d.checkManager(ctx, current.ID, *changes.ManagerID)
+}
+
+// managing returns the index of the first of ids that an employee not among
+// them reports to, -1 when there is none.
+func (d Database) managing(ctx context.Context, ids []int64) (int, error) {
+	list, args := idList(ids)
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(ReportingQuery+list), args...)
+	if err != nil {
+		return -1, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	index := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	first := -1
+	for rows.Next() {
+		var id, managerID int64
+		err = rows.Scan(&id, &managerID)
+		if err != nil {
+			return -1, err
+		}
+
+		if _, ok := index[id]; ok {
+			continue
+		}
+
+		if i := index[managerID]; first == -1 || i < first {
+			first = i
+		}
+	}
+
+	return first, d.translate(rows.Err())
+}
+
+// managerGone reports whether the employee's manager was deleted or purged,
+// locking the manager's row otherwise, as lockManager does.
+func (d Database) managerGone(ctx context.Context, employee models.Employee) (bool, error) {
+	err := d.lockManager(ctx, employee.ManagerID)
+	if errors.Is(err, ErrUnknownManager) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+func (s *memoryState) reports(id int64) ([]models.Employee, error) {
+	_, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []models.Employee{}
+	for _, employee := range s.employees {
+		if employee.ManagerID == id && employee.DeletedAt == nil {
+			reports = append(reports, employee)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID < reports[j].ID })
+
+	return reports, nil
+}
+
+// chain follows the managers of the employee up to one reporting to nobody.
+func (s *memoryState) chain(id int64) ([]models.Employee, error) {
+	employee, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []models.Employee{}
+	for employee.ManagerID != 0 {
+		employee, err = s.get(employee.ManagerID)
+		if err != nil {
+			break
+		}
+
+		chain = append(chain, employee)
+	}
+
+	return chain, nil
+}
+
+// subtree walks the employees under id breadth first, each level in id
+// order.
+func (s *memoryState) subtree(id int64) ([]models.Employee, error) {
+	_, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	subtree := []models.Employee{}
+	level := []models.Employee{{ID: id}}
+	for len(level) > 0 {
+		var next []models.Employee
+		for _, manager := range level {
+			reports, _ := s.reports(manager.ID)
+			next = append(next, reports...)
+		}
+
+		sort.Slice(next, func(i, j int) bool { return next[i].ID < next[j].ID })
+
+		subtree = append(subtree, next...)
+		level = next
+	}
+
+	return subtree, nil
+}
+
+// checkManager is Database.checkManager for the employees held in memory.
+func (s *memoryState) checkManager(id, managerID int64) error {
+	if managerID == id {
+		return ErrManagerCycle
+	}
+
+	err := s.knownManager(managerID)
+	if err != nil || managerID == 0 {
+		return err
+	}
+
+	chain, _ := s.chain(managerID)
+	for _, manager := range chain {
+		if manager.ID == id {
+			return ErrManagerCycle
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryState) checkReporting(current models.Employee, changes models.EmployeeChanges) error {
+	if changes.ManagerID == nil || *changes.ManagerID == current.ManagerID {
+		return nil
+	}
+
+	return s.checkManager(current.ID, *changes.ManagerID)
+}
+
+// knownManager reports ErrUnknownManager unless id is zero or names an
+// employee that isn't deleted.
+func (s *memoryState) knownManager(id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	if _, err := s.get(id); err != nil {
+		return ErrUnknownManager
+	}
+
+	return nil
+}
+
+// managing is Database.managing for the employees held in memory.
+func (s *memoryState) managing(ids []int64) int {
+	index := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	first := -1
+	for _, employee := range s.employees {
+		i, ok := index[employee.ManagerID]
+		if !ok || employee.DeletedAt != nil {
+			continue
+		}
+
+		if _, deleted := index[employee.ID]; deleted {
+			continue
+		}
+
+		if first == -1 || i < first {
+			first = i
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+)
+
+// changed records a change of the employee from before to after, either nil
+// where it doesn't exist, in the audit log and the history, in the
+// transaction making it.
+func (d Database) changed(ctx context.Context, operation string, before, after *models.Employee) error {
+	entry := audit.NewEntry(ctx, operation, before, after)
+
+	return d.changedAt(ctx, entry, before, after, entry.At)
+}
+
+// changedAt is changed with the audit entry given and the version written
+// valid from the given time.
+func (d Database) changedAt(ctx context.Context, entry audit.Entry, before, after *models.Employee, validFrom time.Time) error {
+	err := d.record(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	if live(before) {
+		_, err = d.conn().ExecContext(ctx, d.rebind(HistoryCloseQuery), validFrom, before.ID)
+		if err != nil {
+			return d.translate(err)
+		}
+	}
+
+	if !live(after) {
+		return nil
+	}
+
+	_, err = d.conn().ExecContext(ctx, d.rebind(HistoryInsertQuery),
+		after.ID, after.Version, after.Name, after.Position, after.Salary, after.Currency, nullableID(after.DepartmentID), nullableID(after.ManagerID), validFrom)
+
+	return d.translate(err)
+}
+
+// live reports whether the employee exists and isn't deleted.
+func live(employee *models.Employee) bool {
+	return employee != nil && employee.DeletedAt == nil
+}
+
+func (d Database) GetAsOf(ctx context.Context, id int64, at time.Time) (models.Employee, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	at = at.UTC()
+
+	version, err := scanVersion(d.conn().QueryRowContext(ctx, d.rebind(AsOfQuery), id, at, at))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Employee{}, ErrNotFound
+	}
+
+	return version.Employee, d.translate(err)
+}
+
+func (d Database) History(ctx context.Context, id int64) ([]models.EmployeeVersion, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(HistoryQuery), id)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var versions []models.EmployeeVersion
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return versions, nil
+}
+
+// scanVersion reads the columns selected by HistoryQuery and AsOfQuery.
+func scanVersion(s scanner) (models.EmployeeVersion, error) {
+	var version models.EmployeeVersion
+	var departmentID, managerID sql.NullInt64
+	var validTo sql.NullTime
+
+	err := s.Scan(&version.ID, &version.Name, &version.Position, &version.Salary, &version.Currency, &departmentID, &managerID, &version.Version, &version.ValidFrom, &validTo)
+	version.DepartmentID = departmentID.Int64
+	version.ManagerID = managerID.Int64
+	version.ValidFrom = version.ValidFrom.UTC()
+
+	if validTo.Valid {
+		t := validTo.Time.UTC()
+		version.ValidTo = &t
+	}
+
+	return version, err
+}
+
+func (s *memoryState) changed(ctx context.Context, operation string, before, after *models.Employee) {
+	entry := audit.NewEntry(ctx, operation, before, after)
+	s.changedAt(entry, before, after, entry.At)
+}
+
+func (s *memoryState) changedAt(entry audit.Entry, before, after *models.Employee, validFrom time.Time) {
+	s.record(entry)
+
+	if live(before) {
+		if versions := s.history[before.ID]; len(versions) > 0 {
+			versions[len(versions)-1].ValidTo = &validFrom
+		}
+	}
+
+	if live(after) {
+		s.history[after.ID] = append(s.history[after.ID], models.EmployeeVersion{Employee: *after, ValidFrom: validFrom})
+	}
+}
+
+func (s *memoryState) asOf(id int64, at time.Time) (models.Employee, error) {
+	for _, version := range s.history[id] {
+		if !version.ValidFrom.After(at) && (version.ValidTo == nil || version.ValidTo.After(at)) {
+			return version.Employee, nil
+		}
+	}
+
+	return models.Employee{}, ErrNotFound
+}
+
+func (s *memoryState) versions(id int64) ([]models.EmployeeVersion, error) {
+	versions := s.history[id]
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return append([]models.EmployeeVersion(nil), versions...), nil
+}
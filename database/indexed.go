@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/search"
+)
+
+// Indexed is a Store keeping a search index in step with the employee
+// writes made through it, scheduled and approved changes included. Writes
+// inside WithTx reach the index once the transaction commits. Writes made
+// around it, by another instance sharing the database for example, are only
+// picked up by Rebuild.
+type Indexed struct {
+	Store
+	index *search.Index
+}
+
+func NewIndexed(store Store) *Indexed {
+	return &Indexed{Store: store, index: search.NewIndex()}
+}
+
+// rebuildPageSize is the number of employees read per page by Rebuild.
+const rebuildPageSize = 500
+
+// Rebuild replaces the index with every employee in the store.
+func (i *Indexed) Rebuild(ctx context.Context) error {
+	var employees []models.Employee
+	opts := ListOptions{Limit: rebuildPageSize}
+
+	for {
+		page, err := i.Store.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		employees = append(employees, page.Employees...)
+
+		if !page.More {
+			break
+		}
+
+		last := KeysetOf(page.Employees[len(page.Employees)-1])
+		opts.After = &last
+	}
+
+	i.index.Load(employees)
+
+	return nil
+}
+
+// Search returns up to limit employees matching query, best first.
+func (i *Indexed) Search(ctx context.Context, query string, limit int) ([]search.Hit, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return i.index.Search(query, limit), nil
+}
+
+func (i *Indexed) Create(ctx context.Context, employee models.Employee) (int64, error) {
+	id, err := i.Store.Create(ctx, employee)
+	if err == nil {
+		i.index.Put(created(employee, id))
+	}
+
+	return id, err
+}
+
+func (i *Indexed) CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	ids, err := i.Store.CreateMany(ctx, employees)
+	if err == nil {
+		for n, id := range ids {
+			i.index.Put(created(employees[n], id))
+		}
+	}
+
+	return ids, err
+}
+
+func (i *Indexed) Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	employee, err := i.Store.Update(ctx, id, changes)
+	if err == nil {
+		i.index.Put(employee)
+	}
+
+	return employee, err
+}
+
+func (i *Indexed) Delete(ctx context.Context, id int64, ifVersion int64) error {
+	err := i.Store.Delete(ctx, id, ifVersion)
+	if err == nil {
+		i.index.Remove(id)
+	}
+
+	return err
+}
+
+func (i *Indexed) DeleteMany(ctx context.Context, ids []int64) error {
+	err := i.Store.DeleteMany(ctx, ids)
+	if err == nil {
+		for _, id := range ids {
+			i.index.Remove(id)
+		}
+	}
+
+	return err
+}
+
+func (i *Indexed) Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	employee, err := i.Store.Restore(ctx, id, ifVersion)
+	if err == nil {
+		i.index.Put(employee)
+	}
+
+	return employee, err
+}
+
+func (i *Indexed) ApplyScheduled(ctx context.Context, now time.Time) (Applied, error) {
+	applied, err := i.Store.ApplyScheduled(ctx, now)
+	if err == nil {
+		for _, employee := range applied.Employees {
+			i.index.Put(employee)
+		}
+	}
+
+	return applied, err
+}
+
+// ApproveCompensation reads back an employee whose salary changed at once.
+func (i *Indexed) ApproveCompensation(ctx context.Context, id, changeID int64, now time.Time) (models.CompensationChange, error) {
+	change, err := i.Store.ApproveCompensation(ctx, id, changeID, now)
+	if err != nil || change.EffectiveAt.After(now) {
+		return change, err
+	}
+
+	employee, err := i.Store.Get(ctx, id)
+	if err == nil {
+		i.index.Put(employee)
+	}
+
+	// the approval stands, the index catches up on the next write
+	return change, nil
+}
+
+func (i *Indexed) WithTx(ctx context.Context, fn func(tx Employee) error) error {
+	var pending []func()
+
+	err := i.Store.WithTx(ctx, func(tx Employee) error {
+		return fn(&indexedTx{Employee: tx, index: i.index, pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, apply := range pending {
+		apply()
+	}
+
+	return nil
+}
+
+// created is the employee as stored by Create.
+func created(employee models.Employee, id int64) models.Employee {
+	employee.ID = id
+	employee.Version = models.InitialVersion
+
+	return employee
+}
+
+// indexedTx holds back the index changes of a transaction until it commits.
+type indexedTx struct {
+	Employee
+	index   *search.Index
+	pending *[]func()
+}
+
+func (t *indexedTx) later(apply func()) {
+	*t.pending = append(*t.pending, apply)
+}
+
+func (t *indexedTx) Create(ctx context.Context, employee models.Employee) (int64, error) {
+	id, err := t.Employee.Create(ctx, employee)
+	if err == nil {
+		t.later(func() { t.index.Put(created(employee, id)) })
+	}
+
+	return id, err
+}
+
+func (t *indexedTx) CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	ids, err := t.Employee.CreateMany(ctx, employees)
+	if err == nil {
+		t.later(func() {
+			for n, id := range ids {
+				t.index.Put(created(employees[n], id))
+			}
+		})
+	}
+
+	return ids, err
+}
+
+func (t *indexedTx) Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	employee, err := t.Employee.Update(ctx, id, changes)
+	if err == nil {
+		t.later(func() { t.index.Put(employee) })
+	}
+
+	return employee, err
+}
+
+func (t *indexedTx) Delete(ctx context.Context, id int64, ifVersion int64) error {
+	err := t.Employee.Delete(ctx, id, ifVersion)
+	if err == nil {
+		t.later(func() { t.index.Remove(id) })
+	}
+
+	return err
+}
+
+func (t *indexedTx) DeleteMany(ctx context.Context, ids []int64) error {
+	err := t.Employee.DeleteMany(ctx, ids)
+	if err == nil {
+		t.later(func() {
+			for _, id := range ids {
+				t.index.Remove(id)
+			}
+		})
+	}
+
+	return err
+}
+
+func (t *indexedTx) Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	employee, err := t.Employee.Restore(ctx, id, ifVersion)
+	if err == nil {
+		t.later(func() { t.index.Put(employee) })
+	}
+
+	return employee, err
+}
+
+func (t *indexedTx) WithTx(ctx context.Context, fn func(tx Employee) error) error {
+	return t.Employee.WithTx(ctx, func(tx Employee) error {
+		return fn(&indexedTx{Employee: tx, index: t.index, pending: t.pending})
+	})
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/m/Assesment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func searchIDs(t *testing.T, indexed *Indexed, query string) []int64 {
+	hits, err := indexed.Search(context.Background(), query, 10)
+	assert.NoError(t, err)
+
+	var result []int64
+	for _, hit := range hits {
+		result = append(result, hit.Employee.ID)
+	}
+
+	return result
+}
+
+func TestIndexed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	_, err := store.Create(ctx, models.Employee{Name: "John Doe", Position: "SDE", Salary: 1})
+	assert.NoError(t, err)
+
+	indexed := NewIndexed(store)
+	assert.Empty(t, searchIDs(t, indexed, "john"))
+
+	err = indexed.Rebuild(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, searchIDs(t, indexed, "john"))
+
+	id, err := indexed.Create(ctx, models.Employee{Name: "Jane Roe", Position: "Manager", Salary: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{id}, searchIDs(t, indexed, "jane"))
+
+	name := "Jane Smith"
+	_, err = indexed.Update(ctx, id, models.EmployeeChanges{Name: &name})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{id}, searchIDs(t, indexed, "smith"))
+	assert.Empty(t, searchIDs(t, indexed, "roe"))
+
+	// failed writes leave the index alone
+	_, err = indexed.Update(ctx, id, models.EmployeeChanges{Name: &name, IfVersion: 1})
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	err = indexed.Delete(ctx, id, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, searchIDs(t, indexed, "jane"))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = indexed.Search(ctx, "john", 10)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestIndexedTx(t *testing.T) {
+	ctx := context.Background()
+	indexed := NewIndexed(NewMemory())
+
+	errRollback := errors.New("rollback")
+	err := indexed.WithTx(ctx, func(tx Employee) error {
+		_, err := tx.Create(ctx, models.Employee{Name: "Rolled Back", Position: "SDE", Salary: 1})
+		assert.NoError(t, err)
+		assert.Empty(t, searchIDs(t, indexed, "rolled"))
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Empty(t, searchIDs(t, indexed, "rolled"))
+
+	err = indexed.WithTx(ctx, func(tx Employee) error {
+		id, err := tx.Create(ctx, models.Employee{Name: "Committed", Position: "SDE", Salary: 1})
+		if err != nil {
+			return err
+		}
+
+		// nested transactions join and hold back their changes too
+		return tx.WithTx(ctx, func(tx Employee) error {
+			position := "Manager"
+			_, err := tx.Update(ctx, id, models.EmployeeChanges{Position: &position})
+			return err
+		})
+	})
+	assert.NoError(t, err)
+	assert.Len(t, searchIDs(t, indexed, "committed manager"), 1)
+}
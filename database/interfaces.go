@@ -1,11 +1,160 @@
 package database
 
-import "example.com/m/Assesment/models"
+import (
+	"context"
+	"time"
 
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/search"
+)
+
+// Employee keeps employees: creating, reading, changing and deleting them,
+// and the audit log of those changes.
 type Employee interface {
-	Create(employee models.Employee) (int64, error)
-	Update(employee models.Employee, id int64) error
-	Get(id int64) (models.Employee, error)
-	GetAll(page, pageLimit int) ([]models.Employee, error)
-	Delete(id int64) error
+	Create(ctx context.Context, employee models.Employee) (int64, error)
+	// CreateMany creates all of the employees or none, returning their ids
+	// in order.
+	CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error)
+	// Update applies changes and bumps the version, failing with
+	// ErrVersionMismatch when changes.IfVersion is set and not current, and
+	// ErrSalaryPrecision when the salary and currency it leaves don't fit.
+	// It returns the employee as written, read atomically with the write.
+	Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error)
+	Get(ctx context.Context, id int64) (models.Employee, error)
+	GetAll(ctx context.Context, page, pageLimit int) ([]models.Employee, error)
+	// List reads a page of employees by keyset, see ListOptions.
+	List(ctx context.Context, opts ListOptions) (Page, error)
+	// Export hands every employee selected by opts to fn, in order, from a
+	// consistent snapshot. It stops at the first error fn returns.
+	Export(ctx context.Context, opts ExportOptions, fn func(models.Employee) error) error
+	// Delete marks the employee deleted, only at ifVersion unless it is
+	// zero. Deleted employees are hidden from every read but List and
+	// Export with Filter.IncludeDeleted, until restored or purged.
+	Delete(ctx context.Context, id int64, ifVersion int64) error
+	// DeleteMany deletes all of the employees or none. A missing employee,
+	// or one listed twice, fails with an *ItemError naming its index.
+	DeleteMany(ctx context.Context, ids []int64) error
+	// Restore undoes the deletion of the employee, only at ifVersion unless
+	// it is zero, and returns it as restored. Restoring an employee that
+	// isn't deleted fails with ErrNotDeleted.
+	Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error)
+	// Purge removes for good the employees deleted before the given time,
+	// returning how many there were.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// AuditLog reads the entries of the audit log selected by q, newest
+	// first. Every change above is recorded in the transaction making it,
+	// with the caller set on its context by audit.WithCaller.
+	AuditLog(ctx context.Context, q audit.Query) ([]audit.Entry, error)
+	// WithTx runs fn against a transaction scoped Employee, committing when
+	// fn returns nil and rolling back otherwise. Calls made inside an
+	// existing transaction join it.
+	WithTx(ctx context.Context, fn func(tx Employee) error) error
+}
+
+// History keeps every version of the employees written through Employee,
+// and changes scheduled to be made later.
+type History interface {
+	// GetAsOf reads the employee as it was at the given time, failing with
+	// ErrNotFound when it didn't exist or was deleted then.
+	GetAsOf(ctx context.Context, id int64, at time.Time) (models.Employee, error)
+	// History lists every version of the employee oldest first, failing
+	// with ErrNotFound when it has none.
+	History(ctx context.Context, id int64) ([]models.EmployeeVersion, error)
+	// Schedule stores a change of an existing employee to be made at its
+	// EffectiveAt, returning it with its id and caller set. It fails with
+	// ErrSalaryPrecision, ErrUnknownDepartment, ErrUnknownManager and
+	// ErrManagerCycle as Update would now.
+	Schedule(ctx context.Context, change models.ScheduledChange) (models.ScheduledChange, error)
+	// Scheduled lists the changes waiting for the employee, in the order
+	// they will be made.
+	Scheduled(ctx context.Context, id int64) ([]models.ScheduledChange, error)
+	// CancelScheduled drops a waiting change of the employee, failing with
+	// ErrChangeNotFound when there is none with that id and with
+	// ErrCompensationScheduled when an approved compensation change waits
+	// in it.
+	CancelScheduled(ctx context.Context, id, changeID int64) error
+	// ApplyScheduled makes every change due at now and returns the
+	// employees as changed, along with the changes it had to drop.
+	ApplyScheduled(ctx context.Context, now time.Time) (Applied, error)
+}
+
+// Compensation keeps the salary changes proposed for employees, made once
+// approved, at once or through History when effective later.
+type Compensation interface {
+	// ProposeCompensation stores a proposed salary change of an existing
+	// employee, from its current salary and position, as proposed by the
+	// caller of ctx. It fails with ErrSalaryPrecision for a salary finer
+	// than the employee's currency.
+	ProposeCompensation(ctx context.Context, change models.CompensationChange) (models.CompensationChange, error)
+	// Compensation lists the compensation changes of the employee newest
+	// first, only those with the status unless it is empty.
+	Compensation(ctx context.Context, id int64, status string) ([]models.CompensationChange, error)
+	// ApproveCompensation approves a proposed change as the caller of ctx,
+	// making it at once when effective by now and scheduling it otherwise.
+	// It fails with ErrProposalNotFound, ErrDecided, ErrSelfApproval or, when
+	// the salary changed since the proposal, ErrStaleProposal.
+	ApproveCompensation(ctx context.Context, id, changeID int64, now time.Time) (models.CompensationChange, error)
+	// RejectCompensation rejects a proposed change as the caller of ctx.
+	RejectCompensation(ctx context.Context, id, changeID int64) (models.CompensationChange, error)
+	// CompensationReport totals the approved changes selected by opts, by
+	// group and currency.
+	CompensationReport(ctx context.Context, opts ReportOptions) ([]models.RaiseSummary, error)
+}
+
+// Store keeps employees, their history and compensation, their departments
+// and reporting lines.
+type Store interface {
+	Employee
+	History
+	Compensation
+	Department
+	Hierarchy
+}
+
+// Department keeps the departments employees are in. Employees name their
+// department by id, Employee writes fail with ErrUnknownDepartment for one
+// that doesn't exist.
+type Department interface {
+	// CreateDepartment stores the department and returns its id, failing
+	// with ErrDepartmentExists when the name is taken.
+	CreateDepartment(ctx context.Context, department models.Department) (int64, error)
+	// GetDepartment fails with ErrDepartmentNotFound when there is none
+	// with the id.
+	GetDepartment(ctx context.Context, id int64) (models.Department, error)
+	// ListDepartments lists every department by id.
+	ListDepartments(ctx context.Context) ([]models.Department, error)
+	// UpdateDepartment renames the department with department.ID and
+	// returns it as stored.
+	UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error)
+	// DeleteDepartment removes a department no employee is in, failing
+	// with ErrDepartmentInUse otherwise.
+	DeleteDepartment(ctx context.Context, id int64) error
+	// DepartmentSummaries totals the employees that aren't deleted by
+	// department and currency, ordered by both. Employees in no department
+	// are totalled under department id zero.
+	DepartmentSummaries(ctx context.Context) ([]models.DepartmentSummary, error)
+}
+
+// Hierarchy reads the reporting lines between employees. Each employee
+// reports to the manager named by its ManagerID, Employee writes fail with
+// ErrUnknownManager for a missing or deleted one and ErrManagerCycle for one
+// under the employee, and deletes fail with ErrHasReports while others report
+// to it. Deleted employees are left out.
+type Hierarchy interface {
+	// Reports lists the employees reporting directly to the manager, by
+	// id, failing with ErrNotFound when there is no such employee.
+	Reports(ctx context.Context, id int64) ([]models.Employee, error)
+	// Chain lists the managers of the employee from its own up to one
+	// reporting to nobody.
+	Chain(ctx context.Context, id int64) ([]models.Employee, error)
+	// Subtree lists every employee under the manager, breadth first, each
+	// level by id.
+	Subtree(ctx context.Context, id int64) ([]models.Employee, error)
+}
+
+// Searcher finds employees by free text over their name and position.
+type Searcher interface {
+	// Search returns up to limit employees matching query, best first.
+	Search(ctx context.Context, query string, limit int) ([]search.Hit, error)
 }
@@ -0,0 +1,513 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+)
+
+// ListOptions selects a page of employees by keyset instead of offset, so
+// pages stay stable while employees are added or removed. The page starts
+// after After, or when Before is set instead it ends before Before.
+type ListOptions struct {
+	Filter Filter
+	// Sort orders the list, by id when empty. The id breaks any ties.
+	Sort   []SortKey
+	After  *Keyset
+	Before *Keyset
+	Limit  int
+	// Count asks for the number of matching employees along with the page.
+	Count bool
+}
+
+// Filter narrows a list to the employees matching all of its conditions,
+// zero values match every employee that isn't deleted.
+type Filter struct {
+	// IncludeDeleted lists deleted employees along with the others.
+	IncludeDeleted bool
+	// Positions matches any of the positions, ignoring case.
+	Positions []string
+	// Currencies matches any of the salary currencies.
+	Currencies []string
+	// Departments matches any of the departments, zero matching employees
+	// in none.
+	Departments []int64
+	// MinSalary and MaxSalary bound the salary, in the one currency of
+	// Currencies they need.
+	MinSalary *money.Amount
+	MaxSalary *money.Amount
+	// NamePrefix and NameContains match the name ignoring case.
+	NamePrefix   string
+	NameContains string
+}
+
+// SortKey orders a list by one of SortFields.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Keyset is the position of an employee in any sort order.
+type Keyset struct {
+	ID       int64
+	Name     string
+	Position string
+	Salary   money.Amount
+}
+
+func KeysetOf(employee models.Employee) Keyset {
+	return Keyset{ID: employee.ID, Name: employee.Name, Position: employee.Position, Salary: employee.Salary}
+}
+
+// Page is a page of employees in the list's sort order.
+type Page struct {
+	Employees []models.Employee
+	// More reports employees past the page in the direction it was read.
+	More bool
+	// Total is the number of matching employees, only set when asked for.
+	Total *int64
+}
+
+// ErrInvalidListOptions reports ListOptions that can't select a page.
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// sortColumns whitelists the fields a list can be sorted by, with their
+// column. Nothing else from a request reaches the SQL text.
+var sortColumns = map[string]string{
+	"id":       "id",
+	"name":     "name",
+	"position": "position",
+	"salary":   "salary",
+}
+
+// textFields are the sort fields holding text, ordered byte by byte so every
+// backend, and the keysets compared outside SQL, agree on the order.
+var textFields = map[string]bool{"name": true, "position": true}
+
+// sortColumn is the expression a list is ordered and its keysets compared
+// by for a sort field.
+func (d Database) sortColumn(field string) string {
+	if textFields[field] {
+		return d.dialect().ByteOrder(sortColumns[field])
+	}
+
+	return sortColumns[field]
+}
+
+// SortFields are the fields a list can be sorted by.
+var SortFields = []string{"id", "name", "position", "salary"}
+
+func (o ListOptions) Validate() error {
+	if o.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidListOptions)
+	}
+
+	if o.After != nil && o.Before != nil {
+		return fmt.Errorf("%w: only one of after and before can be set", ErrInvalidListOptions)
+	}
+
+	err := validateSort(o.Sort)
+	if err != nil {
+		return err
+	}
+
+	return validateSalary(o.Filter, o.Sort)
+}
+
+func validateSort(keys []SortKey) error {
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if _, ok := sortColumns[key.Field]; !ok {
+			return fmt.Errorf("%w: unknown sort field %q", ErrInvalidListOptions, key.Field)
+		}
+
+		if seen[key.Field] {
+			return fmt.Errorf("%w: duplicate sort field %q", ErrInvalidListOptions, key.Field)
+		}
+
+		seen[key.Field] = true
+	}
+
+	return nil
+}
+
+// validateSalary refuses salary bounds and a sort by salary unless the
+// filter keeps to one currency, as amounts of different currencies don't
+// compare.
+func validateSalary(filter Filter, keys []SortKey) error {
+	if len(filter.Currencies) == 1 {
+		return nil
+	}
+
+	if filter.MinSalary != nil || filter.MaxSalary != nil {
+		return fmt.Errorf("%w: salary bounds need a single currency", ErrInvalidListOptions)
+	}
+
+	for _, key := range keys {
+		if key.Field == "salary" {
+			return fmt.Errorf("%w: sorting by salary needs a single currency", ErrInvalidListOptions)
+		}
+	}
+
+	return nil
+}
+
+// backward reports a page read towards the start of the list.
+func (o ListOptions) backward() bool {
+	return o.Before != nil
+}
+
+// bound is the keyset the page is read from, nil on the first page.
+func (o ListOptions) bound() *Keyset {
+	if o.backward() {
+		return o.Before
+	}
+
+	return o.After
+}
+
+// orderKeys is the order the page is read in: the sort keys ended by the id,
+// all reversed for a page read backwards.
+func (o ListOptions) orderKeys() []SortKey {
+	keys := append([]SortKey(nil), o.Sort...)
+
+	byID := false
+	for _, key := range keys {
+		byID = byID || key.Field == "id"
+	}
+
+	if !byID {
+		keys = append(keys, SortKey{Field: "id"})
+	}
+
+	if o.backward() {
+		for i := range keys {
+			keys[i].Desc = !keys[i].Desc
+		}
+	}
+
+	return keys
+}
+
+// value is the keyset's value for a sort field.
+func (k Keyset) value(field string) interface{} {
+	switch field {
+	case "name":
+		return k.Name
+	case "position":
+		return k.Position
+	case "salary":
+		return k.Salary
+	default:
+		return k.ID
+	}
+}
+
+// newPage builds the page from up to Limit+1 employees read in the order of
+// opts, the extra one only telling that more follow.
+func newPage(employees []models.Employee, opts ListOptions) Page {
+	page := Page{Employees: employees}
+
+	if len(employees) > opts.Limit {
+		page.Employees = employees[:opts.Limit]
+		page.More = true
+	}
+
+	if opts.backward() {
+		for i, j := 0, len(page.Employees)-1; i < j; i, j = i+1, j-1 {
+			page.Employees[i], page.Employees[j] = page.Employees[j], page.Employees[i]
+		}
+	}
+
+	return page
+}
+
+func (d Database) List(ctx context.Context, opts ListOptions) (Page, error) {
+	err := opts.Validate()
+	if err != nil {
+		return Page{}, err
+	}
+
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	conditions, args := opts.Filter.conditions()
+	keys := opts.orderKeys()
+
+	query := ListQuery
+	if bound := opts.bound(); bound != nil {
+		condition, boundArgs := d.keysetCondition(keys, *bound)
+		query += " where " + strings.Join(append(append([]string(nil), conditions...), condition), " and ")
+		args = append(append([]interface{}(nil), args...), boundArgs...)
+	} else if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+
+	query += " order by " + d.orderBy(keys) + " limit ?"
+
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), append(args, opts.Limit+1)...)
+	if err != nil {
+		return Page{}, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return Page{}, err
+		}
+
+		employees = append(employees, e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return Page{}, d.translate(err)
+	}
+
+	page := newPage(employees, opts)
+
+	if opts.Count {
+		conditions, args := opts.Filter.conditions()
+
+		query := CountQuery
+		if len(conditions) > 0 {
+			query += " where " + strings.Join(conditions, " and ")
+		}
+
+		var total int64
+		err = d.conn().QueryRowContext(ctx, d.rebind(query), args...).Scan(&total)
+		if err != nil {
+			return page, d.translate(err)
+		}
+
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// conditions are the filter's where conditions, values are always bound.
+func (f Filter) conditions() ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if !f.IncludeDeleted {
+		conditions = append(conditions, "deleted_at is null")
+	}
+
+	if len(f.Positions) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(f.Positions)), ", ")
+		conditions = append(conditions, "lower(position) in ("+placeholders+")")
+		for _, position := range f.Positions {
+			args = append(args, strings.ToLower(position))
+		}
+	}
+
+	if len(f.Currencies) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(f.Currencies)), ", ")
+		conditions = append(conditions, "currency in ("+placeholders+")")
+		for _, currency := range f.Currencies {
+			args = append(args, currency)
+		}
+	}
+
+	if len(f.Departments) > 0 {
+		var ids []int64
+		none := false
+		for _, id := range f.Departments {
+			if id == 0 {
+				none = true
+				continue
+			}
+
+			ids = append(ids, id)
+		}
+
+		var alternatives []string
+		if len(ids) > 0 {
+			list, listArgs := idList(ids)
+			alternatives = append(alternatives, "department_id in "+list)
+			args = append(args, listArgs...)
+		}
+
+		if none {
+			alternatives = append(alternatives, "department_id is null")
+		}
+
+		conditions = append(conditions, "("+strings.Join(alternatives, " or ")+")")
+	}
+
+	if f.MinSalary != nil {
+		conditions = append(conditions, "salary >= ?")
+		args = append(args, *f.MinSalary)
+	}
+
+	if f.MaxSalary != nil {
+		conditions = append(conditions, "salary <= ?")
+		args = append(args, *f.MaxSalary)
+	}
+
+	if f.NamePrefix != "" {
+		conditions = append(conditions, "lower(name) like ? escape '!'")
+		args = append(args, escapeLike(strings.ToLower(f.NamePrefix))+"%")
+	}
+
+	if f.NameContains != "" {
+		conditions = append(conditions, "lower(name) like ? escape '!'")
+		args = append(args, "%"+escapeLike(strings.ToLower(f.NameContains))+"%")
+	}
+
+	return conditions, args
+}
+
+// likeEscaper makes like wildcards literal, "!" is the escape character as
+// backslash is itself an escape in MySQL string literals.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// keysetCondition selects the rows after bound in the order of keys, the
+// expanded form of a row value comparison so keys may mix directions:
+// (a > ?) or (a = ? and b > ?) or ...
+func (d Database) keysetCondition(keys []SortKey, bound Keyset) (string, []interface{}) {
+	var alternatives []string
+	var args []interface{}
+
+	for i, key := range keys {
+		var terms []string
+		for _, previous := range keys[:i] {
+			terms = append(terms, d.sortColumn(previous.Field)+" = ?")
+			args = append(args, bound.value(previous.Field))
+		}
+
+		op := " > ?"
+		if key.Desc {
+			op = " < ?"
+		}
+
+		terms = append(terms, d.sortColumn(key.Field)+op)
+		args = append(args, bound.value(key.Field))
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " and ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " or ") + ")", args
+}
+
+func (d Database) orderBy(keys []SortKey) string {
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		term := d.sortColumn(key.Field)
+		if key.Desc {
+			term += " desc"
+		}
+
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, ", ")
+}
+
+// matches is Filter.conditions for employees held outside SQL.
+func (f Filter) matches(employee models.Employee) bool {
+	if !f.IncludeDeleted && employee.DeletedAt != nil {
+		return false
+	}
+
+	if len(f.Positions) > 0 {
+		found := false
+		for _, position := range f.Positions {
+			found = found || strings.EqualFold(position, employee.Position)
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Currencies) > 0 {
+		found := false
+		for _, currency := range f.Currencies {
+			found = found || currency == employee.Currency
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Departments) > 0 {
+		found := false
+		for _, id := range f.Departments {
+			found = found || id == employee.DepartmentID
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if f.MinSalary != nil && employee.Salary < *f.MinSalary {
+		return false
+	}
+
+	if f.MaxSalary != nil && employee.Salary > *f.MaxSalary {
+		return false
+	}
+
+	name := strings.ToLower(employee.Name)
+
+	if f.NamePrefix != "" && !strings.HasPrefix(name, strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+
+	return f.NameContains == "" || strings.Contains(name, strings.ToLower(f.NameContains))
+}
+
+// compareKeysets orders a and b by keys the way the SQL order by does.
+func compareKeysets(a, b Keyset, keys []SortKey) int {
+	for _, key := range keys {
+		c := compareValues(a.value(key.Field), b.value(key.Field))
+		if key.Desc {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		// byte order, as sortColumn pins it in SQL
+		return strings.Compare(a, b.(string))
+	case money.Amount:
+		return compareOrdered(a, b.(money.Amount))
+	default:
+		return compareOrdered(a.(int64), b.(int64))
+	}
+}
+
+func compareOrdered[T int64 | money.Amount](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,799 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+)
+
+// Memory is an in-process implementation of Store for local development
+// and tests. It mirrors the behaviour of Database, including change sets,
+// versions and pagination, and is safe for concurrent use.
+type Memory struct {
+	mu    sync.RWMutex
+	state memoryState
+}
+
+func NewMemory() *Memory {
+	return &Memory{state: newMemoryState()}
+}
+
+func (m *Memory) Create(ctx context.Context, employee models.Employee) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.create(ctx, employee)
+}
+
+func (m *Memory) CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.createMany(ctx, employees)
+}
+
+func (m *Memory) Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.update(ctx, id, changes)
+}
+
+func (m *Memory) Get(ctx context.Context, id int64) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.get(id)
+}
+
+func (m *Memory) GetAll(ctx context.Context, page, pageLimit int) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.getAll(page, pageLimit)
+}
+
+func (m *Memory) List(ctx context.Context, opts ListOptions) (Page, error) {
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.list(opts)
+}
+
+// Export streams a snapshot taken under the lock, so a slow consumer
+// doesn't hold writers back.
+func (m *Memory) Export(ctx context.Context, opts ExportOptions, fn func(models.Employee) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	employees, err := m.state.export(opts)
+	m.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return streamTo(ctx, employees, fn)
+}
+
+func (m *Memory) Delete(ctx context.Context, id int64, ifVersion int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.delete(ctx, id, ifVersion)
+}
+
+func (m *Memory) DeleteMany(ctx context.Context, ids []int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.deleteMany(ctx, ids)
+}
+
+func (m *Memory) Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.restore(ctx, id, ifVersion)
+}
+
+func (m *Memory) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.purge(ctx, before), nil
+}
+
+func (m *Memory) AuditLog(ctx context.Context, q audit.Query) ([]audit.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.auditLog(q)
+}
+
+func (m *Memory) GetAsOf(ctx context.Context, id int64, at time.Time) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.asOf(id, at)
+}
+
+func (m *Memory) History(ctx context.Context, id int64) ([]models.EmployeeVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.versions(id)
+}
+
+func (m *Memory) Schedule(ctx context.Context, change models.ScheduledChange) (models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return change, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.schedule(ctx, change)
+}
+
+func (m *Memory) Scheduled(ctx context.Context, id int64) ([]models.ScheduledChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.pending(id), nil
+}
+
+func (m *Memory) CancelScheduled(ctx context.Context, id, changeID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.cancelScheduled(id, changeID)
+}
+
+func (m *Memory) ApplyScheduled(ctx context.Context, now time.Time) (Applied, error) {
+	if err := ctx.Err(); err != nil {
+		return Applied{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.applyScheduled(ctx, now), nil
+}
+
+func (m *Memory) ProposeCompensation(ctx context.Context, change models.CompensationChange) (models.CompensationChange, error) {
+	if err := ctx.Err(); err != nil {
+		return change, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.proposeCompensation(ctx, change)
+}
+
+func (m *Memory) Compensation(ctx context.Context, id int64, status string) ([]models.CompensationChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.compensationOf(id, status), nil
+}
+
+// ApproveCompensation works on a copy of the state like WithTx, so a failed
+// approval leaves no trace.
+func (m *Memory) ApproveCompensation(ctx context.Context, id, changeID int64, now time.Time) (models.CompensationChange, error) {
+	if err := ctx.Err(); err != nil {
+		return models.CompensationChange{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := m.state.clone()
+
+	change, err := state.approveCompensation(ctx, id, changeID, now)
+	if err == nil {
+		m.state = state
+	}
+
+	return change, err
+}
+
+func (m *Memory) RejectCompensation(ctx context.Context, id, changeID int64) (models.CompensationChange, error) {
+	if err := ctx.Err(); err != nil {
+		return models.CompensationChange{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.rejectCompensation(ctx, id, changeID)
+}
+
+func (m *Memory) CompensationReport(ctx context.Context, opts ReportOptions) ([]models.RaiseSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.compensationReport(opts)
+}
+
+func (m *Memory) CreateDepartment(ctx context.Context, department models.Department) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.createDepartment(department)
+}
+
+func (m *Memory) GetDepartment(ctx context.Context, id int64) (models.Department, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Department{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.getDepartment(id)
+}
+
+func (m *Memory) ListDepartments(ctx context.Context) ([]models.Department, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.listDepartments(), nil
+}
+
+func (m *Memory) UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	if err := ctx.Err(); err != nil {
+		return department, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.updateDepartment(department)
+}
+
+func (m *Memory) DeleteDepartment(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.deleteDepartment(id)
+}
+
+func (m *Memory) DepartmentSummaries(ctx context.Context) ([]models.DepartmentSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.departmentSummaries(), nil
+}
+
+func (m *Memory) Reports(ctx context.Context, id int64) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.reports(id)
+}
+
+func (m *Memory) Chain(ctx context.Context, id int64) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.chain(id)
+}
+
+func (m *Memory) Subtree(ctx context.Context, id int64) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state.subtree(id)
+}
+
+// WithTx holds the store's lock for the whole of fn, which works on a copy
+// of the employees that replaces them only when fn succeeds.
+func (m *Memory) WithTx(ctx context.Context, fn func(tx Employee) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{state: m.state.clone()}
+
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+
+	m.state = tx.state
+
+	return nil
+}
+
+// memoryTx is the Employee handed to Memory.WithTx callbacks, the caller
+// already holds the lock.
+type memoryTx struct {
+	state memoryState
+}
+
+func (t *memoryTx) Create(ctx context.Context, employee models.Employee) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return t.state.create(ctx, employee)
+}
+
+func (t *memoryTx) CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.state.createMany(ctx, employees)
+}
+
+func (t *memoryTx) Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	return t.state.update(ctx, id, changes)
+}
+
+func (t *memoryTx) Get(ctx context.Context, id int64) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	return t.state.get(id)
+}
+
+func (t *memoryTx) GetAll(ctx context.Context, page, pageLimit int) ([]models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.state.getAll(page, pageLimit)
+}
+
+func (t *memoryTx) List(ctx context.Context, opts ListOptions) (Page, error) {
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+
+	return t.state.list(opts)
+}
+
+func (t *memoryTx) Export(ctx context.Context, opts ExportOptions, fn func(models.Employee) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	employees, err := t.state.export(opts)
+	if err != nil {
+		return err
+	}
+
+	return streamTo(ctx, employees, fn)
+}
+
+func (t *memoryTx) Delete(ctx context.Context, id int64, ifVersion int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return t.state.delete(ctx, id, ifVersion)
+}
+
+func (t *memoryTx) DeleteMany(ctx context.Context, ids []int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return t.state.deleteMany(ctx, ids)
+}
+
+func (t *memoryTx) Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Employee{}, err
+	}
+
+	return t.state.restore(ctx, id, ifVersion)
+}
+
+func (t *memoryTx) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return t.state.purge(ctx, before), nil
+}
+
+func (t *memoryTx) AuditLog(ctx context.Context, q audit.Query) ([]audit.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.state.auditLog(q)
+}
+
+// WithTx joins the transaction in progress.
+func (t *memoryTx) WithTx(ctx context.Context, fn func(tx Employee) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return fn(t)
+}
+
+// memoryState holds the employees, deleted ones included until purged, the
+// audit log and history of their changes, the changes scheduled for later,
+// the compensation changes and the departments. Callers synchronise access to
+// it.
+type memoryState struct {
+	lastID             int64
+	employees          map[int64]models.Employee
+	lastAuditID        int64
+	audit              []audit.Entry
+	history            map[int64][]models.EmployeeVersion
+	lastScheduledID    int64
+	scheduled          []models.ScheduledChange
+	lastCompensationID int64
+	compensation       []models.CompensationChange
+	lastDepartmentID   int64
+	departments        map[int64]models.Department
+}
+
+func newMemoryState() memoryState {
+	return memoryState{
+		employees:   make(map[int64]models.Employee),
+		history:     make(map[int64][]models.EmployeeVersion),
+		departments: make(map[int64]models.Department),
+	}
+}
+
+func (s memoryState) clone() memoryState {
+	c := memoryState{
+		lastID:      s.lastID,
+		employees:   make(map[int64]models.Employee, len(s.employees)),
+		lastAuditID: s.lastAuditID,
+		// capped so appends to the copy never write into the original
+		audit:              s.audit[:len(s.audit):len(s.audit)],
+		history:            make(map[int64][]models.EmployeeVersion, len(s.history)),
+		lastScheduledID:    s.lastScheduledID,
+		scheduled:          s.scheduled[:len(s.scheduled):len(s.scheduled)],
+		lastCompensationID: s.lastCompensationID,
+		// copied, deciding a change writes into it
+		compensation:     append([]models.CompensationChange(nil), s.compensation...),
+		lastDepartmentID: s.lastDepartmentID,
+		departments:      make(map[int64]models.Department, len(s.departments)),
+	}
+
+	for id, department := range s.departments {
+		c.departments[id] = department
+	}
+
+	for id, employee := range s.employees {
+		c.employees[id] = employee
+	}
+
+	// copied, closing the current version writes into the last one
+	for id, versions := range s.history {
+		c.history[id] = append([]models.EmployeeVersion(nil), versions...)
+	}
+
+	return c
+}
+
+func (s *memoryState) create(ctx context.Context, employee models.Employee) (int64, error) {
+	err := s.checkDepartment(employee.DepartmentID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.knownManager(employee.ManagerID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.lastID++
+	employee = created(employee, s.lastID)
+	s.employees[employee.ID] = employee
+	s.changed(ctx, audit.OpCreate, nil, &employee)
+
+	return employee.ID, nil
+}
+
+// createMany checks every department and manager before creating any
+// employee.
+func (s *memoryState) createMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	for i, employee := range employees {
+		if err := s.checkDepartment(employee.DepartmentID); err != nil {
+			return nil, &ItemError{Index: i, Err: err}
+		}
+
+		if err := s.knownManager(employee.ManagerID); err != nil {
+			return nil, &ItemError{Index: i, Err: err}
+		}
+	}
+
+	var ids []int64
+	for _, employee := range employees {
+		id, err := s.create(ctx, employee)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (s *memoryState) update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	current, err := s.get(id)
+	if err != nil {
+		return current, err
+	}
+
+	err = checkVersion(current, changes.IfVersion)
+	if err != nil || changes.Empty() {
+		return current, err
+	}
+
+	err = checkPrecision(current, changes)
+	if err != nil {
+		return current, err
+	}
+
+	err = s.checkMove(current, changes)
+	if err != nil {
+		return current, err
+	}
+
+	err = s.checkReporting(current, changes)
+	if err != nil {
+		return current, err
+	}
+
+	employee := changes.Apply(current)
+	employee.Version++
+	s.employees[id] = employee
+	s.changed(ctx, audit.OpUpdate, &current, &employee)
+
+	return employee, nil
+}
+
+func (s *memoryState) get(id int64) (models.Employee, error) {
+	employee, ok := s.employees[id]
+	if !ok || employee.DeletedAt != nil {
+		return employee, ErrNotFound
+	}
+
+	return employee, nil
+}
+
+func (s *memoryState) getAll(page, pageLimit int) ([]models.Employee, error) {
+	var employee []models.Employee
+
+	offset := (page - 1) * pageLimit
+	if offset < 0 || pageLimit < 0 {
+		return employee, errors.New("invalid page or page limit")
+	}
+
+	ids := s.sortedIDs()
+	for i := offset; i < len(ids) && i < offset+pageLimit; i++ {
+		employee = append(employee, s.employees[ids[i]])
+	}
+
+	return employee, nil
+}
+
+func (s *memoryState) list(opts ListOptions) (Page, error) {
+	err := opts.Validate()
+	if err != nil {
+		return Page{}, err
+	}
+
+	var matching []models.Employee
+	for _, employee := range s.employees {
+		if opts.Filter.matches(employee) {
+			matching = append(matching, employee)
+		}
+	}
+
+	keys := opts.orderKeys()
+	sort.Slice(matching, func(i, j int) bool {
+		return compareKeysets(KeysetOf(matching[i]), KeysetOf(matching[j]), keys) < 0
+	})
+
+	// read from the keyset bound on, one past the limit
+	var employees []models.Employee
+	bound := opts.bound()
+	for _, employee := range matching {
+		if len(employees) > opts.Limit {
+			break
+		}
+
+		if bound == nil || compareKeysets(KeysetOf(employee), *bound, keys) > 0 {
+			employees = append(employees, employee)
+		}
+	}
+
+	page := newPage(employees, opts)
+
+	if opts.Count {
+		total := int64(len(matching))
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// sortedIDs are the ids of the employees that aren't deleted.
+func (s *memoryState) sortedIDs() []int64 {
+	ids := make([]int64, 0, len(s.employees))
+	for id, employee := range s.employees {
+		if employee.DeletedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func (s *memoryState) delete(ctx context.Context, id int64, ifVersion int64) error {
+	current, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(current, ifVersion)
+	if err != nil {
+		return err
+	}
+
+	if s.managing([]int64{id}) >= 0 {
+		return ErrHasReports
+	}
+
+	s.markDeleted(ctx, current, deletionTime())
+
+	return nil
+}
+
+func (s *memoryState) markDeleted(ctx context.Context, current models.Employee, at time.Time) {
+	deleted := markedDeleted(current, at)
+	s.employees[deleted.ID] = deleted
+	s.changed(ctx, audit.OpDelete, &current, &deleted)
+}
+
+// deleteMany checks every id before deleting any.
+func (s *memoryState) deleteMany(ctx context.Context, ids []int64) error {
+	err := checkDistinct(ids)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		if _, err := s.get(id); err != nil {
+			return &ItemError{Index: i, Err: err}
+		}
+	}
+
+	if managing := s.managing(ids); managing >= 0 {
+		return &ItemError{Index: managing, Err: ErrHasReports}
+	}
+
+	at := deletionTime()
+	for _, id := range ids {
+		s.markDeleted(ctx, s.employees[id], at)
+	}
+
+	return nil
+}
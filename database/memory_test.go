@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"example.com/m/Assesment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryConcurrentCreate(t *testing.T) {
+	store := NewMemory()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Create(ctx, models.Employee{Name: "John", Position: "SDE", Salary: 1})
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	all, err := store.GetAll(ctx, 1, 100)
+	assert.NoError(t, err)
+	assert.Len(t, all, 50)
+	assert.Equal(t, int64(50), all[49].ID)
+}
+
+func TestMemoryInvalidPage(t *testing.T) {
+	store := NewMemory()
+
+	_, err := store.GetAll(context.Background(), 0, 10)
+	assert.Error(t, err)
+}
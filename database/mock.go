@@ -1,35 +1,84 @@
 package database
 
 import (
+	"context"
+	"time"
+
+	"example.com/m/Assesment/audit"
 	"example.com/m/Assesment/models"
 	"github.com/stretchr/testify/mock"
 )
 
 type MockDatabase struct {
 	mock.Mock
-	CreateF func(employee models.Employee) (int64, error)
-	UpdateF func(employee models.Employee, id int64) error
-	GetF    func(id int64) (models.Employee, error)
-	GetAllF func(page, pageLimit int) ([]models.Employee, error)
-	DeleteF func(id int64) error
+	CreateF     func(ctx context.Context, employee models.Employee) (int64, error)
+	CreateManyF func(ctx context.Context, employees []models.Employee) ([]int64, error)
+	UpdateF     func(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error)
+	GetF        func(ctx context.Context, id int64) (models.Employee, error)
+	GetAllF     func(ctx context.Context, page, pageLimit int) ([]models.Employee, error)
+	ListF       func(ctx context.Context, opts ListOptions) (Page, error)
+	ExportF     func(ctx context.Context, opts ExportOptions, fn func(models.Employee) error) error
+	DeleteF     func(ctx context.Context, id int64, ifVersion int64) error
+	DeleteManyF func(ctx context.Context, ids []int64) error
+	RestoreF    func(ctx context.Context, id int64, ifVersion int64) (models.Employee, error)
+	PurgeF      func(ctx context.Context, before time.Time) (int64, error)
+	AuditLogF   func(ctx context.Context, q audit.Query) ([]audit.Entry, error)
+	// WithTxF defaults to running fn against the mock itself.
+	WithTxF func(ctx context.Context, fn func(tx Employee) error) error
+}
+
+func (m *MockDatabase) Create(ctx context.Context, employee models.Employee) (int64, error) {
+	return m.CreateF(ctx, employee)
+}
+
+func (m *MockDatabase) CreateMany(ctx context.Context, employees []models.Employee) ([]int64, error) {
+	return m.CreateManyF(ctx, employees)
+}
+
+func (m *MockDatabase) Update(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+	return m.UpdateF(ctx, id, changes)
+}
+
+func (m *MockDatabase) Get(ctx context.Context, id int64) (models.Employee, error) {
+	return m.GetF(ctx, id)
 }
 
-func (m *MockDatabase) Create(employee models.Employee) (int64, error) {
-	return m.CreateF(employee)
+func (m *MockDatabase) GetAll(ctx context.Context, page, pageLimit int) ([]models.Employee, error) {
+	return m.GetAllF(ctx, page, pageLimit)
 }
 
-func (m *MockDatabase) Update(employee models.Employee, id int64) error {
-	return m.UpdateF(employee, id)
+func (m *MockDatabase) List(ctx context.Context, opts ListOptions) (Page, error) {
+	return m.ListF(ctx, opts)
 }
 
-func (m *MockDatabase) Get(id int64) (models.Employee, error) {
-	return m.GetF(id)
+func (m *MockDatabase) Export(ctx context.Context, opts ExportOptions, fn func(models.Employee) error) error {
+	return m.ExportF(ctx, opts, fn)
 }
 
-func (m *MockDatabase) GetAll(page, pageLimit int) ([]models.Employee, error) {
-	return m.GetAllF(page, pageLimit)
+func (m *MockDatabase) Delete(ctx context.Context, id int64, ifVersion int64) error {
+	return m.DeleteF(ctx, id, ifVersion)
 }
 
-func (m *MockDatabase) Delete(id int64) error {
-	return m.DeleteF(id)
+func (m *MockDatabase) DeleteMany(ctx context.Context, ids []int64) error {
+	return m.DeleteManyF(ctx, ids)
+}
+
+func (m *MockDatabase) Restore(ctx context.Context, id int64, ifVersion int64) (models.Employee, error) {
+	return m.RestoreF(ctx, id, ifVersion)
+}
+
+func (m *MockDatabase) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return m.PurgeF(ctx, before)
+}
+
+func (m *MockDatabase) AuditLog(ctx context.Context, q audit.Query) ([]audit.Entry, error) {
+	return m.AuditLogF(ctx, q)
+}
+
+func (m *MockDatabase) WithTx(ctx context.Context, fn func(tx Employee) error) error {
+	if m.WithTxF == nil {
+		return fn(m)
+	}
+
+	return m.WithTxF(ctx, fn)
 }
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresRebind(t *testing.T) {
+	assert.Equal(t, "update employee set name = $1,salary = $2 where id = $3",
+		Postgres.Rebind("update employee set name = ?,salary = ? where id = ?"))
+	assert.Equal(t, "select 1", Postgres.Rebind("select 1"))
+}
+
+func TestPostgresTranslateError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "Unique violation", err: &pq.Error{Code: "23505"}, expected: ErrDuplicate},
+		{name: "Foreign key violation", err: &pq.Error{Code: "23503"}, expected: ErrConstraint},
+		{name: "Not null violation", err: &pq.Error{Code: "23502"}, expected: ErrConstraint},
+		{name: "Check violation", err: &pq.Error{Code: "23514"}, expected: ErrConstraint},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Postgres.TranslateError(tc.err)
+			assert.ErrorIs(t, err, tc.expected)
+
+			// the driver error stays reachable for logging
+			var pqErr *pq.Error
+			assert.True(t, errors.As(err, &pqErr))
+		})
+	}
+
+	other := errors.New("test error")
+	assert.Equal(t, other, Postgres.TranslateError(other))
+	assert.NoError(t, Postgres.TranslateError(nil))
+}
+
+func TestPostgresCreate(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	database := New(db, Postgres, Timeouts{})
+	ctx := context.Background()
+
+	employee := models.Employee{Name: "John Doe", Position: "Software Engineer", Salary: money.Major(70000), Currency: "USD"}
+	query := "insert into employee (name, position, salary, currency, department_id, manager_id, version) values ($1, $2, $3, $4, $5, $6, 1) returning id"
+
+	// success case, the id comes from "returning id" instead of LastInsertId
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(Postgres.Rebind(AuditInsertQuery)).
+		WithArgs(int64(7), audit.OpCreate, audit.SystemActor, "", sqlmock.AnyArg(), int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(Postgres.Rebind(HistoryInsertQuery)).
+		WithArgs(int64(7), int64(1), employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	id, err := database.Create(ctx, employee)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+
+	// duplicate case
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).
+		WithArgs(employee.Name, employee.Position, employee.Salary, employee.Currency, nil, nil).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	_, err = database.Create(ctx, employee)
+	assert.ErrorIs(t, err, ErrDuplicate)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresConformance(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectConformance(mock, Postgres)
+
+	testConformance(t, New(db, Postgres, Timeouts{}))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
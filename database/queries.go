@@ -1,6 +1,113 @@
 package database
 
-const CreateQuery string = "insert into employee values(?,?,?)"
-const GetQuery string = "select * from employee where id = ?"
-const DeleteQuery string = "delete from employee where id = ?"
-const GetAllQuery string = "SELECT id, name, position FROM employee LIMIT ? OFFSET ?"
+// queries are written with "?" placeholders and rebound per dialect
+const CreateQuery string = "insert into employee (name, position, salary, currency, department_id, manager_id, version) values (?, ?, ?, ?, ?, ?, 1)"
+const GetQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee where id = ? and deleted_at is null"
+const GetAllQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee where deleted_at is null order by id limit ? offset ?"
+
+// deleting an employee only marks it, Purge removes it for good
+const DeleteQuery string = "update employee set deleted_at = ?, version = version + 1 where id = ? and deleted_at is null"
+const RestoreQuery string = "update employee set deleted_at = null, version = version + 1 where id = ? and deleted_at is not null"
+
+// RestoreUnmanagedQuery restores an employee whose manager is gone, making it
+// report to nobody
+const RestoreUnmanagedQuery string = "update employee set deleted_at = null, manager_id = null, version = version + 1 where id = ? and deleted_at is not null"
+
+// PurgeableQuery selects a chunk of employees deleted before a time,
+// PurgeQuery is completed with their id list
+const PurgeableQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee where deleted_at < ? order by id limit ?"
+const PurgeQuery string = "delete from employee where id in "
+
+// GetAnyQuery reads the employee whether deleted or not.
+const GetAnyQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee where id = ?"
+
+// returningColumns reads back the row an update wrote, where the dialect
+// supports it
+const returningColumns string = " returning id, name, position, salary, currency, department_id, manager_id, version, deleted_at"
+
+// lockClause locks the rows a select reads until the transaction ends.
+const lockClause string = " for update"
+
+// ListQuery and CountQuery are completed with the list's where and order by
+const ListQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee"
+const CountQuery string = "select count(*) from employee"
+
+// CreateManyQuery is completed with one createManyRow per employee
+const CreateManyQuery string = "insert into employee (name, position, salary, currency, department_id, manager_id, version) values "
+const createManyRow string = "(?, ?, ?, ?, ?, ?, 1)"
+
+// AutoIncrementQuery reads the MySQL settings deciding whether the rows of
+// one insert get consecutive ids.
+const AutoIncrementQuery string = "select @@innodb_autoinc_lock_mode, @@auto_increment_increment"
+
+// ExistingQuery and DeleteManyQuery are completed with the id list
+const ExistingQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee where deleted_at is null and id in "
+const DeleteManyQuery string = "update employee set deleted_at = ?, version = version + 1 where deleted_at is null and id in "
+
+// AuditInsertQuery appends an entry to the audit log, AuditQuery is completed
+// with the log query's where, order by and limit
+const AuditInsertQuery string = "insert into employee_audit (employee_id, operation, actor, request_id, changed_at, version, changes) values (?, ?, ?, ?, ?, ?, ?)"
+const AuditQuery string = "select id, employee_id, operation, actor, request_id, changed_at, version, changes from employee_audit"
+
+// the history keeps every version of every employee with the span of time it
+// was valid, the open version is the current one
+const HistoryInsertQuery string = "insert into employee_history (employee_id, version, name, position, salary, currency, department_id, manager_id, valid_from) values (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const HistoryCloseQuery string = "update employee_history set valid_to = ? where employee_id = ? and valid_to is null"
+const HistoryOpenQuery string = "select valid_from from employee_history where employee_id = ? and valid_to is null"
+const HistoryQuery string = "select employee_id, name, position, salary, currency, department_id, manager_id, version, valid_from, valid_to from employee_history where employee_id = ? order by version"
+const AsOfQuery string = "select employee_id, name, position, salary, currency, department_id, manager_id, version, valid_from, valid_to from employee_history where employee_id = ? and valid_from <= ? and (valid_to is null or valid_to > ?)"
+
+// changes scheduled for later wait in employee_schedule until applied, a
+// null field is left untouched
+const ScheduleInsertQuery string = "insert into employee_schedule (employee_id, effective_at, name, position, salary, currency, department_id, manager_id, actor, request_id) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const ScheduledQuery string = "select id, employee_id, effective_at, name, position, salary, currency, department_id, manager_id, actor, request_id from employee_schedule where employee_id = ? order by effective_at, id"
+const DueQuery string = "select id, employee_id, effective_at, name, position, salary, currency, department_id, manager_id, actor, request_id from employee_schedule where effective_at <= ? order by effective_at, id"
+const CancelScheduledQuery string = "delete from employee_schedule where id = ? and employee_id = ?"
+const AppliedQuery string = "delete from employee_schedule where id = ?"
+
+// salary changes go through compensation_change, proposed then decided
+const CompensationInsertQuery string = "insert into compensation_change (employee_id, position, current_salary, salary, currency, reason, effective_at, status, proposed_by, proposed_at, note) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const CompensationQuery string = "select id, employee_id, position, current_salary, salary, currency, reason, effective_at, status, proposed_by, proposed_at, decided_by, decided_at, schedule_id, note from compensation_change"
+const CompensationDecideQuery string = "update compensation_change set status = ?, decided_by = ?, decided_at = ?, schedule_id = ? where id = ? and status = ?"
+
+// CompensationScheduledQuery counts the approved changes waiting in a
+// scheduled change
+const CompensationScheduledQuery string = "select count(*) from compensation_change where schedule_id = ?"
+
+// CompensationReportQuery is completed with the grouped column, the report
+// conditions and the grouping, always by currency too
+const CompensationReportQuery string = "select %[1]s, currency, count(*), sum(salary - current_salary), count(case when current_salary <> 0 then 1 end), sum(case when current_salary <> 0 then (salary - current_salary) * 100.0 / current_salary end) from compensation_change"
+
+// HistoryPurgeQuery, SchedulePurgeQuery and CompensationPurgeQuery are
+// completed with the id list of the purged employees
+const HistoryPurgeQuery string = "delete from employee_history where employee_id in "
+const SchedulePurgeQuery string = "delete from employee_schedule where employee_id in "
+const CompensationPurgeQuery string = "delete from compensation_change where employee_id in "
+
+// departments are referenced by employee.department_id, DepartmentUsedQuery
+// counts the employees in one whether deleted or not
+const DepartmentInsertQuery string = "insert into department (name) values (?)"
+const DepartmentQuery string = "select id, name from department where id = ?"
+const DepartmentsQuery string = "select id, name from department order by id"
+const DepartmentUpdateQuery string = "update department set name = ? where id = ?"
+const DepartmentDeleteQuery string = "delete from department where id = ?"
+const DepartmentUsedQuery string = "select count(*) from employee where department_id = ?"
+
+// DepartmentSummaryQuery totals the employees by department, zero for none,
+// and currency
+const DepartmentSummaryQuery string = "select coalesce(department_id, 0), currency, count(*), sum(salary) from employee where deleted_at is null group by coalesce(department_id, 0), currency order by coalesce(department_id, 0), currency"
+
+// employees report to the employee named by manager_id. ReportingQuery is
+// completed with the id list of managers, ReportsQuery reads a manager with
+// its direct reports, ChainQuery an employee with its managers up to the top
+// by depth, and SubtreeQuery a manager with everyone under it by depth
+const ReportingQuery string = "select id, manager_id from employee where deleted_at is null and manager_id in "
+const ReportsQuery string = "select id, name, position, salary, currency, department_id, manager_id, version, deleted_at from employee where deleted_at is null and (id = ? or manager_id = ?) order by id"
+const ChainQuery string = "with recursive chain (id, manager_id, depth) as (" +
+	"select id, manager_id, 0 from employee where id = ? and deleted_at is null " +
+	"union all select e.id, e.manager_id, c.depth + 1 from employee e join chain c on e.id = c.manager_id where e.deleted_at is null) " +
+	"select e.id, e.name, e.position, e.salary, e.currency, e.department_id, e.manager_id, e.version, e.deleted_at from chain c join employee e on e.id = c.id order by c.depth"
+const SubtreeQuery string = "with recursive subtree (id, depth) as (" +
+	"select id, 0 from employee where id = ? and deleted_at is null " +
+	"union all select e.id, s.depth + 1 from employee e join subtree s on e.manager_id = s.id where e.deleted_at is null) " +
+	"select e.id, e.name, e.position, e.salary, e.currency, e.department_id, e.manager_id, e.version, e.deleted_at from subtree s join employee e on e.id = s.id order by s.depth, e.id"
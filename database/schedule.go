@@ -0,0 +1,436 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+)
+
+// Applied is what ApplyScheduled made of the changes due.
+type Applied struct {
+	// Employees are the employees as changed, in the order changed.
+	Employees []models.Employee
+	// Dropped are the changes that could no longer be made, taken off the
+	// schedule all the same.
+	Dropped []DroppedChange
+}
+
+// DroppedChange is a scheduled change ApplyScheduled gave up on, with Err
+// saying why.
+type DroppedChange struct {
+	Change models.ScheduledChange
+	Err    error
+}
+
+// scheduleTime is the time a scheduled change is stored with, in UTC to the
+// microsecond every backend keeps.
+func scheduleTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// Schedule stores a change of the employee for ApplyScheduled to make once
+// it is due, as made by the caller of ctx. The employee is read and locked
+// first so a change is never scheduled for one being deleted.
+func (d Database) Schedule(ctx context.Context, change models.ScheduledChange) (models.ScheduledChange, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	caller := audit.CallerFrom(ctx)
+	change.EffectiveAt = scheduleTime(change.EffectiveAt)
+	change.Actor, change.RequestID = caller.Actor, caller.RequestID
+
+	err := d.inTx(ctx, func(tx Database) error {
+		current, err := tx.getForUpdate(ctx, GetQuery, change.EmployeeID)
+		if err != nil {
+			return err
+		}
+
+		err = checkPrecision(current, change.Changes)
+		if err != nil {
+			return err
+		}
+
+		err = tx.checkMove(ctx, current, change.Changes)
+		if err != nil {
+			return err
+		}
+
+		err = tx.checkReporting(ctx, current, change.Changes)
+		if err != nil {
+			return err
+		}
+
+		change.ID, err = tx.insertScheduled(ctx, change)
+
+		return err
+	})
+
+	return change, err
+}
+
+func (d Database) insertScheduled(ctx context.Context, change models.ScheduledChange) (int64, error) {
+	c := change.Changes
+
+	return d.insertID(ctx, ScheduleInsertQuery, change.EmployeeID, change.EffectiveAt,
+		nullString(c.Name), nullString(c.Position), nullAmount(c.Salary), nullString(c.Currency), nullInt(c.DepartmentID), nullInt(c.ManagerID), change.Actor, change.RequestID)
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func nullAmount(a *money.Amount) sql.NullInt64 {
+	if a == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(*a), Valid: true}
+}
+
+func nullInt(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+func (d Database) Scheduled(ctx context.Context, id int64) ([]models.ScheduledChange, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Read)
+	defer cancel()
+
+	return d.scheduled(ctx, ScheduledQuery, id)
+}
+
+// scheduled reads the changes selected by query, locked until the
+// transaction ends when it ends with lockClause.
+func (d Database) scheduled(ctx context.Context, query string, args ...interface{}) ([]models.ScheduledChange, error) {
+	rows, err := d.conn().QueryContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return nil, d.translate(err)
+	}
+
+	defer rows.Close()
+
+	var changes []models.ScheduledChange
+	for rows.Next() {
+		change, err := scanScheduled(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, d.translate(rows.Err())
+}
+
+// scanScheduled reads the columns selected by ScheduledQuery and DueQuery.
+func scanScheduled(s scanner) (models.ScheduledChange, error) {
+	var change models.ScheduledChange
+	var name, position, currency sql.NullString
+	var salary, department, manager sql.NullInt64
+
+	err := s.Scan(&change.ID, &change.EmployeeID, &change.EffectiveAt, &name, &position, &salary, &currency, &department, &manager, &change.Actor, &change.RequestID)
+	change.EffectiveAt = change.EffectiveAt.UTC()
+
+	if name.Valid {
+		change.Changes.Name = &name.String
+	}
+
+	if position.Valid {
+		change.Changes.Position = &position.String
+	}
+
+	if salary.Valid {
+		amount := money.Amount(salary.Int64)
+		change.Changes.Salary = &amount
+	}
+
+	if currency.Valid {
+		change.Changes.Currency = &currency.String
+	}
+
+	if department.Valid {
+		change.Changes.DepartmentID = &department.Int64
+	}
+
+	if manager.Valid {
+		change.Changes.ManagerID = &manager.Int64
+	}
+
+	return change, err
+}
+
+func (d Database) CancelScheduled(ctx context.Context, id, changeID int64) error {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	return d.inTx(ctx, func(tx Database) error {
+		var linked int64
+		err := tx.conn().QueryRowContext(ctx, tx.rebind(CompensationScheduledQuery), changeID).Scan(&linked)
+		if err != nil {
+			return tx.translate(err)
+		}
+
+		if linked > 0 {
+			return ErrCompensationScheduled
+		}
+
+		result, err := tx.conn().ExecContext(ctx, tx.rebind(CancelScheduledQuery), changeID, id)
+		if err != nil {
+			return tx.translate(err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return ErrChangeNotFound
+		}
+
+		return nil
+	})
+}
+
+// ApplyScheduled makes the changes due at now in one transaction, oldest
+// first, each recorded as made by whoever scheduled it and valid from its
+// effective time, or from the current version's start when that is later.
+// Changes of employees deleted since they were scheduled, those whose salary
+// no longer fits the employee's currency, those moving the employee to a
+// department deleted since and those making it report to a manager deleted
+// since or under it by now are dropped and reported.
+func (d Database) ApplyScheduled(ctx context.Context, now time.Time) (Applied, error) {
+	ctx, cancel := withTimeout(ctx, d.Timeouts.Write)
+	defer cancel()
+
+	var applied Applied
+
+	err := d.inTx(ctx, func(tx Database) error {
+		query := DueQuery
+		if tx.dialect().LockRows() {
+			query = query + lockClause
+		}
+
+		due, err := tx.scheduled(ctx, query, scheduleTime(now))
+		if err != nil {
+			return err
+		}
+
+		for _, change := range due {
+			employee, err := tx.applyScheduled(ctx, change)
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrSalaryPrecision) || errors.Is(err, ErrUnknownDepartment) ||
+				errors.Is(err, ErrUnknownManager) || errors.Is(err, ErrManagerCycle) {
+				applied.Dropped = append(applied.Dropped, DroppedChange{Change: change, Err: err})
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			applied.Employees = append(applied.Employees, employee)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Applied{}, err
+	}
+
+	return applied, nil
+}
+
+func (d Database) applyScheduled(ctx context.Context, change models.ScheduledChange) (models.Employee, error) {
+	_, err := d.conn().ExecContext(ctx, d.rebind(AppliedQuery), change.ID)
+	if err != nil {
+		return models.Employee{}, d.translate(err)
+	}
+
+	current, err := d.getForUpdate(ctx, GetQuery, change.EmployeeID)
+	if err != nil {
+		return current, err
+	}
+
+	// the currency may have changed since, the department or the manager
+	// gone, the manager moved under the employee
+	err = checkPrecision(current, change.Changes)
+	if err != nil {
+		return current, err
+	}
+
+	err = d.checkMove(ctx, current, change.Changes)
+	if err != nil {
+		return current, err
+	}
+
+	err = d.checkReporting(ctx, current, change.Changes)
+	if err != nil {
+		return current, err
+	}
+
+	return d.updateAt(scheduledBy(ctx, change), current, change.Changes, change.EffectiveAt)
+}
+
+// updateAt writes changes over current, read and locked in the transaction,
+// as a version valid from validFrom, or from the start of the current version
+// when that is later, recorded as made by the caller of ctx.
+func (d Database) updateAt(ctx context.Context, current models.Employee, changes models.EmployeeChanges, validFrom time.Time) (models.Employee, error) {
+	var opened time.Time
+	err := d.conn().QueryRowContext(ctx, d.rebind(HistoryOpenQuery), current.ID).Scan(&opened)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return current, d.translate(err)
+	}
+
+	validFrom = latest(validFrom, opened.UTC())
+
+	employee, err := d.updateRow(ctx, current.ID, changes)
+	if err != nil {
+		return current, err
+	}
+
+	entry := audit.NewEntry(ctx, audit.OpUpdate, &current, &employee)
+
+	return employee, d.changedAt(ctx, entry, &current, &employee, validFrom)
+}
+
+// scheduledBy is ctx carrying the caller who scheduled the change.
+func scheduledBy(ctx context.Context, change models.ScheduledChange) context.Context {
+	return audit.WithCaller(ctx, audit.Caller{Actor: change.Actor, RequestID: change.RequestID})
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+
+	return a
+}
+
+func (s *memoryState) schedule(ctx context.Context, change models.ScheduledChange) (models.ScheduledChange, error) {
+	current, err := s.get(change.EmployeeID)
+	if err != nil {
+		return change, err
+	}
+
+	err = checkPrecision(current, change.Changes)
+	if err != nil {
+		return change, err
+	}
+
+	err = s.checkMove(current, change.Changes)
+	if err != nil {
+		return change, err
+	}
+
+	err = s.checkReporting(current, change.Changes)
+	if err != nil {
+		return change, err
+	}
+
+	caller := audit.CallerFrom(ctx)
+
+	s.lastScheduledID++
+	change.ID = s.lastScheduledID
+	change.EffectiveAt = scheduleTime(change.EffectiveAt)
+	change.Actor, change.RequestID = caller.Actor, caller.RequestID
+	s.scheduled = append(s.scheduled, change)
+
+	return change, nil
+}
+
+// pending lists the scheduled changes of the employee, or every one when id
+// is zero, in the order they apply.
+func (s *memoryState) pending(id int64) []models.ScheduledChange {
+	var changes []models.ScheduledChange
+	for _, change := range s.scheduled {
+		if id == 0 || change.EmployeeID == id {
+			changes = append(changes, change)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].EffectiveAt.Before(changes[j].EffectiveAt)
+	})
+
+	return changes
+}
+
+func (s *memoryState) cancelScheduled(id, changeID int64) error {
+	for _, compensation := range s.compensation {
+		if compensation.ScheduleID != nil && *compensation.ScheduleID == changeID {
+			return ErrCompensationScheduled
+		}
+	}
+
+	for i, change := range s.scheduled {
+		if change.ID == changeID && change.EmployeeID == id {
+			s.scheduled = append(s.scheduled[:i:i], s.scheduled[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrChangeNotFound
+}
+
+func (s *memoryState) applyScheduled(ctx context.Context, now time.Time) Applied {
+	var applied Applied
+	var waiting []models.ScheduledChange
+
+	for _, change := range s.pending(0) {
+		if change.EffectiveAt.After(now) {
+			waiting = append(waiting, change)
+			continue
+		}
+
+		current, err := s.get(change.EmployeeID)
+		if err == nil {
+			err = checkPrecision(current, change.Changes)
+		}
+
+		if err == nil {
+			err = s.checkMove(current, change.Changes)
+		}
+
+		if err == nil {
+			err = s.checkReporting(current, change.Changes)
+		}
+
+		if err != nil {
+			applied.Dropped = append(applied.Dropped, DroppedChange{Change: change, Err: err})
+			continue
+		}
+
+		employee := s.updateAt(scheduledBy(ctx, change), current, change.Changes, change.EffectiveAt)
+		applied.Employees = append(applied.Employees, employee)
+	}
+
+	s.scheduled = waiting
+
+	return applied
+}
+
+func (s *memoryState) updateAt(ctx context.Context, current models.Employee, changes models.EmployeeChanges, validFrom time.Time) models.Employee {
+	if versions := s.history[current.ID]; len(versions) > 0 {
+		validFrom = latest(validFrom, versions[len(versions)-1].ValidFrom)
+	}
+
+	employee := changes.Apply(current)
+	employee.Version++
+	s.employees[employee.ID] = employee
+	s.changedAt(audit.NewEntry(ctx, audit.OpUpdate, &current, &employee), &current, &employee, validFrom)
+
+	return employee
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"example.com/m/Assesment/migration"
+	"example.com/m/Assesment/models"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite returns a Database backed by a fresh SQLite file in a temporary
+// directory, so the real queries run instead of mocked expectations.
+func openSQLite(t *testing.T) Database {
+	t.Helper()
+
+	db, dialect, err := Open("sqlite://" + filepath.Join(t.TempDir(), "employee.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	// the schema comes from the same migrations production runs
+	migrator, err := migration.New(db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(db, dialect, Timeouts{})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	testConformance(t, openSQLite(t))
+}
+
+func TestSQLiteUpdateAllFields(t *testing.T) {
+	store := openSQLite(t)
+	ctx := context.Background()
+
+	id, err := store.Create(ctx, john)
+	assert.NoError(t, err)
+
+	_, err = store.Update(ctx, id, models.ChangesFrom(jane))
+	assert.NoError(t, err)
+
+	resp, err := store.Get(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, withVersion(withID(jane, id), 2), resp)
+}
+
+func TestSQLiteSortsLikeMemory(t *testing.T) {
+	ctx := context.Background()
+	byName := []SortKey{{Field: "name"}}
+
+	// case and accents order the same everywhere, so keysets carry over
+	for _, store := range []Employee{openSQLite(t), NewMemory()} {
+		for _, name := range []string{"adam", "Zed", "Émile", "bob", "Adam"} {
+			_, err := store.Create(ctx, models.Employee{Name: name, Position: "SDE", Salary: 1})
+			assert.NoError(t, err)
+		}
+
+		page, err := store.List(ctx, ListOptions{Sort: byName, Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{5, 2}, ids(page.Employees))
+
+		page, err = store.List(ctx, ListOptions{Sort: byName, After: ptr(KeysetOf(page.Employees[1])), Limit: 5})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 4, 3}, ids(page.Employees))
+	}
+}
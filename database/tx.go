@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// querier is what Database needs from either the pool or a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn is the transaction the Database is scoped to, the pool otherwise.
+func (d Database) conn() querier {
+	if d.tx != nil {
+		return d.tx
+	}
+
+	return d.DB
+}
+
+// WithTx runs fn against a Database scoped to a new transaction, or to the
+// current one when d is already transaction scoped.
+func (d Database) WithTx(ctx context.Context, fn func(tx Employee) error) error {
+	return d.inTx(ctx, func(tx Database) error {
+		return fn(tx)
+	})
+}
+
+func (d Database) inTx(ctx context.Context, fn func(tx Database) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return d.translate(err)
+	}
+
+	// a no-op once committed, it covers errors and panics in fn
+	defer tx.Rollback()
+
+	scoped := d
+	scoped.tx = tx
+
+	err = fn(scoped)
+	if err != nil {
+		return err
+	}
+
+	return d.translate(tx.Commit())
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/m/Assesment/audit"
+)
+
+// ActorHeader names the caller recorded with the changes of a request. Like
+// RoleHeader it is trusted as set by the authenticating proxy.
+const ActorHeader = "X-Actor"
+
+// AnonymousActor is recorded for requests without ActorHeader.
+const AnonymousActor = "anonymous"
+
+// RequestIDHeader carries the id recorded with the changes of a request, one
+// is generated when the client sends none. It is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request ids taken from clients.
+const maxRequestIDLength = 128
+
+// WithCaller is middleware putting the caller of the request on its context,
+// for the store to record with every change it makes.
+func WithCaller(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := strings.TrimSpace(r.Header.Get(RequestIDHeader))
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+
+		actor := strings.TrimSpace(r.Header.Get(ActorHeader))
+		if actor == "" {
+			actor = AnonymousActor
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := audit.WithCaller(r.Context(), audit.Caller{Actor: actor, RequestID: requestID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+
+	// crypto/rand only fails when the platform has no randomness at all
+	_, err := rand.Read(id)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// AuditResponse is a page of audit entries, newest first. NextCursor is
+// passed back as the cursor query parameter to read older entries.
+type AuditResponse struct {
+	Items      []audit.Entry `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// Audit reads the audit log of every employee, for admins only, narrowed by
+// the audit filters and employee_id.
+func (h Handler) Audit(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "read the audit log") {
+		return
+	}
+
+	q, ok := parseAuditQuery(w, r)
+	if !ok {
+		return
+	}
+
+	if value := r.URL.Query().Get("employee_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid employee_id value "+strconv.Quote(value))
+			return
+		}
+
+		q.EmployeeID = id
+	}
+
+	h.auditLog(w, r, q)
+}
+
+// EmployeeAudit reads the audit log of one employee, for admins only. It
+// answers an empty page for an employee that never existed, the log outlives
+// purged ones.
+func (h Handler) EmployeeAudit(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "read the audit log") {
+		return
+	}
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	q, ok := parseAuditQuery(w, r)
+	if !ok {
+		return
+	}
+
+	q.EmployeeID = id
+	h.auditLog(w, r, q)
+}
+
+func (h Handler) auditLog(w http.ResponseWriter, r *http.Request, q audit.Query) {
+	// one past the limit tells whether older entries remain
+	limit := q.Limit
+	q.Limit++
+
+	entries, err := h.EmployeeDB.AuditLog(r.Context(), q)
+	if err != nil {
+		dbError(w, r, err, "error reading the audit log")
+		return
+	}
+
+	resp := AuditResponse{Items: entries}
+	if len(entries) > limit {
+		resp.Items = entries[:limit]
+		resp.NextCursor = strconv.FormatInt(entries[limit-1].ID, 10)
+	}
+
+	if resp.Items == nil {
+		resp.Items = []audit.Entry{}
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// parseAuditQuery reads the audit filters: actor, operation (repeated or
+// comma separated), since and until as RFC 3339 times, with limit and cursor
+// paging like lists.
+func parseAuditQuery(w http.ResponseWriter, r *http.Request) (audit.Query, bool) {
+	query := r.URL.Query()
+
+	limit, ok := intParam(w, r, "limit", DefaultPageSize, 1, MaxPageSize)
+	if !ok {
+		return audit.Query{}, false
+	}
+
+	q := audit.Query{Actor: query.Get("actor"), Limit: limit}
+
+	for _, value := range query["operation"] {
+		q.Operations = append(q.Operations, splitList(value)...)
+	}
+
+	if !parseTimeBounds(w, r, &q.Since, &q.Until) {
+		return q, false
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		before, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || before <= 0 {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidCursor, "invalid cursor, use one returned by a previous page")
+			return q, false
+		}
+
+		q.Before = before
+	}
+
+	err := q.Validate()
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return q, false
+	}
+
+	return q, true
+}
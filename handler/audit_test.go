@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// audited is the seeded store after alice raised John's salary and deleted
+// Jane, four entries in all.
+func audited(t *testing.T) *database.Memory {
+	store := seeded(t)
+	ctx := audit.WithCaller(context.Background(), audit.Caller{Actor: "alice", RequestID: "req-1"})
+
+	salary := money.Major(1500)
+	_, err := store.Update(ctx, 1, models.EmployeeChanges{Salary: &salary})
+	assert.NoError(t, err)
+
+	err = store.Delete(ctx, 2, 0)
+	assert.NoError(t, err)
+
+	return store
+}
+
+func decodeAudit(t *testing.T, w *httptest.ResponseRecorder) AuditResponse {
+	var resp AuditResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	return resp
+}
+
+func auditOperations(resp AuditResponse) []string {
+	operations := []string{}
+	for _, entry := range resp.Items {
+		operations = append(operations, entry.Operation)
+	}
+
+	return operations
+}
+
+func TestWithCaller(t *testing.T) {
+	store := database.NewMemory()
+	create := WithCaller(http.HandlerFunc(Handler{EmployeeDB: store}.Create))
+
+	r := httptest.NewRequest(http.MethodPost, "/employee", strings.NewReader(`{"name": "John", "position": "SDE", "salary": 1000}`))
+	r.Header.Set(ActorHeader, "alice")
+	r.Header.Set(RequestIDHeader, "req-9")
+
+	w := httptest.NewRecorder()
+	create.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-9", w.Header().Get(RequestIDHeader))
+
+	// without headers the request gets an id and an anonymous actor
+	w = httptest.NewRecorder()
+	create.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/employee", strings.NewReader(`{"name": "Jane", "position": "PM", "salary": 2000}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.Len(t, requestID, 32)
+
+	entries, err := store.AuditLog(context.Background(), audit.Query{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, AnonymousActor, entries[0].Actor)
+	assert.Equal(t, requestID, entries[0].RequestID)
+	assert.Equal(t, "alice", entries[1].Actor)
+	assert.Equal(t, "req-9", entries[1].RequestID)
+}
+
+func TestAudit(t *testing.T) {
+	tt := []struct {
+		name       string
+		target     string
+		operations []string
+		nextCursor string
+	}{
+		{
+			name:       "newest first",
+			target:     "/employee/audit",
+			operations: []string{audit.OpDelete, audit.OpUpdate, audit.OpCreate, audit.OpCreate},
+		},
+		{
+			name:       "by actor and operation",
+			target:     "/employee/audit?actor=alice&operation=create,update",
+			operations: []string{audit.OpUpdate},
+		},
+		{
+			name:       "by employee",
+			target:     "/employee/audit?employee_id=2",
+			operations: []string{audit.OpDelete, audit.OpCreate},
+		},
+		{
+			name:       "by time",
+			target:     "/employee/audit?until=2000-01-01T00:00:00Z",
+			operations: []string{},
+		},
+		{
+			name:       "first page",
+			target:     "/employee/audit?limit=3",
+			operations: []string{audit.OpDelete, audit.OpUpdate, audit.OpCreate},
+			nextCursor: "2",
+		},
+		{
+			name:       "last page",
+			target:     "/employee/audit?limit=3&cursor=2",
+			operations: []string{audit.OpCreate},
+		},
+	}
+
+	store := audited(t)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			r.Header.Set(RoleHeader, RoleAdmin)
+
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: store}.Audit(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			resp := decodeAudit(t, w)
+			assert.Equal(t, tc.operations, auditOperations(resp))
+			assert.Equal(t, tc.nextCursor, resp.NextCursor)
+		})
+	}
+}
+
+func TestEmployeeAudit(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/employee/1/audit", nil)
+	r.Header.Set(RoleHeader, RoleAdmin)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+
+	w := httptest.NewRecorder()
+	Handler{EmployeeDB: audited(t)}.EmployeeAudit(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	resp := decodeAudit(t, w)
+	assert.Equal(t, []string{audit.OpUpdate, audit.OpCreate}, auditOperations(resp))
+
+	update := resp.Items[0]
+	assert.Equal(t, "alice", update.Actor)
+	assert.Equal(t, int64(2), update.Version)
+	assert.Len(t, update.Changes, 1)
+	assert.Equal(t, "salary", update.Changes[0].Field)
+	assert.JSONEq(t, "1000", string(update.Changes[0].Before))
+	assert.JSONEq(t, "1500", string(update.Changes[0].After))
+}
+
+func TestAuditInvalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		target string
+		role   string
+		status int
+		code   string
+	}{
+		{name: "not an admin", target: "/employee/audit", status: http.StatusForbidden, code: CodeForbidden},
+		{name: "operation", target: "/employee/audit?operation=rename", role: RoleAdmin, status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "time", target: "/employee/audit?since=yesterday", role: RoleAdmin, status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "time range", target: "/employee/audit?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z", role: RoleAdmin, status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "employee", target: "/employee/audit?employee_id=x", role: RoleAdmin, status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "cursor", target: "/employee/audit?cursor=abc", role: RoleAdmin, status: http.StatusBadRequest, code: CodeInvalidCursor},
+		{name: "limit", target: "/employee/audit?limit=0", role: RoleAdmin, status: http.StatusBadRequest, code: CodeInvalidQuery},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			r.Header.Set(RoleHeader, tc.role)
+
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: seeded(t)}.Audit(w, r)
+
+			assertProblem(t, w, tc.status, tc.code)
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
+)
+
+// MaxBatchSize caps the items of a batch request.
+const MaxBatchSize = 500
+
+// batch modes, chosen with the mode query parameter
+const (
+	// BatchAtomic writes every item in one transaction or none of them.
+	BatchAtomic = "atomic"
+	// BatchBestEffort writes the items that can be written.
+	BatchBestEffort = "best_effort"
+)
+
+// BatchResult is the outcome of one item, Status is the HTTP status the
+// item would have had on its own.
+type BatchResult struct {
+	Index    int              `json:"index"`
+	Status   int              `json:"status"`
+	ID       int64            `json:"id,omitempty"`
+	Employee *models.Employee `json:"employee,omitempty"`
+	Problem  *Problem         `json:"problem,omitempty"`
+}
+
+// BatchResponse answers a batch with 207 Multi-Status and a result per
+// item, in the order of the request.
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchUpdate changes the fields present, only at Version unless it is zero.
+type BatchUpdate struct {
+	ID       int64         `json:"id"`
+	Version  int64         `json:"version"`
+	Name     *string       `json:"name"`
+	Position *string       `json:"position"`
+	Salary   *money.Amount `json:"salary"`
+	Currency *string       `json:"currency"`
+	// DepartmentID moves the employee, zero takes it out of its department.
+	DepartmentID *int64 `json:"department_id"`
+	// ManagerID makes the employee report to another, zero to nobody.
+	ManagerID *int64 `json:"manager_id"`
+}
+
+// batch collects the results of a batch request.
+type batch struct {
+	mode    string
+	results []BatchResult
+	// pending are the indexes of the items still to be written
+	pending []int
+}
+
+// readBatch reads the mode and the items of a batch body, a JSON array of
+// at most MaxBatchSize items, responding with a problem when either is
+// invalid. Items are decoded one by one so a bad item only fails itself.
+func readBatch(w http.ResponseWriter, r *http.Request) (*batch, []json.RawMessage, bool) {
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = BatchAtomic
+	case BatchAtomic, BatchBestEffort:
+	default:
+		detail := fmt.Sprintf("invalid mode %q, want %s or %s", mode, BatchAtomic, BatchBestEffort)
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, detail)
+		return nil, nil, false
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
+		return nil, nil, false
+	}
+
+	var items []json.RawMessage
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "a batch must be a JSON array")
+		return nil, nil, false
+	}
+
+	if len(items) == 0 || len(items) > MaxBatchSize {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("a batch must hold 1 to %d items", MaxBatchSize))
+		return nil, nil, false
+	}
+
+	b := &batch{mode: mode, results: make([]BatchResult, len(items))}
+	for i := range b.results {
+		b.results[i].Index = i
+	}
+
+	return b, items, true
+}
+
+// fail records the problem of an item and drops it from the pending ones.
+func (b *batch) fail(i int, p Problem) {
+	b.results[i].Status = p.Status
+	b.results[i].Problem = &p
+
+	for n, pending := range b.pending {
+		if pending == i {
+			b.pending = append(b.pending[:n], b.pending[n+1:]...)
+			break
+		}
+	}
+}
+
+func (b *batch) failed() bool {
+	for _, result := range b.results {
+		if result.Problem != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// abort fails the items an atomic batch did not write because of others.
+func (b *batch) abort() {
+	for _, i := range append([]int(nil), b.pending...) {
+		b.fail(i, newProblem(http.StatusFailedDependency, CodeBatchAborted, "not written, another item of the atomic batch failed"))
+	}
+}
+
+// itemError records a write error of an item.
+func (b *batch) itemError(i int, err error, detail string) {
+	if errors.Is(err, database.ErrVersionMismatch) {
+		b.fail(i, newProblem(http.StatusPreconditionFailed, CodePreconditionFailed, "the employee is not at the expected version"))
+		return
+	}
+
+	b.fail(i, dbProblem(err, detail))
+}
+
+func (b *batch) write(w http.ResponseWriter, r *http.Request) {
+	resp := BatchResponse{Mode: b.mode, Results: b.results}
+	for _, result := range b.results {
+		if result.Problem != nil {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+	}
+
+	writeJSON(w, r, http.StatusMultiStatus, resp)
+}
+
+// CreateBatch creates the employees of a JSON array. Atomic batches are
+// written with multi-row inserts in one transaction, best effort ones the
+// same way when every item is valid and item by item when that fails.
+func (h Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	b, items, ok := readBatch(w, r)
+	if !ok {
+		return
+	}
+
+	employees := make([]models.Employee, len(items))
+	for i, item := range items {
+		err := json.Unmarshal(item, &employees[i])
+		if err != nil {
+			b.fail(i, newProblem(http.StatusBadRequest, CodeInvalidBody, "an employee must be a JSON object"))
+			continue
+		}
+
+		employees[i] = h.validator().Defaults(employees[i])
+		err = h.validator().ValidateCreate(employees[i])
+		if err != nil {
+			b.fail(i, validationProblem(err))
+			continue
+		}
+
+		b.pending = append(b.pending, i)
+	}
+
+	if b.mode == BatchAtomic && b.failed() {
+		b.abort()
+		b.write(w, r)
+		return
+	}
+
+	valid := make([]models.Employee, 0, len(b.pending))
+	for _, i := range b.pending {
+		valid = append(valid, employees[i])
+	}
+
+	ids, err := h.EmployeeDB.CreateMany(r.Context(), valid)
+
+	var itemErr *database.ItemError
+	switch {
+	case err == nil:
+		for n, i := range b.pending {
+			b.created(i, employees[i], ids[n])
+		}
+	case b.mode == BatchAtomic && errors.As(err, &itemErr):
+		// the index is into valid, only the pending employees
+		b.itemError(b.pending[itemErr.Index], itemErr.Err, "error creating employee")
+		b.abort()
+	case b.mode == BatchAtomic:
+		dbError(w, r, err, "error creating employees")
+		return
+	default:
+		for _, i := range b.pending {
+			id, err := h.EmployeeDB.Create(r.Context(), employees[i])
+			if err != nil {
+				b.itemError(i, err, "error creating employee")
+				continue
+			}
+
+			b.created(i, employees[i], id)
+		}
+	}
+
+	b.write(w, r)
+}
+
+func (b *batch) created(i int, employee models.Employee, id int64) {
+	employee.ID = id
+	employee.Version = models.InitialVersion
+
+	b.results[i].Status = http.StatusCreated
+	b.results[i].ID = id
+	b.results[i].Employee = &employee
+}
+
+// UpdateBatch applies a JSON array of BatchUpdate. Atomic batches run in one
+// transaction, stopping at the first failing item.
+func (h Handler) UpdateBatch(w http.ResponseWriter, r *http.Request) {
+	b, items, ok := readBatch(w, r)
+	if !ok {
+		return
+	}
+
+	updates := make([]BatchUpdate, len(items))
+	seen := make(map[int64]bool)
+
+	for i, item := range items {
+		err := json.Unmarshal(item, &updates[i])
+		if err != nil {
+			b.fail(i, newProblem(http.StatusBadRequest, CodeInvalidBody, "an update must be a JSON object"))
+			continue
+		}
+
+		ok := b.checkID(i, updates[i].ID, seen)
+		if !ok {
+			continue
+		}
+
+		err = h.validator().ValidateChanges(updates[i].changes())
+		if err != nil {
+			b.fail(i, validationProblem(err))
+			continue
+		}
+
+		b.pending = append(b.pending, i)
+	}
+
+	if b.mode == BatchAtomic && b.failed() {
+		b.abort()
+		b.write(w, r)
+		return
+	}
+
+	if b.mode == BatchBestEffort {
+		for _, i := range b.pending {
+			employee, err := h.EmployeeDB.Update(r.Context(), updates[i].ID, updates[i].changes())
+			if err != nil {
+				b.itemError(i, err, "error updating employee")
+				continue
+			}
+
+			b.updated(i, employee)
+		}
+
+		b.write(w, r)
+		return
+	}
+
+	written := make(map[int]models.Employee, len(b.pending))
+	failed := -1
+
+	err := h.EmployeeDB.WithTx(r.Context(), func(tx database.Employee) error {
+		for _, i := range b.pending {
+			employee, err := tx.Update(r.Context(), updates[i].ID, updates[i].changes())
+			if err != nil {
+				failed = i
+				return err
+			}
+
+			written[i] = employee
+		}
+
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		for i, employee := range written {
+			b.updated(i, employee)
+		}
+	case failed >= 0:
+		b.itemError(failed, err, "error updating employee")
+		b.abort()
+	default:
+		dbError(w, r, err, "error updating employees")
+		return
+	}
+
+	b.write(w, r)
+}
+
+func (u BatchUpdate) changes() models.EmployeeChanges {
+	changes := validation.NormalizeChanges(models.EmployeeChanges{Name: u.Name, Position: u.Position, Salary: u.Salary, Currency: u.Currency, DepartmentID: u.DepartmentID, ManagerID: u.ManagerID})
+	changes.IfVersion = u.Version
+
+	return changes
+}
+
+func (b *batch) updated(i int, employee models.Employee) {
+	b.results[i].Status = http.StatusOK
+	b.results[i].ID = employee.ID
+	b.results[i].Employee = &employee
+}
+
+// checkID fails an item whose id is invalid or listed by an earlier item.
+func (b *batch) checkID(i int, id int64, seen map[int64]bool) bool {
+	if id <= 0 {
+		b.fail(i, newProblem(http.StatusBadRequest, CodeInvalidID, "the id must be a positive integer"))
+		return false
+	}
+
+	if seen[id] {
+		b.fail(i, newProblem(http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("employee %d is listed more than once", id)))
+		return false
+	}
+
+	seen[id] = true
+
+	return true
+}
+
+// DeleteBatch deletes the employees of a JSON array of ids. Atomic batches
+// are one delete statement in a transaction.
+func (h Handler) DeleteBatch(w http.ResponseWriter, r *http.Request) {
+	b, items, ok := readBatch(w, r)
+	if !ok {
+		return
+	}
+
+	ids := make([]int64, len(items))
+	seen := make(map[int64]bool)
+
+	for i, item := range items {
+		err := json.Unmarshal(item, &ids[i])
+		if err != nil {
+			b.fail(i, newProblem(http.StatusBadRequest, CodeInvalidID, "the id must be a positive integer"))
+			continue
+		}
+
+		if b.checkID(i, ids[i], seen) {
+			b.pending = append(b.pending, i)
+		}
+	}
+
+	if b.mode == BatchAtomic && b.failed() {
+		b.abort()
+		b.write(w, r)
+		return
+	}
+
+	if b.mode == BatchBestEffort {
+		for _, i := range b.pending {
+			err := h.EmployeeDB.Delete(r.Context(), ids[i], 0)
+			if err != nil {
+				b.itemError(i, err, "error deleting employee")
+				continue
+			}
+
+			b.deleted(i, ids[i])
+		}
+
+		b.write(w, r)
+		return
+	}
+
+	err := h.EmployeeDB.DeleteMany(r.Context(), ids)
+
+	var itemErr *database.ItemError
+	switch {
+	case err == nil:
+		for _, i := range b.pending {
+			b.deleted(i, ids[i])
+		}
+	case errors.As(err, &itemErr):
+		b.itemError(itemErr.Index, itemErr.Err, "error deleting employee")
+		b.abort()
+	default:
+		dbError(w, r, err, "error deleting employees")
+		return
+	}
+
+	b.write(w, r)
+}
+
+func (b *batch) deleted(i int, id int64) {
+	b.results[i].Status = http.StatusNoContent
+	b.results[i].ID = id
+}
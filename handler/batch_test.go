@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// seeded is an in-memory store holding employees 1 and 2.
+func seeded(t *testing.T) *database.Memory {
+	store := database.NewMemory()
+	for _, e := range []models.Employee{
+		{Name: "John", Position: "SDE", Salary: money.Major(1000), Currency: "USD"},
+		{Name: "Jane", Position: "PM", Salary: money.Major(2000), Currency: "USD"},
+	} {
+		_, err := store.Create(context.Background(), e)
+		assert.NoError(t, err)
+	}
+
+	return store
+}
+
+func statuses(resp BatchResponse) []int {
+	var result []int
+	for _, r := range resp.Results {
+		result = append(result, r.Status)
+	}
+
+	return result
+}
+
+func TestBatch(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		statuses []int
+		// remaining are the ids stored afterwards
+		remaining []int64
+	}{
+		{
+			name:      "create atomic",
+			method:    http.MethodPost,
+			target:    "/employee/batch",
+			body:      `[{"name": "Jim", "position": "QA", "salary": 10}, {"name": " Pam ", "position": "HR", "salary": 20}]`,
+			statuses:  []int{http.StatusCreated, http.StatusCreated},
+			remaining: []int64{1, 2, 3, 4},
+		},
+		{
+			name:      "create atomic with an invalid item",
+			method:    http.MethodPost,
+			target:    "/employee/batch",
+			body:      `[{"name": "Jim", "position": "QA", "salary": 10}, {"name": "", "salary": 20}, 42]`,
+			statuses:  []int{http.StatusFailedDependency, http.StatusBadRequest, http.StatusBadRequest},
+			remaining: []int64{1, 2},
+		},
+		{
+			name:      "create best effort with an invalid item",
+			method:    http.MethodPost,
+			target:    "/employee/batch?mode=best_effort",
+			body:      `[{"name": "Jim", "position": "QA", "salary": 10}, {"name": "", "salary": 20}]`,
+			statuses:  []int{http.StatusCreated, http.StatusBadRequest},
+			remaining: []int64{1, 2, 3},
+		},
+		{
+			name:      "create atomic with an unknown department",
+			method:    http.MethodPost,
+			target:    "/employee/batch",
+			body:      `[{"name": "Jim", "position": "QA", "salary": 10}, {"name": "Joe", "position": "QA", "salary": 10, "department_id": 9}]`,
+			statuses:  []int{http.StatusFailedDependency, http.StatusUnprocessableEntity},
+			remaining: []int64{1, 2},
+		},
+		{
+			name:      "create atomic with an unknown manager",
+			method:    http.MethodPost,
+			target:    "/employee/batch",
+			body:      `[{"name": "Jim", "position": "QA", "salary": 10, "manager_id": 99}, {"name": "Joe", "position": "QA", "salary": 10}]`,
+			statuses:  []int{http.StatusUnprocessableEntity, http.StatusFailedDependency},
+			remaining: []int64{1, 2},
+		},
+		{
+			name:      "update atomic with a missing employee",
+			method:    http.MethodPatch,
+			target:    "/employee/batch",
+			body:      `[{"id": 1, "salary": 1500}, {"id": 99, "salary": 1}, {"id": 2, "name": "Janet"}]`,
+			statuses:  []int{http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency},
+			remaining: []int64{1, 2},
+		},
+		{
+			name:      "update best effort",
+			method:    http.MethodPatch,
+			target:    "/employee/batch?mode=best_effort",
+			body:      `[{"id": 1, "salary": 1500, "version": 1}, {"id": 2, "name": "Janet", "version": 7}, {"id": 1}]`,
+			statuses:  []int{http.StatusOK, http.StatusPreconditionFailed, http.StatusBadRequest},
+			remaining: []int64{1, 2},
+		},
+		{
+			name:      "delete atomic",
+			method:    http.MethodDelete,
+			target:    "/employee/batch",
+			body:      `[2, 1]`,
+			statuses:  []int{http.StatusNoContent, http.StatusNoContent},
+			remaining: nil,
+		},
+		{
+			name:      "delete atomic with a missing employee",
+			method:    http.MethodDelete,
+			target:    "/employee/batch",
+			body:      `[1, 99]`,
+			statuses:  []int{http.StatusFailedDependency, http.StatusNotFound},
+			remaining: []int64{1, 2},
+		},
+		{
+			name:      "delete best effort",
+			method:    http.MethodDelete,
+			target:    "/employee/batch?mode=best_effort",
+			body:      `[1, 99, 0]`,
+			statuses:  []int{http.StatusNoContent, http.StatusNotFound, http.StatusBadRequest},
+			remaining: []int64{2},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := seeded(t)
+			h := Handler{EmployeeDB: store}
+
+			handlers := map[string]http.HandlerFunc{
+				http.MethodPost:   h.CreateBatch,
+				http.MethodPatch:  h.UpdateBatch,
+				http.MethodDelete: h.DeleteBatch,
+			}
+
+			w := httptest.NewRecorder()
+			handlers[tc.method](w, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+
+			assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+			var resp BatchResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.statuses, statuses(resp))
+
+			page, err := store.List(context.Background(), database.ListOptions{Limit: 10})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.remaining, employeeIDs(page.Employees))
+		})
+	}
+}
+
+func employeeIDs(employees []models.Employee) []int64 {
+	var result []int64
+	for _, e := range employees {
+		result = append(result, e.ID)
+	}
+
+	return result
+}
+
+func TestCreateBatchResults(t *testing.T) {
+	h := Handler{EmployeeDB: seeded(t)}
+
+	w := httptest.NewRecorder()
+	body := `[{"name": " Jim ", "position": "QA", "salary": 10}]`
+	h.CreateBatch(w, httptest.NewRequest(http.MethodPost, "/employee/batch", strings.NewReader(body)))
+
+	assert.JSONEq(t, `{
+		"mode": "atomic",
+		"succeeded": 1,
+		"failed": 0,
+		"results": [{"index": 0, "status": 201, "id": 3, "employee": {"id": 3, "name": "Jim", "position": "QA", "salary": 10, "currency": "USD"}}]
+	}`, w.Body.String())
+}
+
+func TestBatchInvalidRequests(t *testing.T) {
+	var tooMany []string
+	for i := 0; i <= MaxBatchSize; i++ {
+		tooMany = append(tooMany, "1")
+	}
+
+	tt := []struct {
+		name   string
+		target string
+		body   string
+		code   string
+	}{
+		{name: "not an array", target: "/employee/batch", body: `{"id": 1}`, code: CodeInvalidBody},
+		{name: "empty", target: "/employee/batch", body: `[]`, code: CodeInvalidBody},
+		{name: "too many", target: "/employee/batch", body: "[" + strings.Join(tooMany, ",") + "]", code: CodeInvalidBody},
+		{name: "unknown mode", target: "/employee/batch?mode=some", body: `[1]`, code: CodeInvalidQuery},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: seeded(t)}.DeleteBatch(w, httptest.NewRequest(http.MethodDelete, tc.target, strings.NewReader(tc.body)))
+
+			assertProblem(t, w, http.StatusBadRequest, tc.code)
+		})
+	}
+}
+
+func TestCreateBatchFallback(t *testing.T) {
+	created := 0
+	mockDB := &database.MockDatabase{
+		CreateManyF: func(ctx context.Context, employees []models.Employee) ([]int64, error) {
+			return nil, database.ErrConstraint
+		},
+		CreateF: func(ctx context.Context, employee models.Employee) (int64, error) {
+			if employee.Name == "Jim" {
+				return 0, database.ErrConstraint
+			}
+
+			created++
+			return int64(created), nil
+		},
+	}
+
+	body := `[{"name": "Jim", "position": "QA", "salary": 10}, {"name": "Pam", "position": "HR", "salary": 20}]`
+
+	// an atomic batch fails as a whole
+	w := httptest.NewRecorder()
+	Handler{EmployeeDB: mockDB}.CreateBatch(w, httptest.NewRequest(http.MethodPost, "/employee/batch", strings.NewReader(body)))
+	assertProblem(t, w, http.StatusUnprocessableEntity, CodeConstraint)
+
+	// a best effort one retries item by item
+	w = httptest.NewRecorder()
+	Handler{EmployeeDB: mockDB}.CreateBatch(w, httptest.NewRequest(http.MethodPost, "/employee/batch?mode=best_effort", strings.NewReader(body)))
+
+	var resp BatchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []int{http.StatusUnprocessableEntity, http.StatusCreated}, statuses(resp))
+	assert.Equal(t, 1, resp.Succeeded)
+	assert.Equal(t, 1, resp.Failed)
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+)
+
+// maxNoteLength bounds the note of a compensation change, in runes.
+const maxNoteLength = 500
+
+// CompensationRequest proposes a new salary for an employee, effective now
+// when EffectiveAt is left out.
+type CompensationRequest struct {
+	Salary      *money.Amount `json:"salary"`
+	Reason      string        `json:"reason"`
+	EffectiveAt *time.Time    `json:"effective_at"`
+	Note        string        `json:"note"`
+}
+
+// CompensationChange is a compensation change in responses, both salaries in
+// Currency. PercentChange is null for a change from a zero salary,
+// DecidedBy and DecidedAt until it is decided, ScheduleID unless it was
+// approved to take effect later.
+type CompensationChange struct {
+	ID            int64        `json:"id"`
+	EmployeeID    int64        `json:"employee_id"`
+	Position      string       `json:"position"`
+	CurrentSalary money.Amount `json:"current_salary"`
+	Salary        money.Amount `json:"salary"`
+	Currency      string       `json:"currency"`
+	PercentChange *float64     `json:"percent_change"`
+	Reason        string       `json:"reason"`
+	EffectiveAt   time.Time    `json:"effective_at"`
+	Status        string       `json:"status"`
+	ProposedBy    string       `json:"proposed_by"`
+	ProposedAt    time.Time    `json:"proposed_at"`
+	DecidedBy     string       `json:"decided_by,omitempty"`
+	DecidedAt     *time.Time   `json:"decided_at,omitempty"`
+	ScheduleID    *int64       `json:"schedule_id,omitempty"`
+	Note          string       `json:"note,omitempty"`
+}
+
+func compensationChange(c models.CompensationChange) CompensationChange {
+	resp := CompensationChange{
+		ID:            c.ID,
+		EmployeeID:    c.EmployeeID,
+		Position:      c.Position,
+		CurrentSalary: c.CurrentSalary,
+		Salary:        c.Salary,
+		Currency:      c.Currency,
+		Reason:        c.Reason,
+		EffectiveAt:   c.EffectiveAt,
+		Status:        c.Status,
+		ProposedBy:    c.ProposedBy,
+		ProposedAt:    c.ProposedAt,
+		DecidedBy:     c.DecidedBy,
+		DecidedAt:     c.DecidedAt,
+		ScheduleID:    c.ScheduleID,
+		Note:          c.Note,
+	}
+
+	if percent, ok := c.PercentChange(); ok {
+		resp.PercentChange = &percent
+	}
+
+	return resp
+}
+
+// CompensationResponse lists the compensation changes of an employee,
+// newest first.
+type CompensationResponse struct {
+	Items []CompensationChange `json:"items"`
+}
+
+// RaiseSummary is a group of a compensation report, its raises converted
+// into the report's currency.
+type RaiseSummary struct {
+	Group          string       `json:"group"`
+	Changes        int64        `json:"changes"`
+	AverageRaise   money.Amount `json:"average_raise"`
+	AveragePercent float64      `json:"average_percent"`
+}
+
+// CompensationReportResponse lists the groups of a compensation report in
+// order.
+type CompensationReportResponse struct {
+	By       string         `json:"by"`
+	Currency string         `json:"currency"`
+	Items    []RaiseSummary `json:"items"`
+}
+
+// ProposeCompensation stores a salary change of the employee awaiting an
+// admin's approval, answering 201 Created with the change.
+func (h Handler) ProposeCompensation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
+		return
+	}
+
+	var req CompensationRequest
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error unmarshalling body, effective_at must be an RFC 3339 time")
+		return
+	}
+
+	change, ok := h.compensationRequest(w, r, id, req)
+	if !ok {
+		return
+	}
+
+	if !h.visible(w, r, id) {
+		return
+	}
+
+	change, err = h.CompensationDB.ProposeCompensation(r.Context(), change)
+	if err != nil {
+		dbError(w, r, err, "error proposing compensation change")
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, compensationChange(change))
+}
+
+// compensationRequest is the change req proposes for the employee,
+// responding with a problem and returning false when it is invalid.
+func (h Handler) compensationRequest(w http.ResponseWriter, r *http.Request, id int64, req CompensationRequest) (models.CompensationChange, bool) {
+	change := models.CompensationChange{
+		EmployeeID:  id,
+		Reason:      strings.ToLower(strings.TrimSpace(req.Reason)),
+		EffectiveAt: time.Now(),
+		Note:        strings.TrimSpace(req.Note),
+	}
+
+	if req.Salary == nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "the change must set salary")
+		return change, false
+	}
+
+	if !contains(models.CompensationReasons, change.Reason) {
+		detail := "invalid reason " + strconv.Quote(req.Reason) + ", want one of " + strings.Join(models.CompensationReasons, ", ")
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, detail)
+		return change, false
+	}
+
+	if len([]rune(change.Note)) > maxNoteLength {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "note must be at most "+strconv.Itoa(maxNoteLength)+" characters")
+		return change, false
+	}
+
+	err := h.validator().ValidateChanges(models.EmployeeChanges{Salary: req.Salary})
+	if err != nil {
+		validationError(w, r, err)
+		return change, false
+	}
+
+	change.Salary = *req.Salary
+	if req.EffectiveAt != nil {
+		change.EffectiveAt = *req.EffectiveAt
+	}
+
+	return change, true
+}
+
+// Compensation lists the compensation changes of the employee, only those
+// with the status query parameter when given.
+func (h Handler) Compensation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	statuses := []string{models.StatusProposed, models.StatusApproved, models.StatusRejected}
+
+	if status != "" && !contains(statuses, status) {
+		detail := "invalid status value " + strconv.Quote(status) + ", want one of " + strings.Join(statuses, ", ")
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, detail)
+		return
+	}
+
+	if !h.visible(w, r, id) {
+		return
+	}
+
+	changes, err := h.CompensationDB.Compensation(r.Context(), id, status)
+	if err != nil {
+		dbError(w, r, err, "error reading compensation changes")
+		return
+	}
+
+	resp := CompensationResponse{Items: make([]CompensationChange, 0, len(changes))}
+	for _, change := range changes {
+		resp.Items = append(resp.Items, compensationChange(change))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// ApproveCompensation approves a proposed change. A change already in
+// effect updates the salary at once, a later one is scheduled for then.
+func (h Handler) ApproveCompensation(w http.ResponseWriter, r *http.Request) {
+	h.decideCompensation(w, r, "approve compensation changes", func(id, changeID int64) (models.CompensationChange, error) {
+		return h.CompensationDB.ApproveCompensation(r.Context(), id, changeID, time.Now())
+	})
+}
+
+// RejectCompensation rejects a proposed change.
+func (h Handler) RejectCompensation(w http.ResponseWriter, r *http.Request) {
+	h.decideCompensation(w, r, "reject compensation changes", func(id, changeID int64) (models.CompensationChange, error) {
+		return h.CompensationDB.RejectCompensation(r.Context(), id, changeID)
+	})
+}
+
+func (h Handler) decideCompensation(w http.ResponseWriter, r *http.Request, action string,
+	decide func(id, changeID int64) (models.CompensationChange, error)) {
+	if !requireAdmin(w, r, action) {
+		return
+	}
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	changeID, ok := parseChangeID(w, r)
+	if !ok {
+		return
+	}
+
+	change, err := decide(id, changeID)
+	if err != nil {
+		dbError(w, r, err, "error deciding compensation change")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, compensationChange(change))
+}
+
+// CompensationReport aggregates the approved compensation changes by
+// position or reason, those effective from since until until when given,
+// with raises in the base currency of the converter.
+func (h Handler) CompensationReport(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "read compensation reports") {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := database.ReportOptions{By: query.Get("by")}
+
+	if opts.By == "" {
+		opts.By = database.ReportByPosition
+	}
+
+	if opts.By != database.ReportByPosition && opts.By != database.ReportByReason {
+		detail := "invalid by value " + strconv.Quote(opts.By) + ", want position or reason"
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, detail)
+		return
+	}
+
+	if !parseTimeBounds(w, r, &opts.Since, &opts.Until) {
+		return
+	}
+
+	summaries, err := h.CompensationDB.CompensationReport(r.Context(), opts)
+	if err != nil {
+		dbError(w, r, err, "error reading compensation report")
+		return
+	}
+
+	converter := h.converter()
+
+	items, err := raiseSummaries(summaries, converter)
+	if err != nil {
+		problemError(w, r, http.StatusInternalServerError, CodeInternal, "error converting raises: "+err.Error())
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, CompensationReportResponse{By: opts.By, Currency: converter.Base, Items: items})
+}
+
+// raiseSummaries merges the totals of each group in every currency into
+// averages in the base currency of converter, totals come ordered by group.
+func raiseSummaries(summaries []models.RaiseSummary, converter money.Converter) ([]RaiseSummary, error) {
+	items := []RaiseSummary{}
+
+	var raises money.Amount
+	var percents int64
+	var percentSum float64
+
+	for i, s := range summaries {
+		raise, err := converter.Convert(s.TotalRaise, s.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		raises += raise
+		percents += s.Percents
+		percentSum += s.TotalPercent
+
+		if i > 0 && summaries[i-1].Group == s.Group {
+			items[len(items)-1].Changes += s.Changes
+		} else {
+			items = append(items, RaiseSummary{Group: s.Group, Changes: s.Changes})
+		}
+
+		if i+1 < len(summaries) && summaries[i+1].Group == s.Group {
+			continue
+		}
+
+		item := &items[len(items)-1]
+		item.AverageRaise = raises.Quo(item.Changes)
+		if percents > 0 {
+			item.AveragePercent = percentSum / float64(percents)
+		}
+
+		raises, percents, percentSum = 0, 0, 0
+	}
+
+	return items, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
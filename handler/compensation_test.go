@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/Assesment/audit"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompensation(t *testing.T) {
+	store := seeded(t)
+	h := Handler{EmployeeDB: store, CompensationDB: store}
+
+	as := func(r *http.Request, actor, role string, vars map[string]string) *http.Request {
+		r.Header.Set(RoleHeader, role)
+		r = r.WithContext(audit.WithCaller(r.Context(), audit.Caller{Actor: actor, RequestID: "req-" + actor}))
+
+		return mux.SetURLVars(r, vars)
+	}
+
+	propose := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/employee/1/compensation", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ProposeCompensation(w, as(r, "alice", "", map[string]string{"id": "1"}))
+
+		return w
+	}
+
+	approve := func(change, actor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/employee/1/compensation/"+change+"/approve", nil)
+		w := httptest.NewRecorder()
+		h.ApproveCompensation(w, as(r, actor, RoleAdmin, map[string]string{"id": "1", "change": change}))
+
+		return w
+	}
+
+	w := propose(`{"salary": 1100, "reason": " Merit ", "effective_at": "2024-06-01T00:00:00Z", "note": "good year"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var change CompensationChange
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, models.ReasonMerit, change.Reason)
+	assert.Equal(t, models.StatusProposed, change.Status)
+	assert.Equal(t, "SDE", change.Position)
+	assert.Equal(t, money.Major(1000), change.CurrentSalary)
+	assert.Equal(t, "USD", change.Currency)
+	assert.InDelta(t, 10, *change.PercentChange, 1e-9)
+	assert.Equal(t, "alice", change.ProposedBy)
+	assert.Nil(t, change.DecidedAt)
+
+	assertProblem(t, approve("1", "alice"), http.StatusForbidden, CodeForbidden)
+
+	w = approve("1", "bob")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, models.StatusApproved, change.Status)
+	assert.Equal(t, "bob", change.DecidedBy)
+
+	employee, err := store.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, money.Major(1100), employee.Salary)
+
+	assertProblem(t, approve("1", "bob"), http.StatusConflict, CodeConflict)
+	assertProblem(t, approve("9", "bob"), http.StatusNotFound, CodeNotFound)
+
+	// proposed from 1000 before the raise was approved
+	_, err = store.ProposeCompensation(context.Background(), models.CompensationChange{EmployeeID: 1, Salary: money.Major(1200), Reason: models.ReasonPromotion, EffectiveAt: time.Now()})
+	assert.NoError(t, err)
+
+	salary := money.Major(1000)
+	_, err = store.Update(context.Background(), 1, models.EmployeeChanges{Salary: &salary})
+	assert.NoError(t, err)
+
+	assertProblem(t, approve("2", "bob"), http.StatusConflict, CodeConflict)
+
+	r := httptest.NewRequest(http.MethodPost, "/employee/1/compensation/2/reject", nil)
+	w = httptest.NewRecorder()
+	h.RejectCompensation(w, as(r, "bob", "", map[string]string{"id": "1", "change": "2"}))
+	assertProblem(t, w, http.StatusForbidden, CodeForbidden)
+
+	w = httptest.NewRecorder()
+	h.RejectCompensation(w, as(r, "bob", RoleAdmin, map[string]string{"id": "1", "change": "2"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	list := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/employee/1/compensation"+query, nil)
+		w := httptest.NewRecorder()
+		h.Compensation(w, as(r, "carol", "", map[string]string{"id": "1"}))
+
+		return w
+	}
+
+	w = list("")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp CompensationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 2)
+	assert.Equal(t, models.StatusRejected, resp.Items[0].Status)
+	assert.Equal(t, models.StatusApproved, resp.Items[1].Status)
+
+	w = list("?status=approved")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 1)
+
+	assertProblem(t, list("?status=pending"), http.StatusBadRequest, CodeInvalidQuery)
+}
+
+func TestProposeCompensationInvalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "no salary", id: "1", body: `{"reason": "merit"}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "unknown reason", id: "1", body: `{"salary": 1, "reason": "bonus"}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "long note", id: "1", body: `{"salary": 1, "reason": "merit", "note": "` + strings.Repeat("a", maxNoteLength+1) + `"}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "invalid salary", id: "1", body: `{"salary": -1, "reason": "merit"}`, status: http.StatusBadRequest, code: CodeValidation},
+		{name: "invalid time", id: "1", body: `{"salary": 1, "reason": "merit", "effective_at": "soon"}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "missing employee", id: "99", body: `{"salary": 1, "reason": "merit"}`, status: http.StatusNotFound, code: CodeNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/employee/"+tc.id+"/compensation", strings.NewReader(tc.body))
+			r = mux.SetURLVars(r, map[string]string{"id": tc.id})
+
+			w := httptest.NewRecorder()
+			store := seeded(t)
+			Handler{EmployeeDB: store, CompensationDB: store}.ProposeCompensation(w, r)
+
+			assertProblem(t, w, tc.status, tc.code)
+		})
+	}
+}
+
+func TestCompensationReport(t *testing.T) {
+	store := seeded(t)
+	proposer := audit.WithCaller(context.Background(), audit.Caller{Actor: "alice"})
+	approver := audit.WithCaller(context.Background(), audit.Caller{Actor: "bob"})
+
+	for _, c := range []models.CompensationChange{
+		{EmployeeID: 1, Salary: money.Major(1100), Reason: models.ReasonMerit},
+		{EmployeeID: 2, Salary: money.Major(3000), Reason: models.ReasonPromotion},
+	} {
+		c.EffectiveAt = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		change, err := store.ProposeCompensation(proposer, c)
+		assert.NoError(t, err)
+
+		_, err = store.ApproveCompensation(approver, c.EmployeeID, change.ID, time.Now())
+		assert.NoError(t, err)
+	}
+
+	report := func(query, role string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/employee/compensation/report"+query, nil)
+		r.Header.Set(RoleHeader, role)
+
+		w := httptest.NewRecorder()
+		Handler{EmployeeDB: store, CompensationDB: store}.CompensationReport(w, r)
+
+		return w
+	}
+
+	w := report("", RoleAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"by": "position", "currency": "USD", "items": [
+		{"group": "PM", "changes": 1, "average_raise": 1000, "average_percent": 50},
+		{"group": "SDE", "changes": 1, "average_raise": 100, "average_percent": 10}
+	]}`, w.Body.String())
+
+	w = report("?by=reason&until=2024-06-01T00:00:00Z", RoleAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"by": "reason", "currency": "USD", "items": []}`, w.Body.String())
+
+	assertProblem(t, report("", ""), http.StatusForbidden, CodeForbidden)
+	assertProblem(t, report("?by=salary", RoleAdmin), http.StatusBadRequest, CodeInvalidQuery)
+	assertProblem(t, report("?since=yesterday", RoleAdmin), http.StatusBadRequest, CodeInvalidQuery)
+}
+
+func TestRaiseSummaries(t *testing.T) {
+	converter := money.Converter{Base: "USD", Rates: money.Rates{"EUR": big.NewRat(11, 10)}}
+
+	// each group merges its currencies, raises converted before averaging
+	items, err := raiseSummaries([]models.RaiseSummary{
+		{Group: "PM", Currency: "EUR", Changes: 1, TotalRaise: money.Major(1000), Percents: 1, TotalPercent: 20},
+		{Group: "PM", Currency: "USD", Changes: 2, TotalRaise: money.Major(200), Percents: 1, TotalPercent: 10},
+		{Group: "SDE", Currency: "USD", Changes: 1, TotalRaise: money.Major(100)},
+	}, converter)
+	assert.NoError(t, err)
+	assert.Equal(t, []RaiseSummary{
+		{Group: "PM", Changes: 3, AverageRaise: money.Major(1300) / 3, AveragePercent: 15},
+		{Group: "SDE", Changes: 1, AverageRaise: money.Major(100)},
+	}, items)
+
+	_, err = raiseSummaries([]models.RaiseSummary{{Group: "PM", Currency: "GBP", Changes: 1}}, converter)
+	assert.ErrorIs(t, err, money.ErrNoRate)
+}
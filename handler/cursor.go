@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"example.com/m/Assesment/database"
+)
+
+// Cursors signs the opaque cursors handed out by the employee list, so
+// clients can't forge or edit them.
+type Cursors struct {
+	key []byte
+}
+
+// NewCursors returns Cursors signing with key. Cursors only verify with the
+// key they were signed with, so every instance must share it.
+func NewCursors(key []byte) Cursors {
+	return Cursors{key: key}
+}
+
+// defaultCursors signs with a key made at startup, cursors are then only
+// valid until the process exits.
+var defaultCursors = NewCursors(randomKey())
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+
+	_, err := rand.Read(key)
+	if err != nil {
+		panic("handler: generating the cursor key: " + err.Error())
+	}
+
+	return key
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the position a list page continues from. Exactly one of After
+// and Before is set. List is the listDigest of the filter and sort the
+// position belongs to, the cursor is refused for any other.
+type cursor struct {
+	After  *database.Keyset `json:"a,omitempty"`
+	Before *database.Keyset `json:"b,omitempty"`
+	List   string           `json:"l,omitempty"`
+}
+
+// listDigest identifies a list by its filter and its sort, as formatSort
+// writes it. The filter is normalized first, the conditions ignoring case
+// compared in lower case and the positions in any order. It is empty for
+// the unfiltered list in id order.
+func listDigest(filter database.Filter, sort string) string {
+	if reflect.DeepEqual(filter, database.Filter{}) && sort == "" {
+		return ""
+	}
+
+	if len(filter.Positions) > 0 {
+		positions := make([]string, 0, len(filter.Positions))
+		for _, position := range filter.Positions {
+			positions = append(positions, strings.ToLower(position))
+		}
+
+		slices.Sort(positions)
+		filter.Positions = slices.Compact(positions)
+	}
+
+	filter.NamePrefix = strings.ToLower(filter.NamePrefix)
+	filter.NameContains = strings.ToLower(filter.NameContains)
+
+	data, _ := json.Marshal(struct {
+		Filter database.Filter
+		Sort   string
+	}{filter, sort})
+
+	sum := sha256.Sum256(data)
+
+	return cursorEncoding.EncodeToString(sum[:16])
+}
+
+var cursorEncoding = base64.RawURLEncoding
+
+// encode renders c as payload.signature, both base64url encoded.
+func (c Cursors) encode(cur cursor) string {
+	payload, _ := json.Marshal(cur)
+
+	encoded := cursorEncoding.EncodeToString(payload)
+
+	return encoded + "." + cursorEncoding.EncodeToString(c.sign(encoded))
+}
+
+func (c Cursors) decode(value string) (cursor, error) {
+	var cur cursor
+
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return cur, errInvalidCursor
+	}
+
+	mac, err := cursorEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(encoded)) {
+		return cur, errInvalidCursor
+	}
+
+	payload, err := cursorEncoding.DecodeString(encoded)
+	if err != nil {
+		return cur, errInvalidCursor
+	}
+
+	err = json.Unmarshal(payload, &cur)
+	if err != nil || (cur.After == nil) == (cur.Before == nil) {
+		return cur, errInvalidCursor
+	}
+
+	return cur, nil
+}
+
+func (c Cursors) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(encoded))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
+)
+
+// DepartmentsResponse lists the departments by id.
+type DepartmentsResponse struct {
+	Items []models.Department `json:"items"`
+}
+
+// DepartmentSummary totals the employees of a department that aren't
+// deleted, with salaries converted into the report's currency. The employees
+// in no department are totalled without a department_id and name.
+type DepartmentSummary struct {
+	DepartmentID  int64        `json:"department_id,omitempty"`
+	Name          string       `json:"name,omitempty"`
+	Headcount     int64        `json:"headcount"`
+	Payroll       money.Amount `json:"payroll"`
+	AverageSalary money.Amount `json:"average_salary"`
+}
+
+// DepartmentSummaryResponse lists every department, empty ones included, by
+// id.
+type DepartmentSummaryResponse struct {
+	Currency string              `json:"currency"`
+	Items    []DepartmentSummary `json:"items"`
+}
+
+// CreateDepartment stores a department, answering 201 Created with it.
+func (h Handler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
+	department, ok := readDepartment(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := h.DepartmentDB.CreateDepartment(r.Context(), department)
+	if err != nil {
+		dbError(w, r, err, "error creating department")
+		return
+	}
+
+	department.ID = id
+
+	writeJSON(w, r, http.StatusCreated, department)
+}
+
+// readDepartment reads and validates a department body, responding with a
+// problem and returning false when it is invalid.
+func readDepartment(w http.ResponseWriter, r *http.Request) (models.Department, bool) {
+	var department models.Department
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
+		return department, false
+	}
+
+	err = json.Unmarshal(data, &department)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error unmarshalling body")
+		return department, false
+	}
+
+	department = validation.NormalizeDepartment(department)
+	err = validation.ValidateDepartment(department)
+	if err != nil {
+		validationError(w, r, err)
+		return department, false
+	}
+
+	return department, true
+}
+
+// ListDepartments lists every department.
+func (h Handler) ListDepartments(w http.ResponseWriter, r *http.Request) {
+	departments, err := h.DepartmentDB.ListDepartments(r.Context())
+	if err != nil {
+		dbError(w, r, err, "error listing departments")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, DepartmentsResponse{Items: departments})
+}
+
+// GetDepartment reads one department.
+func (h Handler) GetDepartment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	department, err := h.DepartmentDB.GetDepartment(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error fetching department")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, department)
+}
+
+// UpdateDepartment renames the department.
+func (h Handler) UpdateDepartment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	department, ok := readDepartment(w, r)
+	if !ok {
+		return
+	}
+
+	department.ID = id
+
+	department, err := h.DepartmentDB.UpdateDepartment(r.Context(), department)
+	if err != nil {
+		dbError(w, r, err, "error updating department")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, department)
+}
+
+// DeleteDepartment removes a department once no employee is in it, deleted
+// employees included until they are purged.
+func (h Handler) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DepartmentDB.DeleteDepartment(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error deleting department")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DepartmentSummary reports the headcount and payroll of every department in
+// the base currency of the converter, for admins only.
+func (h Handler) DepartmentSummary(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "read department payrolls") {
+		return
+	}
+
+	departments, err := h.DepartmentDB.ListDepartments(r.Context())
+	if err != nil {
+		dbError(w, r, err, "error listing departments")
+		return
+	}
+
+	summaries, err := h.DepartmentDB.DepartmentSummaries(r.Context())
+	if err != nil {
+		dbError(w, r, err, "error reading department summaries")
+		return
+	}
+
+	converter := h.converter()
+
+	items, err := departmentSummaries(departments, summaries, converter)
+	if err != nil {
+		problemError(w, r, http.StatusInternalServerError, CodeInternal, "error converting payrolls: "+err.Error())
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, DepartmentSummaryResponse{Currency: converter.Base, Items: items})
+}
+
+// departmentSummaries merges the totals of each department in every currency
+// into one item per department in the base currency of converter. A
+// department created after departments were read is listed by id alone.
+func departmentSummaries(departments []models.Department, summaries []models.DepartmentSummary, converter money.Converter) ([]DepartmentSummary, error) {
+	items := make([]DepartmentSummary, 0, len(departments)+1)
+	index := make(map[int64]int, len(departments)+1)
+
+	for _, department := range departments {
+		index[department.ID] = len(items)
+		items = append(items, DepartmentSummary{DepartmentID: department.ID, Name: department.Name})
+	}
+
+	for _, s := range summaries {
+		payroll, err := converter.Convert(s.Payroll, s.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := index[s.DepartmentID]
+		if !ok {
+			i = len(items)
+			index[s.DepartmentID] = i
+			items = append(items, DepartmentSummary{DepartmentID: s.DepartmentID})
+		}
+
+		items[i].Headcount += s.Headcount
+		items[i].Payroll += payroll
+	}
+
+	for i := range items {
+		if items[i].Headcount > 0 {
+			items[i].AverageSalary = items[i].Payroll.Quo(items[i].Headcount)
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepartments(t *testing.T) {
+	store := seeded(t)
+	h := Handler{EmployeeDB: store, DepartmentDB: store}
+
+	serve := func(handle http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/department/"+id, strings.NewReader(body))
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+
+		w := httptest.NewRecorder()
+		handle(w, r)
+
+		return w
+	}
+
+	w := serve(h.CreateDepartment, http.MethodPost, "", `{"name": " Engineering "}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": 1, "name": "Engineering"}`, w.Body.String())
+
+	assertProblem(t, serve(h.CreateDepartment, http.MethodPost, "", `{"name": "Engineering"}`), http.StatusConflict, CodeDuplicate)
+	assertProblem(t, serve(h.CreateDepartment, http.MethodPost, "", `{"name": ""}`), http.StatusBadRequest, CodeValidation)
+	assertProblem(t, serve(h.CreateDepartment, http.MethodPost, "", `{`), http.StatusBadRequest, CodeInvalidBody)
+
+	w = serve(h.CreateDepartment, http.MethodPost, "", `{"name": "Sales"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = serve(h.UpdateDepartment, http.MethodPut, "2", `{"name": "Marketing"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id": 2, "name": "Marketing"}`, w.Body.String())
+
+	assertProblem(t, serve(h.UpdateDepartment, http.MethodPut, "2", `{"name": "Engineering"}`), http.StatusConflict, CodeDuplicate)
+	assertProblem(t, serve(h.UpdateDepartment, http.MethodPut, "9", `{"name": "Legal"}`), http.StatusNotFound, CodeNotFound)
+
+	w = serve(h.GetDepartment, http.MethodGet, "2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id": 2, "name": "Marketing"}`, w.Body.String())
+
+	assertProblem(t, serve(h.GetDepartment, http.MethodGet, "9", ""), http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(h.GetDepartment, http.MethodGet, "x", ""), http.StatusBadRequest, CodeInvalidID)
+
+	w = serve(h.ListDepartments, http.MethodGet, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items": [{"id": 1, "name": "Engineering"}, {"id": 2, "name": "Marketing"}]}`, w.Body.String())
+
+	// employees may only name a department that exists
+	w = serve(h.Create, http.MethodPost, "", `{"name": "Ann", "position": "SDE", "salary": 1500, "currency": "USD", "department_id": 9}`)
+	assertProblem(t, w, http.StatusUnprocessableEntity, CodeConstraint)
+
+	w = serve(h.Create, http.MethodPost, "", `{"name": "Ann", "position": "SDE", "salary": 1500, "currency": "USD", "department_id": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var employee models.Employee
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &employee))
+	assert.Equal(t, int64(1), employee.DepartmentID)
+
+	assertProblem(t, serve(h.DeleteDepartment, http.MethodDelete, "1", ""), http.StatusConflict, CodeConflict)
+
+	w = serve(h.DeleteDepartment, http.MethodDelete, "2", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assertProblem(t, serve(h.DeleteDepartment, http.MethodDelete, "2", ""), http.StatusNotFound, CodeNotFound)
+}
+
+func TestDepartmentSummary(t *testing.T) {
+	store := seeded(t)
+
+	id, err := store.CreateDepartment(context.Background(), models.Department{Name: "Engineering"})
+	assert.NoError(t, err)
+
+	_, err = store.CreateDepartment(context.Background(), models.Department{Name: "Sales"})
+	assert.NoError(t, err)
+
+	for _, e := range []models.Employee{
+		{Name: "Ann", Position: "SDE", Salary: money.Major(3000), Currency: "USD", DepartmentID: id},
+		{Name: "Bob", Position: "SDE", Salary: money.Major(1000), Currency: "EUR", DepartmentID: id},
+	} {
+		_, err = store.Create(context.Background(), e)
+		assert.NoError(t, err)
+	}
+
+	summary := func(role string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/department/summary", nil)
+		r.Header.Set(RoleHeader, role)
+
+		w := httptest.NewRecorder()
+		Handler{
+			EmployeeDB:   store,
+			DepartmentDB: store,
+			Converter:    &money.Converter{Base: "USD", Rates: money.Rates{"EUR": big.NewRat(11, 10)}},
+		}.DepartmentSummary(w, r)
+
+		return w
+	}
+
+	w := summary(RoleAdmin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"currency": "USD", "items": [
+		{"department_id": 1, "name": "Engineering", "headcount": 2, "payroll": 4100, "average_salary": 2050},
+		{"department_id": 2, "name": "Sales", "headcount": 0, "payroll": 0, "average_salary": 0},
+		{"headcount": 2, "payroll": 3000, "average_salary": 1500}
+	]}`, w.Body.String())
+
+	assertProblem(t, summary(""), http.StatusForbidden, CodeForbidden)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -9,214 +10,321 @@ import (
 
 	"example.com/m/Assesment/database"
 	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
 	"github.com/gorilla/mux"
 )
 
 type Handler struct {
 	EmployeeDB database.Employee
+	// HistoryDB keeps the versions and scheduled changes of the employees
+	// of EmployeeDB.
+	HistoryDB database.History
+	// CompensationDB keeps the salary changes proposed for the employees of
+	// EmployeeDB.
+	CompensationDB database.Compensation
+	// DepartmentDB keeps the departments, in the store of EmployeeDB.
+	DepartmentDB database.Department
+	// HierarchyDB reads who reports to whom, in the store of EmployeeDB.
+	HierarchyDB database.Hierarchy
+	// Validator holds the employee rules, the defaults when nil.
+	Validator *validation.Employee
+	// Cursors signs list cursors, with a per process key when nil.
+	Cursors *Cursors
+	// Searcher answers employee searches, search is disabled when nil.
+	Searcher database.Searcher
+	// Converter makes reports in its base currency, only from
+	// money.DefaultCurrency when nil.
+	Converter *money.Converter
+}
+
+var defaultValidator = validation.NewEmployee(validation.EmployeeOptions{})
+
+func (h Handler) converter() money.Converter {
+	if h.Converter == nil {
+		return money.Converter{Base: money.DefaultCurrency}
+	}
+
+	return *h.Converter
+}
+
+func (h Handler) validator() validation.Employee {
+	if h.Validator == nil {
+		return defaultValidator
+	}
+
+	return *h.Validator
 }
 
 func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "error reading body", http.StatusBadRequest)
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
 		return
 	}
 
 	var employee models.Employee
 	err = json.Unmarshal(data, &employee)
 	if err != nil {
-		http.Error(w, "error unmarshalling body", http.StatusBadRequest)
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error unmarshalling body")
 		return
 	}
 
-	// checking mandatory fields
-	employee.Name = strings.TrimSpace(employee.Name)
-	if employee.Name == "" {
-		http.Error(w, "error employee name missing", http.StatusBadRequest)
+	employee = h.validator().Defaults(employee)
+	err = h.validator().ValidateCreate(employee)
+	if err != nil {
+		validationError(w, r, err)
 		return
 	}
 
-	employee.Position = strings.TrimSpace(employee.Position)
-	if employee.Name == "" {
-		http.Error(w, "error employee name missing", http.StatusBadRequest)
+	id, err := h.EmployeeDB.Create(r.Context(), employee)
+	if err != nil {
+		dbError(w, r, err, "error creating employee")
 		return
 	}
 
-	if employee.Salary == 0 {
-		http.Error(w, "error employee name missing", http.StatusBadRequest)
+	employee.ID = id
+	employee.Version = models.InitialVersion
+
+	setETag(w, employee)
+	writeJSON(w, r, http.StatusOK, employee)
+}
+
+// Update replaces every field of the employee with the request body.
+func (h Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
 		return
 	}
 
-	id, err := h.EmployeeDB.Create(employee)
+	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "error creating employee", http.StatusInternalServerError)
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
 		return
 	}
 
-	employee.ID = id
+	var employee models.Employee
+	err = json.Unmarshal(data, &employee)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error unmarshalling body")
+		return
+	}
 
-	response, err := json.Marshal(employee)
+	// a replacement needs the full representation, but for the currency
+	// which is kept when left out
+	employee = validation.Normalize(employee)
+	err = h.validator().ValidateCreate(employee)
 	if err != nil {
-		http.Error(w, "error marshalling response", http.StatusBadRequest)
+		validationError(w, r, err)
 		return
 	}
 
-	w.Write(response)
-}
+	p := parseIfMatch(r)
+	changes := models.ChangesFrom(employee)
 
-func (h Handler) Update(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	stringID := vars["id"]
+	changes.IfVersion, ok = h.ifVersion(w, r, id, p)
+	if !ok {
+		return
+	}
 
-	intID, err := strconv.Atoi(stringID)
-	if err != nil {
-		http.Error(w, "error invalid id", http.StatusBadRequest)
+	h.update(w, r, id, changes, p)
+}
+
+// Patch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// to the employee, chosen by the request's Content-Type.
+func (h Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
 		return
 	}
 
-	// check for empty id
-	id := int64(intID)
-	if id == 0 {
-		http.Error(w, "error empty id", http.StatusBadRequest)
+	var apply func(map[string]interface{}, []byte) error
+
+	mediaType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	switch mediaType {
+	case mergePatchContentType, "application/json":
+		apply = applyMergePatch
+	case jsonPatchContentType:
+		apply = applyJSONPatch
+	default:
+		w.Header().Set("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+		problemError(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "unsupported patch format "+strconv.Quote(mediaType))
 		return
 	}
 
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "error reading body", http.StatusBadRequest)
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
 		return
 	}
 
-	var employee models.Employee
-	err = json.Unmarshal(data, &employee)
+	p := parseIfMatch(r)
+
+	current, err := h.EmployeeDB.Get(r.Context(), id)
 	if err != nil {
-		http.Error(w, "error unmarshalling body", http.StatusBadRequest)
+		p.writeError(w, r, err, "error fetching employee details")
 		return
 	}
 
-	// mandatory check for atleast one field
-	employee.Name = strings.TrimSpace(employee.Name)
-	employee.Position = strings.TrimSpace(employee.Position)
-	if employee.Name == "" && employee.Position == "" && employee.Salary == 0 {
-		http.Error(w, "error no fields to update", http.StatusBadRequest)
+	ifVersion := p.ifVersion(current)
+	if ifVersion != 0 && ifVersion != current.Version {
+		p.writeError(w, r, database.ErrVersionMismatch, "")
 		return
 	}
 
-	err = h.EmployeeDB.Update(employee, id)
+	doc := employeeDocument(current)
+	err = apply(doc, data)
 	if err != nil {
-		http.Error(w, "error creating employee", http.StatusInternalServerError)
+		var pe *patchError
+		if errors.As(err, &pe) {
+			problemError(w, r, pe.status, pe.code, pe.detail)
+			return
+		}
+
+		problemError(w, r, http.StatusBadRequest, CodeInvalidPatch, err.Error())
 		return
 	}
 
-	employee, err = h.EmployeeDB.Get(id)
+	changes, err := changesFromDocument(current, doc)
 	if err != nil {
-		http.Error(w, "error fetching empoyee details", http.StatusInternalServerError)
+		validationError(w, r, err)
 		return
 	}
 
-	response, err := json.Marshal(employee)
+	changes = validation.NormalizeChanges(changes)
+	err = h.validator().ValidateChanges(changes)
 	if err != nil {
-		http.Error(w, "error marshalling response", http.StatusBadRequest)
+		validationError(w, r, err)
 		return
 	}
 
-	w.Write(response)
+	// the patch was computed against current, so the write must find it
+	// unchanged whether or not the client asked for a precondition
+	changes.IfVersion = current.Version
+
+	h.update(w, r, id, changes, p)
 }
 
-func (h Handler) Get(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	stringID := vars["id"]
+// ifVersion resolves the request's If-Match to the version the write must
+// find, fetching the employee only when several tags are listed.
+func (h Handler) ifVersion(w http.ResponseWriter, r *http.Request, id int64, p precondition) (int64, bool) {
+	if len(p.versions) <= 1 {
+		return p.ifVersion(models.Employee{}), true
+	}
 
-	intID, err := strconv.Atoi(stringID)
+	current, err := h.EmployeeDB.Get(r.Context(), id)
 	if err != nil {
-		http.Error(w, "error invalid id", http.StatusBadRequest)
+		dbError(w, r, err, "error fetching employee details")
+		return 0, false
+	}
+
+	return p.ifVersion(current), true
+}
+
+// update writes changes and responds with the employee as stored.
+func (h Handler) update(w http.ResponseWriter, r *http.Request, id int64, changes models.EmployeeChanges, p precondition) {
+	employee, err := h.EmployeeDB.Update(r.Context(), id, changes)
+	if err != nil {
+		p.writeError(w, r, err, "error updating employee")
 		return
 	}
 
-	// check for empty id
-	id := int64(intID)
-	if id == 0 {
-		http.Error(w, "error empty id", http.StatusBadRequest)
+	setETag(w, employee)
+	writeJSON(w, r, http.StatusOK, employee)
+}
+
+// Get responds with the employee, as it was at the ?as_of= time when given.
+func (h Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
 		return
 	}
 
-	employee, err := h.EmployeeDB.Get(id)
-	if err != nil {
-		http.Error(w, "error fetching empoyee details", http.StatusInternalServerError)
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		h.getAsOf(w, r, id, asOf)
 		return
 	}
 
-	response, err := json.Marshal(employee)
+	employee, err := h.EmployeeDB.Get(r.Context(), id)
 	if err != nil {
-		http.Error(w, "error marshalling response", http.StatusBadRequest)
+		dbError(w, r, err, "error fetching employee details")
 		return
 	}
 
-	w.Write(response)
-}
-
-func (h Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
-	pageParam := queryParams.Get("page")
-
-	page, err := strconv.Atoi(pageParam)
-	if err != nil {
-		http.Error(w, "error invalid page value "+pageParam, http.StatusBadRequest)
+	setETag(w, employee)
+	if noneMatched(r, employee) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	pageLimitParam := queryParams.Get("pagelimit")
+	writeJSON(w, r, http.StatusOK, employee)
+}
 
-	pageLimit, err := strconv.Atoi(pageLimitParam)
-	if err != nil {
-		http.Error(w, "error invalid pagelimit", http.StatusBadRequest)
+// Delete marks the employee deleted, admins can restore it until it is
+// purged.
+func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
 		return
 	}
 
-	employees, err := h.EmployeeDB.GetAll(page, pageLimit)
-	if err != nil {
-		http.Error(w, "error fetching all empoyee details", http.StatusInternalServerError)
+	p := parseIfMatch(r)
+
+	ifVersion, ok := h.ifVersion(w, r, id, p)
+	if !ok {
 		return
 	}
 
-	response, err := json.Marshal(employees)
+	err := h.EmployeeDB.Delete(r.Context(), id, ifVersion)
 	if err != nil {
-		http.Error(w, "error marshalling response", http.StatusBadRequest)
+		p.writeError(w, r, err, "error deleting employee")
 		return
 	}
 
-	w.Write(response)
+	writeJSON(w, r, http.StatusOK, "employee deleted sucessfully")
 }
 
-func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	stringID := vars["id"]
-
-	intID, err := strconv.Atoi(stringID)
-	if err != nil {
-		http.Error(w, "error invalid id", http.StatusBadRequest)
+// Restore brings back a deleted employee, for admins only. If-Match is
+// honoured when it names a single version, a deleted employee can't be
+// read to pick one of several.
+func (h Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "restore employees") {
 		return
 	}
 
-	// check for empty id
-	id := int64(intID)
-	if id == 0 {
-		http.Error(w, "error empty id", http.StatusBadRequest)
+	id, ok := parseID(w, r)
+	if !ok {
 		return
 	}
 
-	err = h.EmployeeDB.Delete(id)
+	p := parseIfMatch(r)
+
+	employee, err := h.EmployeeDB.Restore(r.Context(), id, p.ifVersion(models.Employee{}))
 	if err != nil {
-		http.Error(w, "error deleting employee", http.StatusInternalServerError)
+		p.writeError(w, r, err, "error restoring employee")
 		return
 	}
 
-	response, err := json.Marshal("employee deleted sucessfully")
+	setETag(w, employee)
+	writeJSON(w, r, http.StatusOK, employee)
+}
+
+// parseID reads the {id} path variable, responding with a problem and
+// returning false when it is not a positive integer.
+func parseID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	stringID := mux.Vars(r)["id"]
+
+	id, err := strconv.ParseInt(stringID, 10, 64)
 	if err != nil {
-		http.Error(w, "error marshalling response", http.StatusBadRequest)
-		return
+		problemError(w, r, http.StatusBadRequest, CodeInvalidID, "invalid id "+strconv.Quote(stringID))
+		return 0, false
+	}
+
+	// check for empty id
+	if id <= 0 {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidID, "id must be a positive integer")
+		return 0, false
 	}
 
-	w.Write(response)
+	return id, true
 }
@@ -2,15 +2,20 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"example.com/m/Assesment/database"
 	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
@@ -23,11 +28,12 @@ func TestCreate(t *testing.T) {
 		result         int64
 		err            error
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name:           "Successful Create Request",
 			body:           models.Employee{Name: "John", Position: "SDE", Salary: 30000},
-			response:       models.Employee{ID: 1, Name: "John", Position: "SDE", Salary: 30000},
+			response:       models.Employee{ID: 1, Name: "John", Position: "SDE", Salary: 30000, Currency: "USD"},
 			err:            nil,
 			result:         1,
 			expectedStatus: http.StatusOK,
@@ -38,6 +44,28 @@ func TestCreate(t *testing.T) {
 			err:            errors.New("TestError"),
 			result:         0,
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+		},
+		{
+			name:           "Duplicate from db",
+			body:           models.Employee{Name: "John", Position: "SDE", Salary: 30000},
+			err:            database.ErrDuplicate,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeDuplicate,
+		},
+		{
+			name:           "Constraint violation from db",
+			body:           models.Employee{Name: "John", Position: "SDE", Salary: 30000},
+			err:            database.ErrConstraint,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   CodeConstraint,
+		},
+		{
+			name:           "Database unavailable",
+			body:           models.Employee{Name: "John", Position: "SDE", Salary: 30000},
+			err:            database.ErrUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   CodeUnavailable,
 		},
 		{
 			name:           "Mandatory salary check error",
@@ -45,6 +73,7 @@ func TestCreate(t *testing.T) {
 			err:            nil,
 			result:         0,
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
 		},
 		{
 			name:           "Mandatory name check error",
@@ -52,6 +81,7 @@ func TestCreate(t *testing.T) {
 			err:            nil,
 			result:         0,
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
 		},
 		{
 			name:           "Mandatory position check error",
@@ -59,6 +89,7 @@ func TestCreate(t *testing.T) {
 			err:            nil,
 			result:         0,
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
 		},
 	}
 
@@ -67,7 +98,7 @@ func TestCreate(t *testing.T) {
 
 			//mock for dependency
 			testDatabase := new(database.MockDatabase)
-			testDatabase.CreateF = func(models.Employee) (int64, error) {
+			testDatabase.CreateF = func(context.Context, models.Employee) (int64, error) {
 				return tc.result, tc.err
 			}
 
@@ -92,6 +123,10 @@ func TestCreate(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rr.Code)
 			}
 
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+			}
+
 			if tc.expectedStatus == http.StatusOK {
 				resp := models.Employee{}
 				data, err = io.ReadAll(rr.Body)
@@ -120,6 +155,7 @@ func TestUpdate(t *testing.T) {
 		id             string
 		err            error
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name:           "Successful Update Request",
@@ -135,6 +171,39 @@ func TestUpdate(t *testing.T) {
 			err:            errors.New("TestError"),
 			id:             "1",
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+		},
+		{
+			name:           "Missing employee",
+			body:           models.Employee{Name: "John", Position: "SDE", Salary: 30000},
+			err:            database.ErrNotFound,
+			id:             "1",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeNotFound,
+		},
+		{
+			name:           "Conflicting change",
+			body:           models.Employee{Name: "John", Position: "SDE", Salary: 30000},
+			err:            database.ErrConflict,
+			id:             "1",
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeConflict,
+		},
+		{
+			name:           "Partial body is not a replacement",
+			body:           models.Employee{Name: "John"},
+			err:            nil,
+			id:             "1",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
+		},
+		{
+			name:           "Invalid salary on update",
+			body:           models.Employee{Name: "John", Position: "SDE", Salary: -1},
+			err:            nil,
+			id:             "1",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
 		},
 		{
 			name:           "Empty update check",
@@ -142,6 +211,7 @@ func TestUpdate(t *testing.T) {
 			err:            nil,
 			id:             "1",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
 		},
 		{
 			name:           "Empty id check",
@@ -149,6 +219,7 @@ func TestUpdate(t *testing.T) {
 			err:            nil,
 			id:             "0",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidID,
 		},
 		{
 			name:           "Invalid id check",
@@ -156,6 +227,7 @@ func TestUpdate(t *testing.T) {
 			err:            nil,
 			id:             "a",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidID,
 		},
 	}
 
@@ -164,12 +236,10 @@ func TestUpdate(t *testing.T) {
 
 			//mock for dependency
 			testDatabase := new(database.MockDatabase)
-			testDatabase.UpdateF = func(models.Employee, int64) error {
-				return tc.err
-			}
-
-			testDatabase.GetF = func(id int64) (models.Employee, error) {
-				return tc.response, nil
+			testDatabase.UpdateF = func(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+				// a replacement writes every field
+				assert.Equal(t, models.ChangesFrom(tc.body), changes)
+				return tc.response, tc.err
 			}
 
 			mockHandler := Handler{EmployeeDB: testDatabase}
@@ -197,6 +267,10 @@ func TestUpdate(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rr.Code)
 			}
 
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+			}
+
 			if tc.expectedStatus == http.StatusOK {
 				resp := models.Employee{}
 				data, err = io.ReadAll(rr.Body)
@@ -224,6 +298,7 @@ func TestGet(t *testing.T) {
 		id             string
 		err            error
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name:           "Successful Get Request",
@@ -237,18 +312,28 @@ func TestGet(t *testing.T) {
 			err:            errors.New("TestError"),
 			id:             "1",
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+		},
+		{
+			name:           "Missing employee",
+			err:            database.ErrNotFound,
+			id:             "1",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeNotFound,
 		},
 		{
 			name:           "Empty id check",
 			err:            nil,
 			id:             "0",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidID,
 		},
 		{
 			name:           "Invalid id check",
 			err:            nil,
 			id:             "a",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidID,
 		},
 	}
 
@@ -258,7 +343,7 @@ func TestGet(t *testing.T) {
 			//mock for dependency
 			testDatabase := new(database.MockDatabase)
 
-			testDatabase.GetF = func(id int64) (models.Employee, error) {
+			testDatabase.GetF = func(ctx context.Context, id int64) (models.Employee, error) {
 				return tc.response, tc.err
 			}
 
@@ -282,6 +367,10 @@ func TestGet(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rr.Code)
 			}
 
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+			}
+
 			if tc.expectedStatus == http.StatusOK {
 				resp := models.Employee{}
 				data, err := io.ReadAll(rr.Body)
@@ -309,6 +398,7 @@ func TestGetAll(t *testing.T) {
 		queryParams    string
 		err            error
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name:           "Successful Get Request",
@@ -322,18 +412,39 @@ func TestGetAll(t *testing.T) {
 			err:            errors.New("TestError"),
 			queryParams:    "?page=2&pagelimit=20",
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
 		},
 		{
 			name:           "Invalid page check",
 			err:            nil,
 			queryParams:    "?page=apple&pagelimit=20",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
 		},
 		{
 			name:           "Invalid pagelimit check",
 			err:            nil,
 			queryParams:    "?page=2&pagelimit=apple",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Zero page check",
+			queryParams:    "?page=0&pagelimit=20",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Unbounded pagelimit check",
+			queryParams:    "?page=1&pagelimit=1000000",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Page defaults its limit",
+			response:       []models.Employee{{ID: 1, Name: "John", Position: "SDE-2", Salary: 30000}},
+			queryParams:    "?page=1",
+			expectedStatus: http.StatusOK,
 		},
 	}
 
@@ -343,7 +454,7 @@ func TestGetAll(t *testing.T) {
 			//mock for dependency
 			testDatabase := new(database.MockDatabase)
 
-			testDatabase.GetAllF = func(page, pageLimit int) ([]models.Employee, error) {
+			testDatabase.GetAllF = func(ctx context.Context, page, pageLimit int) ([]models.Employee, error) {
 				return tc.response, tc.err
 			}
 
@@ -363,6 +474,10 @@ func TestGetAll(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rr.Code)
 			}
 
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+			}
+
 			if tc.expectedStatus == http.StatusOK {
 				resp := []models.Employee{}
 				data, err := io.ReadAll(rr.Body)
@@ -389,6 +504,7 @@ func TestDelete(t *testing.T) {
 		id             string
 		err            error
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name:           "Successful Delete Request",
@@ -401,18 +517,35 @@ func TestDelete(t *testing.T) {
 			err:            errors.New("TestError"),
 			id:             "1",
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+		},
+		{
+			name:           "Missing employee",
+			err:            database.ErrNotFound,
+			id:             "1",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeNotFound,
+		},
+		{
+			name:           "Database unavailable",
+			err:            database.ErrUnavailable,
+			id:             "1",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   CodeUnavailable,
 		},
 		{
 			name:           "Empty id check",
 			err:            nil,
 			id:             "0",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidID,
 		},
 		{
 			name:           "Invalid id check",
 			err:            nil,
 			id:             "a",
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidID,
 		},
 	}
 
@@ -422,7 +555,7 @@ func TestDelete(t *testing.T) {
 			//mock for dependency
 			testDatabase := new(database.MockDatabase)
 
-			testDatabase.DeleteF = func(id int64) error {
+			testDatabase.DeleteF = func(ctx context.Context, id int64, ifVersion int64) error {
 				return tc.err
 			}
 
@@ -446,6 +579,276 @@ func TestDelete(t *testing.T) {
 				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rr.Code)
 			}
 
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+			}
+
 		})
 	}
 }
+
+func TestSoftDelete(t *testing.T) {
+	store := seeded(t)
+	h := Handler{EmployeeDB: store}
+
+	request := func(method, target, role string) *http.Request {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(RoleHeader, role)
+		return mux.SetURLVars(req, map[string]string{"id": "1"})
+	}
+
+	rr := httptest.NewRecorder()
+	h.Delete(rr, request(http.MethodDelete, "/employee/1", ""))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.Get(rr, request(http.MethodGet, "/employee/1", ""))
+	assertProblem(t, rr, http.StatusNotFound, CodeNotFound)
+
+	// only admins see deleted employees
+	rr = httptest.NewRecorder()
+	h.GetAll(rr, request(http.MethodGet, "/employee/?include_deleted=true", ""))
+	assertProblem(t, rr, http.StatusForbidden, CodeForbidden)
+
+	rr = httptest.NewRecorder()
+	h.GetAll(rr, request(http.MethodGet, "/employee/?include_deleted=true", RoleAdmin))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var list struct{ Items []models.Employee }
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Equal(t, []int64{1, 2}, employeeIDs(list.Items))
+	assert.NotNil(t, list.Items[0].DeletedAt)
+
+	// and restore them
+	rr = httptest.NewRecorder()
+	h.Restore(rr, request(http.MethodPost, "/employee/1/restore", ""))
+	assertProblem(t, rr, http.StatusForbidden, CodeForbidden)
+
+	req := request(http.MethodPost, "/employee/1/restore", RoleAdmin)
+	req.Header.Set("If-Match", `"1"`)
+
+	rr = httptest.NewRecorder()
+	h.Restore(rr, req)
+	assertProblem(t, rr, http.StatusPreconditionFailed, CodePreconditionFailed)
+
+	rr = httptest.NewRecorder()
+	h.Restore(rr, request(http.MethodPost, "/employee/1/restore", RoleAdmin))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+
+	rr = httptest.NewRecorder()
+	h.Restore(rr, request(http.MethodPost, "/employee/1/restore", RoleAdmin))
+	assertProblem(t, rr, http.StatusConflict, CodeConflict)
+
+	rr = httptest.NewRecorder()
+	h.Get(rr, request(http.MethodGet, "/employee/1", ""))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRequestContext(t *testing.T) {
+	type ctxKey struct{}
+
+	// the handler must hand the request's context down to the database
+	testDatabase := new(database.MockDatabase)
+	testDatabase.GetF = func(ctx context.Context, id int64) (models.Employee, error) {
+		if ctx.Value(ctxKey{}) != "request" {
+			t.Error("database called without the request context")
+		}
+
+		return models.Employee{}, ctx.Err()
+	}
+
+	mockHandler := Handler{EmployeeDB: testDatabase}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request"))
+	cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "employee", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+
+	rr := httptest.NewRecorder()
+	mockHandler.Get(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+}
+
+// assertProblem checks rr holds a problem+json body for status and code and
+// returns it for further checks.
+func assertProblem(t *testing.T, rr *httptest.ResponseRecorder, status int, code string) Problem {
+	t.Helper()
+
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var problem Problem
+	err := json.Unmarshal(rr.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatalf("invalid problem body %q: %v", rr.Body.String(), err)
+	}
+
+	assert.Equal(t, status, problem.Status)
+	assert.Equal(t, code, problem.Code)
+	assert.Equal(t, "/problems/"+code, problem.Type)
+	assert.Equal(t, http.StatusText(status), problem.Title)
+	assert.NotEmpty(t, problem.Detail)
+	assert.NotEmpty(t, problem.Instance)
+
+	return problem
+}
+
+func TestCreateValidationErrors(t *testing.T) {
+	testDatabase := new(database.MockDatabase)
+	mockHandler := Handler{EmployeeDB: testDatabase}
+
+	// every missing field is reported at once, each with its own message
+	req, err := http.NewRequest(http.MethodPost, "/employee", bytes.NewReader([]byte(`{"name": "  "}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	mockHandler.Create(rr, req)
+
+	problem := assertProblem(t, rr, http.StatusBadRequest, CodeValidation)
+	assert.Equal(t, "/employee", problem.Instance)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Code: validation.CodeRequired, Message: "is required"},
+		{Field: "position", Code: validation.CodeRequired, Message: "is required"},
+		{Field: "salary", Code: validation.CodeRequired, Message: "is required"},
+	}, problem.Errors)
+
+	// configured rules are applied
+	v := validation.NewEmployee(validation.EmployeeOptions{Positions: []string{"SDE"}})
+	mockHandler.Validator = &v
+
+	req, err = http.NewRequest(http.MethodPost, "/employee", bytes.NewReader([]byte(`{"name": "John", "position": "CEO", "salary": -1}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	mockHandler.Create(rr, req)
+
+	problem = assertProblem(t, rr, http.StatusBadRequest, CodeValidation)
+	assert.Equal(t, []string{"position", "salary"}, []string{problem.Errors[0].Field, problem.Errors[1].Field})
+	assert.Equal(t, validation.CodeNotAllowed, problem.Errors[0].Code)
+
+	// malformed json
+	req, err = http.NewRequest(http.MethodPost, "/employee", bytes.NewReader([]byte(`{"name":`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	mockHandler.Create(rr, req)
+
+	assertProblem(t, rr, http.StatusBadRequest, CodeInvalidBody)
+}
+
+func TestCreateMoney(t *testing.T) {
+	create := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/employee", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		Handler{EmployeeDB: database.NewMemory()}.Create(w, r)
+
+		return w
+	}
+
+	// a salary may be a string, kept to the cent without going through a float
+	w := create(`{"name": "John", "position": "SDE", "salary": "1234567.89", "currency": "eur"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id": 1, "name": "John", "position": "SDE", "salary": 1234567.89, "currency": "EUR"}`, w.Body.String())
+
+	assertProblem(t, create(`{"name": "John", "position": "SDE", "salary": 10.001}`), http.StatusBadRequest, CodeInvalidBody)
+
+	problem := assertProblem(t, create(`{"name": "John", "position": "SDE", "salary": 10.5, "currency": "JPY"}`), http.StatusBadRequest, CodeValidation)
+	assert.Equal(t, []FieldError{{Field: "salary", Code: validation.CodePrecision, Message: "has more decimals than JPY allows"}}, problem.Errors)
+
+	problem = assertProblem(t, create(`{"name": "John", "position": "SDE", "salary": 10, "currency": "XBT"}`), http.StatusBadRequest, CodeValidation)
+	assert.Equal(t, "currency", problem.Errors[0].Field)
+}
+
+func TestChangeMoney(t *testing.T) {
+	// employee 1 is paid in yen, employee 2 in dollars and cents
+	serve := func(t *testing.T, target, body string, handle func(h Handler, w http.ResponseWriter, r *http.Request)) (*httptest.ResponseRecorder, *database.Memory) {
+		store := database.NewMemory()
+		for _, e := range []models.Employee{
+			{Name: "Yuki", Position: "SDE", Salary: money.Major(1000), Currency: "JPY"},
+			{Name: "John", Position: "SDE", Salary: money.Major(1000) + 50, Currency: "USD"},
+		} {
+			_, err := store.Create(context.Background(), e)
+			assert.NoError(t, err)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", mergePatchContentType)
+
+		w := httptest.NewRecorder()
+		handle(Handler{EmployeeDB: store, HistoryDB: store, CompensationDB: store}, w, r)
+
+		return w, store
+	}
+
+	on := func(id string, handle func(h Handler, w http.ResponseWriter, r *http.Request)) func(h Handler, w http.ResponseWriter, r *http.Request) {
+		return func(h Handler, w http.ResponseWriter, r *http.Request) {
+			handle(h, w, mux.SetURLVars(r, map[string]string{"id": id}))
+		}
+	}
+
+	effectiveAt := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	tt := []struct {
+		name   string
+		body   string
+		handle func(h Handler, w http.ResponseWriter, r *http.Request)
+	}{
+		{name: "patched salary", body: `{"salary": 1000.5}`, handle: on("1", Handler.Patch)},
+		{name: "patched currency", body: `{"currency": "JPY"}`, handle: on("2", Handler.Patch)},
+		{name: "replaced keeping the currency", body: `{"name": "Yuki", "position": "SDE", "salary": 1000.5}`, handle: on("1", Handler.Update)},
+		{name: "scheduled salary", body: `{"effective_at": "` + effectiveAt + `", "salary": 1000.5}`, handle: on("1", Handler.Schedule)},
+		{name: "scheduled currency", body: `{"effective_at": "` + effectiveAt + `", "currency": "JPY"}`, handle: on("2", Handler.Schedule)},
+		{name: "proposed salary", body: `{"salary": 1000.5, "reason": "merit"}`, handle: on("1", Handler.ProposeCompensation)},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w, store := serve(t, "/employee", tc.body, tc.handle)
+			assertProblem(t, w, http.StatusUnprocessableEntity, CodeConstraint)
+
+			employee, err := store.Get(context.Background(), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, money.Major(1000), employee.Salary)
+		})
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		w, _ := serve(t, "/employee/batch?mode=best_effort", `[{"id": 1, "salary": 1000.5}, {"id": 2, "currency": "JPY"}]`, Handler.UpdateBatch)
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+		var resp BatchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []int{http.StatusUnprocessableEntity, http.StatusUnprocessableEntity}, statuses(resp))
+	})
+
+	// a salary and the currency it fits may change together
+	w, _ := serve(t, "/employee", `{"salary": 1000, "currency": "JPY"}`, on("2", Handler.Patch))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+)
+
+// etag is the strong entity tag of an employee, its quoted version.
+func etag(employee models.Employee) string {
+	return strconv.Quote(strconv.FormatInt(employee.Version, 10))
+}
+
+func setETag(w http.ResponseWriter, employee models.Employee) {
+	w.Header().Set("ETag", etag(employee))
+}
+
+// entityTags splits an If-Match or If-None-Match header into its tags.
+// wildcard reports a "*" header.
+func entityTags(header string) (tags []string, wildcard bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, false
+}
+
+// tagVersion reads the version out of an entity tag. Weak tags never match
+// strongly and are only accepted when weak is true.
+func tagVersion(tag string, weak bool) (int64, bool) {
+	if strings.HasPrefix(tag, "W/") {
+		if !weak {
+			return 0, false
+		}
+
+		tag = tag[len("W/"):]
+	}
+
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// noVersion is never stored, a write conditional on it always fails.
+const noVersion int64 = -1
+
+// precondition is the If-Match header of a request resolved to a version.
+type precondition struct {
+	// present reports that the request carried If-Match
+	present bool
+	// versions are the strongly comparable versions listed, empty for "*"
+	versions []int64
+}
+
+// parseIfMatch reads If-Match. A header listing no usable strong tag can never
+// match and resolves to an impossible version.
+func parseIfMatch(r *http.Request) precondition {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return precondition{}
+	}
+
+	tags, wildcard := entityTags(header)
+	p := precondition{present: true}
+	if wildcard {
+		return p
+	}
+
+	for _, tag := range tags {
+		version, ok := tagVersion(tag, false)
+		if ok {
+			p.versions = append(p.versions, version)
+		}
+	}
+
+	if len(p.versions) == 0 {
+		p.versions = []int64{noVersion}
+	}
+
+	return p
+}
+
+// ifVersion picks the version a conditional write must find. current is the
+// stored employee, it is only consulted when several tags are listed.
+func (p precondition) ifVersion(current models.Employee) int64 {
+	switch len(p.versions) {
+	case 0:
+		return 0
+	case 1:
+		return p.versions[0]
+	}
+
+	for _, version := range p.versions {
+		if version == current.Version {
+			return version
+		}
+	}
+
+	return noVersion
+}
+
+// noneMatched reports whether If-None-Match lists the employee's tag, compared
+// weakly as RFC 9110 asks for.
+func noneMatched(r *http.Request, employee models.Employee) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	tags, wildcard := entityTags(header)
+	if wildcard {
+		return true
+	}
+
+	for _, tag := range tags {
+		version, ok := tagVersion(tag, true)
+		if ok && version == employee.Version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeError responds to an error from a conditional write. With If-Match a
+// version mismatch fails the precondition, as does a missing employee for
+// "*" since there is no current representation.
+func (p precondition) writeError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	if p.present && (errors.Is(err, database.ErrVersionMismatch) || errors.Is(err, database.ErrNotFound) && len(p.versions) == 0) {
+		problemError(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, "the employee does not match If-Match")
+		return
+	}
+
+	dbError(w, r, err, detail)
+}
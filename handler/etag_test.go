@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetETag(t *testing.T) {
+	employee := models.Employee{ID: 1, Name: "John", Position: "SDE", Salary: 30000, Version: 3}
+
+	testCases := []struct {
+		name           string
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{name: "No precondition", expectedStatus: http.StatusOK},
+		{name: "Current tag", ifNoneMatch: `"3"`, expectedStatus: http.StatusNotModified},
+		{name: "Weak current tag", ifNoneMatch: `"1", W/"3"`, expectedStatus: http.StatusNotModified},
+		{name: "Any tag", ifNoneMatch: "*", expectedStatus: http.StatusNotModified},
+		{name: "Stale tag", ifNoneMatch: `"2"`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDatabase := new(database.MockDatabase)
+			testDatabase.GetF = func(ctx context.Context, id int64) (models.Employee, error) {
+				return employee, nil
+			}
+
+			mockHandler := Handler{EmployeeDB: testDatabase}
+
+			req, err := http.NewRequest(http.MethodGet, "employee", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			mockHandler.Get(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+
+			if tc.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, rr.Body.String())
+			} else {
+				// the version is only exposed through the ETag
+				assert.JSONEq(t, mustJSON(t, employee), rr.Body.String())
+				assert.NotContains(t, rr.Body.String(), "version")
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	current := models.Employee{ID: 1, Name: "John", Position: "SDE", Salary: 30000, Version: 3}
+
+	testCases := []struct {
+		name              string
+		method            string
+		ifMatch           string
+		writeErr          error
+		expectedIfVersion int64
+		skipsWrite        bool
+		expectedStatus    int
+		expectedCode      string
+	}{
+		{
+			name:              "Put without precondition",
+			method:            http.MethodPut,
+			expectedIfVersion: 0,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:              "Put with current tag",
+			method:            http.MethodPut,
+			ifMatch:           `"3"`,
+			expectedIfVersion: 3,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:              "Put with stale tag",
+			method:            http.MethodPut,
+			ifMatch:           `"2"`,
+			writeErr:          database.ErrVersionMismatch,
+			expectedIfVersion: 2,
+			expectedStatus:    http.StatusPreconditionFailed,
+			expectedCode:      CodePreconditionFailed,
+		},
+		{
+			name:              "Put with a list resolves against the stored version",
+			method:            http.MethodPut,
+			ifMatch:           `"1", "3"`,
+			expectedIfVersion: 3,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:              "Put with a weak tag never matches",
+			method:            http.MethodPut,
+			ifMatch:           `W/"3"`,
+			writeErr:          database.ErrVersionMismatch,
+			expectedIfVersion: noVersion,
+			expectedStatus:    http.StatusPreconditionFailed,
+			expectedCode:      CodePreconditionFailed,
+		},
+		{
+			name:              "Put with any tag on a missing employee",
+			method:            http.MethodPut,
+			ifMatch:           "*",
+			writeErr:          database.ErrNotFound,
+			expectedIfVersion: 0,
+			expectedStatus:    http.StatusPreconditionFailed,
+			expectedCode:      CodePreconditionFailed,
+		},
+		{
+			name:              "Patch without precondition compares the version it read",
+			method:            http.MethodPatch,
+			expectedIfVersion: 3,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:              "Patch racing another write is a conflict",
+			method:            http.MethodPatch,
+			writeErr:          database.ErrVersionMismatch,
+			expectedIfVersion: 3,
+			expectedStatus:    http.StatusConflict,
+			expectedCode:      CodeConflict,
+		},
+		{
+			name:           "Patch with stale tag is refused before writing",
+			method:         http.MethodPatch,
+			ifMatch:        `"2"`,
+			skipsWrite:     true,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   CodePreconditionFailed,
+		},
+		{
+			name:              "Delete with current tag",
+			method:            http.MethodDelete,
+			ifMatch:           `"3"`,
+			expectedIfVersion: 3,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:              "Delete with stale tag",
+			method:            http.MethodDelete,
+			ifMatch:           `"2"`,
+			writeErr:          database.ErrVersionMismatch,
+			expectedIfVersion: 2,
+			expectedStatus:    http.StatusPreconditionFailed,
+			expectedCode:      CodePreconditionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			written := false
+			stored := current
+
+			testDatabase := new(database.MockDatabase)
+			testDatabase.GetF = func(ctx context.Context, id int64) (models.Employee, error) {
+				return stored, nil
+			}
+			testDatabase.UpdateF = func(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+				written = true
+				assert.Equal(t, tc.expectedIfVersion, changes.IfVersion)
+				if tc.writeErr == nil {
+					stored = changes.Apply(stored)
+					stored.Version++
+				}
+
+				return stored, tc.writeErr
+			}
+			testDatabase.DeleteF = func(ctx context.Context, id int64, ifVersion int64) error {
+				written = true
+				assert.Equal(t, tc.expectedIfVersion, ifVersion)
+				return tc.writeErr
+			}
+
+			mockHandler := Handler{EmployeeDB: testDatabase}
+
+			body := `{"name": "Jane", "position": "SDE", "salary": 30000}`
+			req, err := http.NewRequest(tc.method, "employee", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			req.Header.Set("Content-Type", mergePatchContentType)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			switch tc.method {
+			case http.MethodPut:
+				mockHandler.Update(rr, req)
+			case http.MethodPatch:
+				mockHandler.Patch(rr, req)
+			case http.MethodDelete:
+				mockHandler.Delete(rr, req)
+			}
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, !tc.skipsWrite, written)
+
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+				return
+			}
+
+			if tc.method != http.MethodDelete {
+				assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestCreateETag(t *testing.T) {
+	testDatabase := new(database.MockDatabase)
+	testDatabase.CreateF = func(ctx context.Context, employee models.Employee) (int64, error) {
+		return 1, nil
+	}
+
+	mockHandler := Handler{EmployeeDB: testDatabase}
+
+	body := `{"name": "John", "position": "SDE", "salary": 30000}`
+	req, err := http.NewRequest(http.MethodPost, "employee", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	mockHandler.Create(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+}
@@ -0,0 +1,387 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportFormat is a representation an export can be streamed in.
+type exportFormat struct {
+	name        string
+	contentType string
+	// compressible formats are gzipped when the client accepts it, XLSX
+	// is a zip archive already
+	compressible bool
+	newWriter    func(w io.Writer, fields []string) (rowWriter, error)
+}
+
+var exportFormats = []exportFormat{
+	{name: "csv", contentType: "text/csv", compressible: true, newWriter: newCSVRows},
+	{name: "ndjson", contentType: "application/x-ndjson", compressible: true, newWriter: newNDJSONRows},
+	{name: "xlsx", contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newWriter: newXLSXRows},
+}
+
+// Export streams every employee matching the list filters, in the list's
+// sort and reduced to its fields, as CSV, NDJSON or XLSX. The format is
+// negotiated from Accept, ?format= overrides it. Rows go out as they are
+// read from the database, so memory stays flat whatever the table's size.
+func (h Handler) Export(w http.ResponseWriter, r *http.Request) {
+	format, ok := negotiateExport(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFields(w, r)
+	if !ok {
+		return
+	}
+
+	if len(fields) == 0 {
+		fields = employeeFields
+	}
+
+	opts := database.ExportOptions{Filter: filter, Sort: parseSort(r.URL.Query().Get("sort"))}
+
+	err := opts.Validate()
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	// an export of the whole table may outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	out := &exportWriter{w: w, format: format, fields: fields, gzip: format.compressible && acceptsGzip(r)}
+
+	err = h.EmployeeDB.Export(r.Context(), opts, out.write)
+	if err == nil {
+		err = out.close()
+	}
+
+	if err == nil {
+		return
+	}
+
+	if !out.started {
+		dbError(w, r, err, "error exporting employees")
+		return
+	}
+
+	// the response is under way: cutting the connection keeps a truncated
+	// file from passing for a complete one
+	panic(http.ErrAbortHandler)
+}
+
+// negotiateExport picks the format of ?format=, else the one preferred by
+// Accept, CSV when it accepts anything.
+func negotiateExport(w http.ResponseWriter, r *http.Request) (exportFormat, bool) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, format := range exportFormats {
+			if format.name == name {
+				return format, true
+			}
+		}
+
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid format "+strconv.Quote(name)+", want csv, ndjson or xlsx")
+		return exportFormat{}, false
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return exportFormats[0], true
+	}
+
+	best, bestQ := -1, 0.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		for i, format := range exportFormats {
+			major, _, _ := strings.Cut(format.contentType, "/")
+
+			matches := mediaType == format.contentType || mediaType == "*/*" || mediaType == major+"/*"
+			if matches && q > bestQ {
+				best, bestQ = i, q
+			}
+		}
+	}
+
+	if best < 0 {
+		w.Header().Set("Vary", "Accept")
+		problemError(w, r, http.StatusNotAcceptable, CodeNotAcceptable, "exports are available as text/csv, application/x-ndjson or "+exportFormats[2].contentType)
+		return exportFormat{}, false
+	}
+
+	return exportFormats[best], true
+}
+
+// acceptsGzip reports whether Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, item := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+
+		value, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+
+		q, err := strconv.ParseFloat(value, 64)
+
+		return err == nil && q > 0
+	}
+
+	return false
+}
+
+// exportWriter starts the response on the first row, so errors before any
+// row still answer with a problem.
+type exportWriter struct {
+	w      http.ResponseWriter
+	format exportFormat
+	fields []string
+	gzip   bool
+
+	started bool
+	rows    rowWriter
+	buf     *bufio.Writer
+	gz      *gzip.Writer
+}
+
+func (e *exportWriter) start() error {
+	e.started = true
+
+	header := e.w.Header()
+	header.Set("Content-Type", e.format.contentType)
+	header.Set("Content-Disposition", `attachment; filename="employees.`+e.format.name+`"`)
+	header.Set("Vary", "Accept, Accept-Encoding")
+
+	var out io.Writer = e.w
+	if e.gzip {
+		header.Set("Content-Encoding", "gzip")
+		e.gz = gzip.NewWriter(out)
+		out = e.gz
+	}
+
+	e.w.WriteHeader(http.StatusOK)
+
+	e.buf = bufio.NewWriterSize(out, 32<<10)
+
+	var err error
+	e.rows, err = e.format.newWriter(e.buf, e.fields)
+
+	return err
+}
+
+func (e *exportWriter) write(employee models.Employee) error {
+	if !e.started {
+		err := e.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.rows.Write(employee)
+}
+
+// close ends the file, an export matching nothing still has its header.
+func (e *exportWriter) close() error {
+	if !e.started {
+		err := e.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := e.rows.Close()
+	if err != nil {
+		return err
+	}
+
+	err = e.buf.Flush()
+	if err != nil {
+		return err
+	}
+
+	if e.gz != nil {
+		return e.gz.Close()
+	}
+
+	return nil
+}
+
+// rowWriter writes employees in an export format.
+type rowWriter interface {
+	Write(employee models.Employee) error
+	Close() error
+}
+
+// fieldValues are the employee's values of fields, in order.
+func fieldValues(employee models.Employee, fields []string) []interface{} {
+	values := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			values = append(values, employee.ID)
+		case "name":
+			values = append(values, employee.Name)
+		case "position":
+			values = append(values, employee.Position)
+		case "salary":
+			values = append(values, employee.Salary)
+		case "currency":
+			values = append(values, employee.Currency)
+		case "department_id":
+			values = append(values, reference(employee.DepartmentID))
+		case "manager_id":
+			values = append(values, reference(employee.ManagerID))
+		}
+	}
+
+	return values
+}
+
+type csvRows struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func newCSVRows(w io.Writer, fields []string) (rowWriter, error) {
+	cw := csv.NewWriter(w)
+
+	return csvRows{w: cw, fields: fields}, cw.Write(fields)
+}
+
+func (c csvRows) Write(employee models.Employee) error {
+	record := make([]string, 0, len(c.fields))
+	for _, value := range fieldValues(employee, c.fields) {
+		switch v := value.(type) {
+		case int64:
+			record = append(record, strconv.FormatInt(v, 10))
+		case money.Amount:
+			record = append(record, v.String())
+		case nil:
+			record = append(record, "")
+		default:
+			record = append(record, v.(string))
+		}
+	}
+
+	return c.w.Write(record)
+}
+
+func (c csvRows) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonRows struct {
+	enc    *json.Encoder
+	fields []string
+}
+
+func newNDJSONRows(w io.Writer, fields []string) (rowWriter, error) {
+	return ndjsonRows{enc: json.NewEncoder(w), fields: fields}, nil
+}
+
+func (n ndjsonRows) Write(employee models.Employee) error {
+	return n.enc.Encode(selectFields([]models.Employee{employee}, n.fields).([]map[string]interface{})[0])
+}
+
+func (ndjsonRows) Close() error {
+	return nil
+}
+
+// xlsxRows writes a workbook with excelize's stream writer, which keeps the
+// sheet in a temporary file rather than in memory until it is written out
+// on Close.
+type xlsxRows struct {
+	w      io.Writer
+	file   *excelize.File
+	sheet  *excelize.StreamWriter
+	fields []string
+	row    int
+}
+
+func newXLSXRows(w io.Writer, fields []string) (rowWriter, error) {
+	file := excelize.NewFile()
+
+	sheet, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	x := &xlsxRows{w: w, file: file, sheet: sheet, fields: fields}
+
+	header := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		header = append(header, field)
+	}
+
+	return x, x.setRow(header)
+}
+
+func (x *xlsxRows) setRow(values []interface{}) error {
+	x.row++
+
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+
+	return x.sheet.SetRow(cell, values)
+}
+
+// Write sets salaries as numbers, which spreadsheets hold as floats anyway.
+func (x *xlsxRows) Write(employee models.Employee) error {
+	values := fieldValues(employee, x.fields)
+	for i, value := range values {
+		if amount, ok := value.(money.Amount); ok {
+			values[i] = amount.Float64()
+		}
+	}
+
+	return x.setRow(values)
+}
+
+func (x *xlsxRows) Close() error {
+	defer x.file.Close()
+
+	err := x.sheet.Flush()
+	if err != nil {
+		return err
+	}
+
+	return x.file.Write(x.w)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExport(t *testing.T) {
+	tt := []struct {
+		name        string
+		target      string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "csv by default",
+			target:      "/employee/export",
+			contentType: "text/csv",
+			body:        "id,name,position,salary,currency,department_id,manager_id\n1,John,SDE,1000,USD,,\n2,Jane,PM,2000,USD,,\n",
+		},
+		{
+			name:        "ndjson by accept",
+			target:      "/employee/export?currency=USD&sort=-salary&fields=id,name",
+			accept:      "text/html, application/x-ndjson;q=0.9, */*;q=0.1",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":2,\"name\":\"Jane\"}\n{\"id\":1,\"name\":\"John\"}\n",
+		},
+		{
+			name:        "format overrides accept",
+			target:      "/employee/export?format=csv&position=PM",
+			accept:      "application/x-ndjson",
+			contentType: "text/csv",
+			body:        "id,name,position,salary,currency,department_id,manager_id\n2,Jane,PM,2000,USD,,\n",
+		},
+		{
+			name:        "header only when nothing matches",
+			target:      "/employee/export?fields=name&position=QA",
+			contentType: "text/csv",
+			body:        "name\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			r.Header.Set("Accept", tc.accept)
+
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: seeded(t)}.Export(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+			assert.Equal(t, tc.body, w.Body.String())
+		})
+	}
+}
+
+func TestExportGzip(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/employee/export?format=ndjson", nil)
+	r.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+
+	w := httptest.NewRecorder()
+	Handler{EmployeeDB: seeded(t)}.Export(w, r)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Get("Vary"), "Accept-Encoding")
+
+	zr, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+
+	lines := 0
+	for s := bufio.NewScanner(zr); s.Scan(); {
+		lines++
+	}
+
+	assert.Equal(t, 2, lines)
+}
+
+func TestExportXLSX(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/employee/export?fields=name,salary", nil)
+	r.Header.Set("Accept", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	w := httptest.NewRecorder()
+	Handler{EmployeeDB: seeded(t)}.Export(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+	file, err := excelize.OpenReader(w.Body)
+	assert.NoError(t, err)
+
+	rows, err := file.GetRows(file.GetSheetName(0))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"name", "salary"}, {"John", "1000"}, {"Jane", "2000"}}, rows)
+}
+
+func TestExportInvalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		target string
+		accept string
+		status int
+		code   string
+	}{
+		{name: "format", target: "/employee/export?format=pdf", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "accept", target: "/employee/export", accept: "application/pdf", status: http.StatusNotAcceptable, code: CodeNotAcceptable},
+		{name: "sort", target: "/employee/export?sort=version", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "salary sort without a currency", target: "/employee/export?sort=salary", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "field", target: "/employee/export?fields=age", status: http.StatusBadRequest, code: CodeInvalidQuery},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			r.Header.Set("Accept", tc.accept)
+
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: seeded(t)}.Export(w, r)
+
+			assertProblem(t, w, tc.status, tc.code)
+		})
+	}
+}
+
+func TestExportDatabaseError(t *testing.T) {
+	errRead := errors.New("connection reset")
+
+	t.Run("before the first row", func(t *testing.T) {
+		mock := &database.MockDatabase{
+			ExportF: func(context.Context, database.ExportOptions, func(models.Employee) error) error {
+				return errRead
+			},
+		}
+
+		w := httptest.NewRecorder()
+		Handler{EmployeeDB: mock}.Export(w, httptest.NewRequest(http.MethodGet, "/employee/export", nil))
+
+		assertProblem(t, w, http.StatusInternalServerError, CodeInternal)
+	})
+
+	t.Run("after the first row", func(t *testing.T) {
+		mock := &database.MockDatabase{
+			ExportF: func(_ context.Context, _ database.ExportOptions, fn func(models.Employee) error) error {
+				err := fn(models.Employee{ID: 1, Name: "John"})
+				if err != nil {
+					return err
+				}
+
+				return errRead
+			},
+		}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/employee/export", nil)
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			Handler{EmployeeDB: mock}.Export(w, r)
+		})
+
+		body, _ := io.ReadAll(w.Body)
+		assert.False(t, strings.Contains(string(body), "problem"))
+	})
+}
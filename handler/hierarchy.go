@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+)
+
+// EmployeesResponse lists employees in the order of the request.
+type EmployeesResponse struct {
+	Items []models.Employee `json:"items"`
+}
+
+// OrgNode is an employee of the org chart with everyone reporting to it.
+type OrgNode struct {
+	ID       int64      `json:"id"`
+	Name     string     `json:"name"`
+	Position string     `json:"position"`
+	Reports  []*OrgNode `json:"reports,omitempty"`
+}
+
+// OrgChartResponse lists the employees at the top of the chart by id.
+type OrgChartResponse struct {
+	Items []*OrgNode `json:"items"`
+}
+
+// orgChartFormats are the representations of the org chart, the first is the
+// default.
+var orgChartFormats = []struct {
+	name        string
+	contentType string
+}{
+	{name: "json", contentType: "application/json"},
+	{name: "dot", contentType: "text/vnd.graphviz"},
+}
+
+// Reports lists the employees reporting directly to the employee.
+func (h Handler) Reports(w http.ResponseWriter, r *http.Request) {
+	h.hierarchy(w, r, h.HierarchyDB.Reports, "error reading reports")
+}
+
+// Chain lists the managers of the employee, its direct one first, up to the
+// top of the org chart.
+func (h Handler) Chain(w http.ResponseWriter, r *http.Request) {
+	h.hierarchy(w, r, h.HierarchyDB.Chain, "error reading reporting chain")
+}
+
+// Subtree lists everyone under the employee, breadth first.
+func (h Handler) Subtree(w http.ResponseWriter, r *http.Request) {
+	h.hierarchy(w, r, h.HierarchyDB.Subtree, "error reading reporting subtree")
+}
+
+// hierarchy answers the employees read by read for the employee of the path.
+func (h Handler) hierarchy(w http.ResponseWriter, r *http.Request, read func(ctx context.Context, id int64) ([]models.Employee, error), detail string) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	employees, err := read(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, detail)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, EmployeesResponse{Items: employees})
+}
+
+// OrgChart renders who reports to whom as a JSON tree or a Graphviz DOT
+// digraph, picked by ?format= or else Accept. The chart covers every
+// employee, or only those under ?root= and the root itself.
+func (h Handler) OrgChart(w http.ResponseWriter, r *http.Request) {
+	format, ok := negotiateOrgChart(w, r)
+	if !ok {
+		return
+	}
+
+	employees, ok := h.orgChartEmployees(w, r)
+	if !ok {
+		return
+	}
+
+	roots := orgChart(employees)
+
+	if format == "json" {
+		writeJSON(w, r, http.StatusOK, OrgChartResponse{Items: roots})
+		return
+	}
+
+	w.Header().Set("Content-Type", orgChartFormats[1].contentType)
+	w.WriteHeader(http.StatusOK)
+
+	out := bufio.NewWriter(w)
+	writeDOT(out, roots)
+	out.Flush()
+}
+
+// negotiateOrgChart picks the format of ?format=, else DOT when Accept asks
+// for it, JSON otherwise.
+func negotiateOrgChart(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		if strings.Contains(r.Header.Get("Accept"), orgChartFormats[1].contentType) {
+			return orgChartFormats[1].name, true
+		}
+
+		return orgChartFormats[0].name, true
+	}
+
+	for _, format := range orgChartFormats {
+		if format.name == name {
+			return name, true
+		}
+	}
+
+	problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid format "+strconv.Quote(name)+", want json or dot")
+	return "", false
+}
+
+// orgChartEmployees reads the employees of the chart, the root first when
+// there is one.
+func (h Handler) orgChartEmployees(w http.ResponseWriter, r *http.Request) ([]models.Employee, bool) {
+	value := r.URL.Query().Get("root")
+	if value == "" {
+		var employees []models.Employee
+		err := h.EmployeeDB.Export(r.Context(), database.ExportOptions{}, func(e models.Employee) error {
+			employees = append(employees, e)
+			return nil
+		})
+		if err != nil {
+			dbError(w, r, err, "error reading employees")
+			return nil, false
+		}
+
+		return employees, true
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid root "+strconv.Quote(value)+", want an employee id")
+		return nil, false
+	}
+
+	root, err := h.EmployeeDB.Get(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error fetching employee")
+		return nil, false
+	}
+
+	subtree, err := h.HierarchyDB.Subtree(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error reading reporting subtree")
+		return nil, false
+	}
+
+	return append([]models.Employee{root}, subtree...), true
+}
+
+// orgChart links the employees into trees, each employee under its manager
+// when the manager is among them and at the top otherwise. Reports keep the
+// order of employees.
+func orgChart(employees []models.Employee) []*OrgNode {
+	nodes := make(map[int64]*OrgNode, len(employees))
+	for _, e := range employees {
+		nodes[e.ID] = &OrgNode{ID: e.ID, Name: e.Name, Position: e.Position}
+	}
+
+	roots := []*OrgNode{}
+	for _, e := range employees {
+		manager, ok := nodes[e.ManagerID]
+		if !ok {
+			roots = append(roots, nodes[e.ID])
+			continue
+		}
+
+		manager.Reports = append(manager.Reports, nodes[e.ID])
+	}
+
+	return roots
+}
+
+// writeDOT writes the trees as a Graphviz digraph, every employee a box
+// labelled with its name and position, edges going from managers to their
+// reports.
+func writeDOT(w *bufio.Writer, roots []*OrgNode) {
+	w.WriteString("digraph orgchart {\n\tnode [shape=box];\n")
+
+	var edges []string
+	queue := append([]*OrgNode(nil), roots...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		fmt.Fprintf(w, "\t%d [label=\"%s\\n%s\"];\n", node.ID, dotEscape(node.Name), dotEscape(node.Position))
+
+		for _, report := range node.Reports {
+			edges = append(edges, fmt.Sprintf("\t%d -> %d;\n", node.ID, report.ID))
+		}
+
+		queue = append(queue, node.Reports...)
+	}
+
+	for _, edge := range edges {
+		w.WriteString(edge)
+	}
+
+	w.WriteString("}\n")
+}
+
+// dotEscape escapes s for a quoted DOT string.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
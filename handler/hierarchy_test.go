@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// organization creates a CEO, a CTO and a VP of sales reporting to the CEO,
+// and an engineer reporting to the CTO.
+func organization(t *testing.T) *database.Memory {
+	store := database.NewMemory()
+	for _, e := range []models.Employee{
+		{Name: "Ann", Position: "CEO", Salary: money.Major(5000), Currency: "USD"},
+		{Name: "Bob", Position: "CTO", Salary: money.Major(4000), Currency: "USD", ManagerID: 1},
+		{Name: "Cid", Position: "VP \"Sales\"", Salary: money.Major(4000), Currency: "USD", ManagerID: 1},
+		{Name: "Dee", Position: "SDE", Salary: money.Major(2000), Currency: "USD", ManagerID: 2},
+	} {
+		_, err := store.Create(context.Background(), e)
+		assert.NoError(t, err)
+	}
+
+	return store
+}
+
+func TestHierarchy(t *testing.T) {
+	store := organization(t)
+	h := Handler{EmployeeDB: store, HierarchyDB: store}
+
+	serve := func(handle http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/employee/"+id, strings.NewReader(body))
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		r.Header.Set("Content-Type", mergePatchContentType)
+
+		w := httptest.NewRecorder()
+		handle(w, r)
+
+		return w
+	}
+
+	names := func(w *httptest.ResponseRecorder) string {
+		var response EmployeesResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+		var names []string
+		for _, e := range response.Items {
+			names = append(names, e.Name)
+		}
+
+		return strings.Join(names, ",")
+	}
+
+	w := serve(h.Reports, http.MethodGet, "1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Bob,Cid", names(w))
+
+	w = serve(h.Chain, http.MethodGet, "4", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Bob,Ann", names(w))
+
+	w = serve(h.Subtree, http.MethodGet, "1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Bob,Cid,Dee", names(w))
+
+	w = serve(h.Reports, http.MethodGet, "4", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items": []}`, w.Body.String())
+
+	assertProblem(t, serve(h.Chain, http.MethodGet, "9", ""), http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(h.Subtree, http.MethodGet, "x", ""), http.StatusBadRequest, CodeInvalidID)
+
+	// reporting to oneself or to anyone below is a cycle
+	w = serve(h.Patch, http.MethodPatch, "1", `{"manager_id": 4}`)
+	assertProblem(t, w, http.StatusUnprocessableEntity, CodeConstraint)
+
+	w = serve(h.Patch, http.MethodPatch, "1", `{"manager_id": 1}`)
+	assertProblem(t, w, http.StatusUnprocessableEntity, CodeConstraint)
+
+	w = serve(h.Create, http.MethodPost, "", `{"name": "Eve", "position": "SDE", "salary": 1500, "currency": "USD", "manager_id": 9}`)
+	assertProblem(t, w, http.StatusUnprocessableEntity, CodeConstraint)
+
+	assertProblem(t, serve(h.Delete, http.MethodDelete, "2", ""), http.StatusConflict, CodeConflict)
+
+	// moving the engineer under the CEO frees the CTO
+	w = serve(h.Patch, http.MethodPatch, "4", `{"manager_id": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(h.Delete, http.MethodDelete, "2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(h.Reports, http.MethodGet, "1", "")
+	assert.Equal(t, "Cid,Dee", names(w))
+
+	w = serve(h.Patch, http.MethodPatch, "4", `{"manager_id": null}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(h.Chain, http.MethodGet, "4", "")
+	assert.JSONEq(t, `{"items": []}`, w.Body.String())
+}
+
+func TestOrgChart(t *testing.T) {
+	store := organization(t)
+	h := Handler{EmployeeDB: store, HierarchyDB: store}
+
+	testCases := []struct {
+		name                string
+		query               string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+		expectedCode        string
+	}{
+		{
+			name:                "Whole organization",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody: `{"items": [{"id": 1, "name": "Ann", "position": "CEO", "reports": [
+				{"id": 2, "name": "Bob", "position": "CTO", "reports": [{"id": 4, "name": "Dee", "position": "SDE"}]},
+				{"id": 3, "name": "Cid", "position": "VP \"Sales\""}
+			]}]}`,
+		},
+		{
+			name:                "Under a root",
+			query:               "?root=2",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `{"items": [{"id": 2, "name": "Bob", "position": "CTO", "reports": [{"id": 4, "name": "Dee", "position": "SDE"}]}]}`,
+		},
+		{
+			name:                "DOT by format",
+			query:               "?format=dot",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/vnd.graphviz",
+			expectedBody: "digraph orgchart {\n\tnode [shape=box];\n" +
+				"\t1 [label=\"Ann\\nCEO\"];\n" +
+				"\t2 [label=\"Bob\\nCTO\"];\n" +
+				"\t3 [label=\"Cid\\nVP \\\"Sales\\\"\"];\n" +
+				"\t4 [label=\"Dee\\nSDE\"];\n" +
+				"\t1 -> 2;\n\t1 -> 3;\n\t2 -> 4;\n}\n",
+		},
+		{
+			name:                "DOT by Accept",
+			query:               "?root=3",
+			accept:              "text/vnd.graphviz",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/vnd.graphviz",
+			expectedBody:        "digraph orgchart {\n\tnode [shape=box];\n\t3 [label=\"Cid\\nVP \\\"Sales\\\"\"];\n}\n",
+		},
+		{
+			name:           "Unknown format",
+			query:          "?format=svg",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Invalid root",
+			query:          "?root=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Unknown root",
+			query:          "?root=9",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/employee/orgchart"+tc.query, nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			h.OrgChart(w, r)
+
+			if tc.expectedCode != "" {
+				assertProblem(t, w, tc.expectedStatus, tc.expectedCode)
+				return
+			}
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), tc.expectedContentType)
+
+			if tc.expectedContentType == "application/json" {
+				assert.JSONEq(t, tc.expectedBody, w.Body.String())
+				return
+			}
+
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
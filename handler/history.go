@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
+	"github.com/gorilla/mux"
+)
+
+// EmployeeVersion is a version of an employee in history responses, valid
+// from ValidFrom until ValidTo, null for the current one.
+type EmployeeVersion struct {
+	models.Employee
+	Version   int64      `json:"version"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+}
+
+// HistoryResponse lists the versions of an employee, oldest first.
+type HistoryResponse struct {
+	Items []EmployeeVersion `json:"items"`
+}
+
+// getAsOf responds with the employee as it was at the as_of time. Past
+// versions carry no ETag, they can't be written to.
+func (h Handler) getAsOf(w http.ResponseWriter, r *http.Request, id int64, value string) {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid as_of value "+strconv.Quote(value)+", want an RFC 3339 time")
+		return
+	}
+
+	// scheduled changes only enter the history once applied
+	if at.After(time.Now()) {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "as_of can't be in the future, see the scheduled changes instead")
+		return
+	}
+
+	if !h.visible(w, r, id) {
+		return
+	}
+
+	employee, err := h.HistoryDB.GetAsOf(r.Context(), id, at)
+	if err != nil {
+		dbError(w, r, err, "error fetching employee details")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, employee)
+}
+
+// History lists every version of the employee with the time it was valid.
+func (h Handler) History(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.visible(w, r, id) {
+		return
+	}
+
+	versions, err := h.HistoryDB.History(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error reading employee history")
+		return
+	}
+
+	resp := HistoryResponse{Items: make([]EmployeeVersion, 0, len(versions))}
+	for _, v := range versions {
+		resp.Items = append(resp.Items, EmployeeVersion{Employee: v.Employee, Version: v.Version, ValidFrom: v.ValidFrom, ValidTo: v.ValidTo})
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// visible responds with a problem and returns false unless the caller may
+// see the past of the employee: admins always, others while it exists.
+func (h Handler) visible(w http.ResponseWriter, r *http.Request, id int64) bool {
+	if isAdmin(r) {
+		return true
+	}
+
+	_, err := h.EmployeeDB.Get(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error fetching employee details")
+		return false
+	}
+
+	return true
+}
+
+// ScheduleRequest is a change of an employee taking effect at EffectiveAt,
+// with the fields of a merge patch; at least one is needed.
+type ScheduleRequest struct {
+	EffectiveAt time.Time     `json:"effective_at"`
+	Name        *string       `json:"name"`
+	Position    *string       `json:"position"`
+	Salary      *money.Amount `json:"salary"`
+	Currency    *string       `json:"currency"`
+	// DepartmentID moves the employee, zero takes it out of its department.
+	DepartmentID *int64 `json:"department_id"`
+	// ManagerID makes the employee report to another, zero to nobody.
+	ManagerID *int64 `json:"manager_id"`
+}
+
+// ScheduledChange is a waiting change in responses, with the caller who
+// scheduled it. Fields it leaves untouched are omitted.
+type ScheduledChange struct {
+	ID          int64         `json:"id"`
+	EmployeeID  int64         `json:"employee_id"`
+	EffectiveAt time.Time     `json:"effective_at"`
+	Name        *string       `json:"name,omitempty"`
+	Position    *string       `json:"position,omitempty"`
+	Salary      *money.Amount `json:"salary,omitempty"`
+	Currency    *string       `json:"currency,omitempty"`
+	// DepartmentID is the department the employee moves to, zero for none.
+	DepartmentID *int64 `json:"department_id,omitempty"`
+	// ManagerID is the employee's manager to be, zero for nobody.
+	ManagerID *int64 `json:"manager_id,omitempty"`
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func scheduledChange(c models.ScheduledChange) ScheduledChange {
+	return ScheduledChange{
+		ID:           c.ID,
+		EmployeeID:   c.EmployeeID,
+		EffectiveAt:  c.EffectiveAt,
+		Name:         c.Changes.Name,
+		Position:     c.Changes.Position,
+		Salary:       c.Changes.Salary,
+		Currency:     c.Changes.Currency,
+		DepartmentID: c.Changes.DepartmentID,
+		ManagerID:    c.Changes.ManagerID,
+		Actor:        c.Actor,
+		RequestID:    c.RequestID,
+	}
+}
+
+// ScheduledResponse lists the waiting changes of an employee in the order
+// they will be made.
+type ScheduledResponse struct {
+	Items []ScheduledChange `json:"items"`
+}
+
+// Schedule stores a change of the employee to be made at a future time,
+// answering 201 Created with the change.
+func (h Handler) Schedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error reading body")
+		return
+	}
+
+	var req ScheduleRequest
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "error unmarshalling body, effective_at must be an RFC 3339 time")
+		return
+	}
+
+	if !req.EffectiveAt.After(time.Now()) {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "effective_at must be in the future, update the employee instead")
+		return
+	}
+
+	changes := validation.NormalizeChanges(models.EmployeeChanges{Name: req.Name, Position: req.Position, Salary: req.Salary, Currency: req.Currency, DepartmentID: req.DepartmentID, ManagerID: req.ManagerID})
+	if changes.Empty() {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, "the change must set at least one of name, position, salary, currency, department_id and manager_id")
+		return
+	}
+
+	err = h.validator().ValidateChanges(changes)
+	if err != nil {
+		validationError(w, r, err)
+		return
+	}
+
+	change, err := h.HistoryDB.Schedule(r.Context(), models.ScheduledChange{EmployeeID: id, EffectiveAt: req.EffectiveAt, Changes: changes})
+	if err != nil {
+		dbError(w, r, err, "error scheduling change")
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, scheduledChange(change))
+}
+
+// Scheduled lists the changes waiting for the employee.
+func (h Handler) Scheduled(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.HistoryDB.Scheduled(r.Context(), id)
+	if err != nil {
+		dbError(w, r, err, "error reading scheduled changes")
+		return
+	}
+
+	resp := ScheduledResponse{Items: make([]ScheduledChange, 0, len(changes))}
+	for _, change := range changes {
+		resp.Items = append(resp.Items, scheduledChange(change))
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// CancelScheduled drops a waiting change of the employee.
+func (h Handler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	changeID, ok := parseChangeID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.HistoryDB.CancelScheduled(r.Context(), id, changeID)
+	if err != nil {
+		dbError(w, r, err, "error cancelling scheduled change")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseChangeID reads the {change} path variable like parseID reads {id}.
+func parseChangeID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	value := mux.Vars(r)["change"]
+
+	changeID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || changeID <= 0 {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidID, "invalid change id "+strconv.Quote(value))
+		return 0, false
+	}
+
+	return changeID, true
+}
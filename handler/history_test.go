@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAsOf(t *testing.T) {
+	store := seeded(t)
+
+	salary := money.Major(1500)
+	_, err := store.Update(context.Background(), 1, models.EmployeeChanges{Salary: &salary})
+	assert.NoError(t, err)
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	tt := []struct {
+		name   string
+		asOf   string
+		status int
+		code   string
+		salary money.Amount
+	}{
+		{name: "current", asOf: now, status: http.StatusOK, salary: money.Major(1500)},
+		{name: "before creation", asOf: "2000-01-01T00:00:00Z", status: http.StatusNotFound, code: CodeNotFound},
+		{name: "future", asOf: time.Now().Add(time.Hour).UTC().Format(time.RFC3339), status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "invalid", asOf: "yesterday", status: http.StatusBadRequest, code: CodeInvalidQuery},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/employee/1?as_of="+tc.asOf, nil)
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: store, HistoryDB: store}.Get(w, r)
+
+			if tc.code != "" {
+				assertProblem(t, w, tc.status, tc.code)
+				return
+			}
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Empty(t, w.Header().Get("ETag"))
+
+			var employee models.Employee
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &employee))
+			assert.Equal(t, tc.salary, employee.Salary)
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	store := audited(t)
+
+	get := func(id, role string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/employee/"+id+"/history", nil)
+		r.Header.Set(RoleHeader, role)
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+
+		w := httptest.NewRecorder()
+		Handler{EmployeeDB: store, HistoryDB: store}.History(w, r)
+
+		return w
+	}
+
+	w := get("1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Items []struct {
+			Salary    float64    `json:"salary"`
+			Version   int64      `json:"version"`
+			ValidFrom time.Time  `json:"valid_from"`
+			ValidTo   *time.Time `json:"valid_to"`
+		} `json:"items"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 2)
+	assert.Equal(t, 1000.0, resp.Items[0].Salary)
+	assert.Equal(t, int64(1), resp.Items[0].Version)
+	assert.Equal(t, resp.Items[1].ValidFrom, *resp.Items[0].ValidTo)
+	assert.Equal(t, 1500.0, resp.Items[1].Salary)
+	assert.Nil(t, resp.Items[1].ValidTo)
+
+	// Jane is deleted, her past is for admins only
+	assertProblem(t, get("2", ""), http.StatusNotFound, CodeNotFound)
+	assert.Equal(t, http.StatusOK, get("2", RoleAdmin).Code)
+
+	assertProblem(t, get("99", RoleAdmin), http.StatusNotFound, CodeNotFound)
+}
+
+func TestSchedule(t *testing.T) {
+	store := seeded(t)
+	h := Handler{EmployeeDB: store, HistoryDB: store}
+	effectiveAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+
+	r := httptest.NewRequest(http.MethodPost, "/employee/1/scheduled",
+		strings.NewReader(`{"effective_at": "`+effectiveAt+`", "position": " SDE-2 ", "salary": 1200}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+
+	w := httptest.NewRecorder()
+	h.Schedule(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var change ScheduledChange
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &change))
+	assert.Equal(t, int64(1), change.EmployeeID)
+	assert.Equal(t, "SDE-2", *change.Position)
+	assert.Nil(t, change.Name)
+
+	r = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/employee/1/scheduled", nil), map[string]string{"id": "1"})
+	w = httptest.NewRecorder()
+	h.Scheduled(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items": [{"id": 1, "employee_id": 1, "effective_at": "`+effectiveAt+`", "position": "SDE-2", "salary": 1200, "actor": "system"}]}`, w.Body.String())
+
+	// nothing changes until the change is due
+	employee, err := store.Get(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "SDE", employee.Position)
+
+	cancel := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodDelete, "/employee/1/scheduled/1", nil)
+		r = mux.SetURLVars(r, map[string]string{"id": "1", "change": "1"})
+
+		w := httptest.NewRecorder()
+		h.CancelScheduled(w, r)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, cancel().Code)
+	assertProblem(t, cancel(), http.StatusNotFound, CodeNotFound)
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tt := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "past", id: "1", body: `{"effective_at": "2000-01-01T00:00:00Z", "salary": 1}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "no time", id: "1", body: `{"salary": 1}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "no change", id: "1", body: `{"effective_at": "` + future + `"}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "invalid field", id: "1", body: `{"effective_at": "` + future + `", "salary": -1}`, status: http.StatusBadRequest, code: CodeValidation},
+		{name: "missing employee", id: "99", body: `{"effective_at": "` + future + `", "salary": 1}`, status: http.StatusNotFound, code: CodeNotFound},
+		{name: "unknown department", id: "1", body: `{"effective_at": "` + future + `", "department_id": 99}`, status: http.StatusUnprocessableEntity, code: CodeConstraint},
+		{name: "unknown manager", id: "1", body: `{"effective_at": "` + future + `", "manager_id": 99}`, status: http.StatusUnprocessableEntity, code: CodeConstraint},
+		{name: "own manager", id: "1", body: `{"effective_at": "` + future + `", "manager_id": 1}`, status: http.StatusUnprocessableEntity, code: CodeConstraint},
+		{name: "invalid body", id: "1", body: `[]`, status: http.StatusBadRequest, code: CodeInvalidBody},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/employee/"+tc.id+"/scheduled", strings.NewReader(tc.body))
+			r = mux.SetURLVars(r, map[string]string{"id": tc.id})
+
+			w := httptest.NewRecorder()
+			store := seeded(t)
+			Handler{EmployeeDB: store, HistoryDB: store}.Schedule(w, r)
+
+			assertProblem(t, w, tc.status, tc.code)
+		})
+	}
+}
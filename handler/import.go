@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"example.com/m/Assesment/importer"
+)
+
+// MaxImportSize caps the size of an import upload.
+const MaxImportSize = 32 << 20
+
+// Import loads employees from a CSV or XLSX upload, either the request body
+// or the "file" part of a multipart form. The format comes from the format
+// query parameter, else the file name or Content-Type. Query parameters:
+// dry_run and upsert (booleans), key (comma separated natural key fields)
+// and map (repeated Header=field mappings). The answer is the import report,
+// or with report=csv the rejected rows as a CSV download.
+func (h Handler) Import(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	opts := importer.Options{Key: splitList(query.Get("key")), Validator: h.Validator}
+
+	for _, flag := range []struct {
+		name string
+		dst  *bool
+	}{
+		{"dry_run", &opts.DryRun},
+		{"upsert", &opts.Upsert},
+	} {
+		value := query.Get(flag.name)
+		if value == "" {
+			continue
+		}
+
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid "+flag.name+" value "+strconv.Quote(value))
+			return
+		}
+
+		*flag.dst = b
+	}
+
+	var err error
+	opts.Mapping, err = importer.ParseMapping(query["map"])
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	csvReport := false
+	switch value := query.Get("report"); value {
+	case "", "json":
+	case "csv":
+		csvReport = true
+	default:
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid report value "+strconv.Quote(value)+", want json or csv")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportSize)
+
+	upload, filename, err := importUpload(r)
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+		return
+	}
+
+	defer upload.Close()
+
+	opts.Format = importer.FormatOf(filename, r.Header.Get("Content-Type"))
+	if value := query.Get("format"); value != "" {
+		opts.Format, err = importer.ParseFormat(value)
+		if err != nil {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+			return
+		}
+	}
+
+	report, err := importer.Run(r.Context(), h.EmployeeDB, upload, opts)
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		problemError(w, r, http.StatusRequestEntityTooLarge, CodeInvalidBody, "the upload is larger than "+strconv.Itoa(MaxImportSize>>20)+" MiB")
+		return
+	case errors.Is(err, importer.ErrInvalidOptions):
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	case errors.Is(err, importer.ErrInvalidFile):
+		problemError(w, r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+		return
+	case err != nil:
+		dbError(w, r, err, "error importing employees")
+		return
+	}
+
+	if !csvReport {
+		writeJSON(w, r, http.StatusOK, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="import-errors.csv"`)
+	w.WriteHeader(http.StatusOK)
+	report.WriteCSV(w)
+}
+
+// importUpload returns the uploaded file with its name, empty for a raw
+// body.
+func importUpload(r *http.Request) (io.ReadCloser, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, "", nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the file part is streamed, not buffered like ParseMultipartForm does
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New("the form has no file part")
+		}
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+
+		part.Close()
+	}
+}
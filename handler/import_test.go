@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/importer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImport(t *testing.T) {
+	file := "name,position,salary\nJim,QA,10\nJohn,SDE,1500\n,PM,1\n"
+
+	tt := []struct {
+		name   string
+		target string
+		report importer.Report
+		ids    []int64
+	}{
+		{
+			name:   "create",
+			target: "/employee/import",
+			report: importer.Report{Rows: 3, Created: 1, Rejected: 2},
+			ids:    []int64{1, 2, 3},
+		},
+		{
+			name:   "upsert",
+			target: "/employee/import?upsert=true",
+			report: importer.Report{Rows: 3, Created: 1, Updated: 1, Rejected: 1},
+			ids:    []int64{1, 2, 3},
+		},
+		{
+			name:   "dry run",
+			target: "/employee/import?upsert=true&dry_run=1",
+			report: importer.Report{DryRun: true, Rows: 3, Created: 1, Updated: 1, Rejected: 1},
+			ids:    []int64{1, 2},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := seeded(t)
+
+			r := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(file))
+			r.Header.Set("Content-Type", "text/csv")
+
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: store}.Import(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var report importer.Report
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tc.report.Created, report.Created)
+			assert.Equal(t, tc.report.Updated, report.Updated)
+			assert.Equal(t, tc.report.Rejected, report.Rejected)
+			assert.Equal(t, tc.report.DryRun, report.DryRun)
+			assert.Len(t, report.Errors, tc.report.Rejected)
+
+			page, err := store.List(context.Background(), database.ListOptions{Limit: 10})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ids, employeeIDs(page.Employees))
+		})
+	}
+}
+
+func TestImportMultipartReport(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	assert.NoError(t, form.WriteField("comment", "ignored"))
+
+	part, err := form.CreateFormFile("file", "staff.csv")
+	assert.NoError(t, err)
+	part.Write([]byte("Full Name,Title,Pay\nJim,QA,ten\n"))
+	assert.NoError(t, form.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/employee/import?report=csv", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+
+	w := httptest.NewRecorder()
+	Handler{EmployeeDB: seeded(t)}.Import(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="import-errors.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "row,Full Name,Title,Pay,errors\n2,Jim,QA,ten,\"salary: \"\"ten\"\" is not a number with at most 2 decimals\"\n", w.Body.String())
+}
+
+func TestImportInvalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "bad flag", target: "/employee/import?dry_run=maybe", body: "name\n", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "bad mapping", target: "/employee/import?map=name", body: "name\n", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "bad key", target: "/employee/import?key=salary", body: "name\n", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "bad format", target: "/employee/import?format=ods", body: "name\n", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "bad report", target: "/employee/import?report=pdf", body: "name\n", status: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "no name column", target: "/employee/import", body: "title\nSDE\n", status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "not a workbook", target: "/employee/import?format=xlsx", body: "name\n", status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "too large", target: "/employee/import", body: "name\n" + strings.Repeat("x", MaxImportSize), status: http.StatusRequestEntityTooLarge, code: CodeInvalidBody},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler{EmployeeDB: seeded(t)}.Import(w, httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body)))
+
+			assertProblem(t, w, tc.status, tc.code)
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+)
+
+// page sizes of the employee list
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ListResponse is a keyset page of employees. The cursors are opaque, they
+// are passed back as the cursor query parameter to read the next or previous
+// page and are left out at either end of the list.
+type ListResponse struct {
+	// Items are the employees, or maps of the requested fields of each.
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Total      *int64      `json:"total,omitempty"`
+}
+
+// employeeFields are the fields a list can be reduced to.
+var employeeFields = []string{"id", "name", "position", "salary", "currency", "department_id", "manager_id"}
+
+func (h Handler) cursors() Cursors {
+	if h.Cursors == nil {
+		return defaultCursors
+	}
+
+	return *h.Cursors
+}
+
+// GetAll lists employees. By default it reads keyset pages selected by the
+// limit, cursor and count query parameters, narrowed by filters, ordered by
+// sort and reduced to fields. Requests with page or pagelimit keep the older
+// offset pages answered with a bare array.
+func (h Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Has("page") || query.Has("pagelimit") {
+		h.getAllByOffset(w, r)
+		return
+	}
+
+	h.list(w, r)
+}
+
+func (h Handler) getAllByOffset(w http.ResponseWriter, r *http.Request) {
+	page, ok := intParam(w, r, "page", 1, 1, math.MaxInt32)
+	if !ok {
+		return
+	}
+
+	pageLimit, ok := intParam(w, r, "pagelimit", DefaultPageSize, 1, MaxPageSize)
+	if !ok {
+		return
+	}
+
+	employees, err := h.EmployeeDB.GetAll(r.Context(), page, pageLimit)
+	if err != nil {
+		dbError(w, r, err, "error fetching all employee details")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, employees)
+}
+
+func (h Handler) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, ok := intParam(w, r, "limit", DefaultPageSize, 1, MaxPageSize)
+	if !ok {
+		return
+	}
+
+	filter, ok := parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	opts := database.ListOptions{Filter: filter, Sort: parseSort(query.Get("sort")), Limit: limit}
+	list := listDigest(opts.Filter, formatSort(opts.Sort))
+
+	fields, ok := parseFields(w, r)
+	if !ok {
+		return
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cur, err := h.cursors().decode(value)
+		if err != nil || cur.List != list {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidCursor, "invalid cursor, use one returned by a previous page with the same filters and sort")
+			return
+		}
+
+		opts.After, opts.Before = cur.After, cur.Before
+	}
+
+	if value := query.Get("count"); value != "" {
+		count, err := strconv.ParseBool(value)
+		if err != nil {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid count value "+strconv.Quote(value))
+			return
+		}
+
+		opts.Count = count
+	}
+
+	err := opts.Validate()
+	if err != nil {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	page, err := h.EmployeeDB.List(r.Context(), opts)
+	if err != nil {
+		dbError(w, r, err, "error fetching all employee details")
+		return
+	}
+
+	resp := ListResponse{Items: selectFields(page.Employees, fields), Total: page.Total}
+
+	if n := len(page.Employees); n > 0 {
+		backward := opts.Before != nil
+		first, last := database.KeysetOf(page.Employees[0]), database.KeysetOf(page.Employees[n-1])
+
+		// a page read backwards came from the employees after it, one read
+		// forwards from a cursor from those before it
+		if page.More && !backward || backward {
+			resp.NextCursor = h.cursors().encode(cursor{After: &last, List: list})
+		}
+
+		if page.More && backward || !backward && opts.After != nil {
+			resp.PrevCursor = h.cursors().encode(cursor{Before: &first, List: list})
+		}
+	}
+
+	w.Header().Set("Link", pageLinks(r, resp))
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// parseFilter reads the list filters: position, currency and department
+// (repeated or comma separated, "none" for employees in no department),
+// salary_min, salary_max, name_prefix and name_contains, and include_deleted
+// for admins.
+func parseFilter(w http.ResponseWriter, r *http.Request) (database.Filter, bool) {
+	query := r.URL.Query()
+
+	filter := database.Filter{
+		NamePrefix:   query.Get("name_prefix"),
+		NameContains: query.Get("name_contains"),
+	}
+
+	if value := query.Get("include_deleted"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "invalid include_deleted value "+strconv.Quote(value))
+			return filter, false
+		}
+
+		if include && !requireAdmin(w, r, "list deleted employees") {
+			return filter, false
+		}
+
+		filter.IncludeDeleted = include
+	}
+
+	for _, value := range query["position"] {
+		filter.Positions = append(filter.Positions, splitList(value)...)
+	}
+
+	for _, value := range query["currency"] {
+		for _, currency := range splitList(value) {
+			filter.Currencies = append(filter.Currencies, strings.ToUpper(currency))
+		}
+	}
+
+	for _, value := range query["department"] {
+		for _, item := range splitList(value) {
+			if item == noDepartment {
+				filter.Departments = append(filter.Departments, 0)
+				continue
+			}
+
+			id, err := strconv.ParseInt(item, 10, 64)
+			if err != nil || id <= 0 {
+				problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, fmt.Sprintf("invalid department value %q, want a department id or %s", item, noDepartment))
+				return filter, false
+			}
+
+			filter.Departments = append(filter.Departments, id)
+		}
+	}
+
+	salaryBounds := []queryBound[*money.Amount]{
+		{"salary_min", &filter.MinSalary},
+		{"salary_max", &filter.MaxSalary},
+	}
+
+	if !parseBounds(w, r, salaryBounds, parseSalary, "an amount") {
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// parseSort reads a sort such as "-salary,name", a leading "-" sorting
+// descending. Fields are checked against the whitelist by the database.
+func parseSort(value string) []database.SortKey {
+	var keys []database.SortKey
+	for _, field := range splitList(value) {
+		key := database.SortKey{Field: strings.TrimPrefix(field, "+")}
+		if strings.HasPrefix(field, "-") {
+			key = database.SortKey{Field: field[1:], Desc: true}
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func formatSort(keys []database.SortKey) string {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Desc {
+			fields = append(fields, "-"+key.Field)
+		} else {
+			fields = append(fields, key.Field)
+		}
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// parseFields reads the sparse fieldset, nil when every field is wanted.
+func parseFields(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	fields := splitList(r.URL.Query().Get("fields"))
+
+	for _, field := range fields {
+		known := false
+		for _, name := range employeeFields {
+			known = known || field == name
+		}
+
+		if !known {
+			detail := fmt.Sprintf("unknown field %q, want any of %s", field, strings.Join(employeeFields, ", "))
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, detail)
+			return nil, false
+		}
+	}
+
+	return fields, true
+}
+
+// selectFields reduces the employees to the fields asked for.
+func selectFields(employees []models.Employee, fields []string) interface{} {
+	if len(fields) == 0 {
+		if employees == nil {
+			return []models.Employee{}
+		}
+
+		return employees
+	}
+
+	items := make([]map[string]interface{}, 0, len(employees))
+	for _, e := range employees {
+		all := map[string]interface{}{"id": e.ID, "name": e.Name, "position": e.Position, "salary": e.Salary, "currency": e.Currency, "department_id": reference(e.DepartmentID), "manager_id": reference(e.ManagerID)}
+
+		item := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			item[field] = all[field]
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// noDepartment filters the employees in no department.
+const noDepartment = "none"
+
+// reference is the value of an optional id such as the employee's
+// department, nil for none.
+func reference(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
+
+// splitList splits a comma separated parameter, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// pageLinks renders the RFC 8288 Link header pointing at the first, previous
+// and next pages, keeping the request's other query parameters.
+func pageLinks(r *http.Request, resp ListResponse) string {
+	links := []string{pageLink(r, "first", "")}
+
+	if resp.PrevCursor != "" {
+		links = append(links, pageLink(r, "prev", resp.PrevCursor))
+	}
+
+	if resp.NextCursor != "" {
+		links = append(links, pageLink(r, "next", resp.NextCursor))
+	}
+
+	return strings.Join(links, ", ")
+}
+
+func pageLink(r *http.Request, rel, cur string) string {
+	query := r.URL.Query()
+	query.Del("cursor")
+
+	if cur != "" {
+		query.Set("cursor", cur)
+	}
+
+	target := r.URL.Path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	return fmt.Sprintf("<%s>; rel=%q", target, rel)
+}
+
+// intParam reads an optional integer query parameter, def when absent,
+// responding with a problem and returning false when it is outside
+// [min, max].
+func intParam(w http.ResponseWriter, r *http.Request, name string, def, min, max int) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, true
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		detail := fmt.Sprintf("invalid %s value %q, want an integer from %d to %d", name, value, min, max)
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, detail)
+		return 0, false
+	}
+
+	return n, true
+}
+
+// queryBound is an optional query parameter bounding a range, read into dst.
+type queryBound[T any] struct {
+	name string
+	dst  *T
+}
+
+// parseBounds reads the bounds present in the query with parse, leaving the
+// others alone, responding with a problem and returning false for the first
+// that doesn't parse into what want describes.
+func parseBounds[T any](w http.ResponseWriter, r *http.Request, bounds []queryBound[T], parse func(string) (T, error), want string) bool {
+	query := r.URL.Query()
+
+	for _, bound := range bounds {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := parse(value)
+		if err != nil {
+			detail := fmt.Sprintf("invalid %s value %q, want %s", bound.name, value, want)
+			problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, detail)
+			return false
+		}
+
+		*bound.dst = parsed
+	}
+
+	return true
+}
+
+// parseTimeBounds reads the since and until query parameters as RFC 3339
+// times, leaving the absent ones zero.
+func parseTimeBounds(w http.ResponseWriter, r *http.Request, since, until *time.Time) bool {
+	bounds := []queryBound[time.Time]{
+		{"since", since},
+		{"until", until},
+	}
+
+	return parseBounds(w, r, bounds, parseTime, "an RFC 3339 time")
+}
+
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseSalary(value string) (*money.Amount, error) {
+	salary, err := money.Parse(value)
+	return &salary, err
+}
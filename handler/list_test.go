@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursors(t *testing.T) {
+	cursors := NewCursors([]byte("secret"))
+	after := cursor{After: &database.Keyset{ID: 42, Salary: money.Major(1000)}, List: listDigest(database.Filter{}, "-salary")}
+
+	encoded := cursors.encode(after)
+	cur, err := cursors.decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, after, cur)
+
+	// cursors signed with another key or edited are refused
+	_, err = NewCursors([]byte("other")).decode(encoded)
+	assert.ErrorIs(t, err, errInvalidCursor)
+
+	_, signature, _ := strings.Cut(encoded, ".")
+	forged := cursorEncoding.EncodeToString([]byte(`{"a":{"ID":1}}`)) + "." + signature
+	_, err = cursors.decode(forged)
+	assert.ErrorIs(t, err, errInvalidCursor)
+
+	both := cursor{After: &database.Keyset{ID: 1}, Before: &database.Keyset{ID: 2}}
+	for _, value := range []string{"", "abc", "abc.def", cursors.encode(cursor{}), cursors.encode(both)} {
+		_, err = cursors.decode(value)
+		assert.ErrorIs(t, err, errInvalidCursor, value)
+	}
+}
+
+// listBody decodes a ListResponse whatever shape its items have.
+type listBody struct {
+	Items      []map[string]interface{} `json:"items"`
+	NextCursor string                   `json:"next_cursor"`
+	PrevCursor string                   `json:"prev_cursor"`
+	Total      *int64                   `json:"total"`
+}
+
+func TestList(t *testing.T) {
+	cursors := NewCursors([]byte("secret"))
+	total := int64(7)
+
+	employees := func(ids ...int64) []models.Employee {
+		var result []models.Employee
+		for _, id := range ids {
+			result = append(result, models.Employee{ID: id, Name: "John", Position: "SDE", Salary: money.Major(id * 1000), Currency: "USD"})
+		}
+
+		return result
+	}
+
+	keyset := func(id int64) *database.Keyset {
+		k := database.KeysetOf(employees(id)[0])
+		return &k
+	}
+
+	bySalary := []database.SortKey{{Field: "salary", Desc: true}, {Field: "name"}}
+	filter := database.Filter{
+		Positions:    []string{"SDE", "QA", "PM"},
+		Currencies:   []string{"USD"},
+		MinSalary:    ptr(money.Major(100)),
+		MaxSalary:    ptr(money.Amount(500050)),
+		NamePrefix:   "jo",
+		NameContains: "n",
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		page           database.Page
+		expectedOpts   database.ListOptions
+		expectedStatus int
+		expectedCode   string
+		expectedNext   *cursor
+		expectedPrev   *cursor
+		expectedFields []string
+	}{
+		{
+			name:           "First page with defaults",
+			page:           database.Page{Employees: employees(1, 2), More: true},
+			expectedOpts:   database.ListOptions{Limit: DefaultPageSize},
+			expectedStatus: http.StatusOK,
+			expectedNext:   &cursor{After: keyset(2)},
+		},
+		{
+			name:           "Forward page from a cursor with count",
+			query:          "?limit=2&count=true&cursor=" + url.QueryEscape(cursors.encode(cursor{After: keyset(2)})),
+			page:           database.Page{Employees: employees(3, 4), Total: &total},
+			expectedOpts:   database.ListOptions{After: keyset(2), Limit: 2, Count: true},
+			expectedStatus: http.StatusOK,
+			expectedPrev:   &cursor{Before: keyset(3)},
+		},
+		{
+			name:           "Backward page",
+			query:          "?limit=2&cursor=" + url.QueryEscape(cursors.encode(cursor{Before: keyset(5)})),
+			page:           database.Page{Employees: employees(3, 4), More: true},
+			expectedOpts:   database.ListOptions{Before: keyset(5), Limit: 2},
+			expectedStatus: http.StatusOK,
+			expectedNext:   &cursor{After: keyset(4)},
+			expectedPrev:   &cursor{Before: keyset(3)},
+		},
+		{
+			name:           "Filters, sort and fields",
+			query:          "?position=SDE,QA&position=PM&currency=usd&salary_min=100&salary_max=5000.5&name_prefix=jo&name_contains=n&sort=-salary,%2Bname&fields=id,salary",
+			page:           database.Page{Employees: employees(4, 3), More: true},
+			expectedOpts:   database.ListOptions{Filter: filter, Sort: bySalary, Limit: DefaultPageSize},
+			expectedStatus: http.StatusOK,
+			expectedNext:   &cursor{After: keyset(3), List: listDigest(filter, "-salary,name")},
+			expectedFields: []string{"id", "salary"},
+		},
+		{
+			name:  "Cursor from the same filters written otherwise",
+			query: "?position=pm,QA&position=sde&currency=USD&salary_min=100.0&salary_max=5000.5&name_prefix=JO&name_contains=N&sort=-salary,name&cursor=" + url.QueryEscape(cursors.encode(cursor{After: keyset(3), List: listDigest(filter, "-salary,name")})),
+			page:  database.Page{Employees: employees(2)},
+			expectedOpts: database.ListOptions{
+				Filter: database.Filter{
+					Positions:    []string{"pm", "QA", "sde"},
+					Currencies:   []string{"USD"},
+					MinSalary:    ptr(money.Major(100)),
+					MaxSalary:    ptr(money.Amount(500050)),
+					NamePrefix:   "JO",
+					NameContains: "N",
+				},
+				Sort:  bySalary,
+				After: keyset(3),
+				Limit: DefaultPageSize,
+			},
+			expectedStatus: http.StatusOK,
+			expectedPrev:   &cursor{Before: keyset(2), List: listDigest(filter, "-salary,name")},
+		},
+		{
+			name:           "Cursor from another sort",
+			query:          "?sort=name&cursor=" + url.QueryEscape(cursors.encode(cursor{After: keyset(2)})),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidCursor,
+		},
+		{
+			name:           "Cursor from another filter",
+			query:          "?position=QA&sort=-salary,name&cursor=" + url.QueryEscape(cursors.encode(cursor{After: keyset(3), List: listDigest(filter, "-salary,name")})),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidCursor,
+		},
+		{
+			name:           "Unfiltered cursor replayed with a filter",
+			query:          "?salary_min=2000&cursor=" + url.QueryEscape(cursors.encode(cursor{After: keyset(2)})),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidCursor,
+		},
+		{
+			name:           "Empty list",
+			expectedOpts:   database.ListOptions{Limit: DefaultPageSize},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Tampered cursor",
+			query:          "?cursor=" + url.QueryEscape(NewCursors([]byte("other")).encode(cursor{After: keyset(2)})),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidCursor,
+		},
+		{
+			name:           "Limit above the maximum",
+			query:          "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Invalid count",
+			query:          "?count=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Unknown sort field",
+			query:          "?sort=version",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Salary bound without a currency",
+			query:          "?salary_min=100",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Salary sort across currencies",
+			query:          "?currency=USD,EUR&sort=salary",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Unknown field",
+			query:          "?fields=id,password",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:           "Invalid salary bound",
+			query:          "?salary_min=NaN",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+		{
+			name:  "Department filter",
+			query: "?department=2,none&department=5",
+			expectedOpts: database.ListOptions{
+				Filter: database.Filter{Departments: []int64{2, 0, 5}},
+				Limit:  DefaultPageSize,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid department",
+			query:          "?department=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidQuery,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testDatabase := new(database.MockDatabase)
+			testDatabase.ListF = func(ctx context.Context, opts database.ListOptions) (database.Page, error) {
+				assert.Equal(t, tc.expectedOpts, opts)
+				return tc.page, nil
+			}
+
+			mockHandler := Handler{EmployeeDB: testDatabase, Cursors: &cursors}
+
+			req, err := http.NewRequest(http.MethodGet, "/employee/"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			mockHandler.GetAll(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+				return
+			}
+
+			var resp listBody
+			err = json.Unmarshal(rr.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.NotNil(t, resp.Items)
+			assert.Equal(t, len(tc.page.Employees), len(resp.Items))
+			assert.Equal(t, tc.page.Total, resp.Total)
+
+			if tc.expectedFields != nil {
+				for _, item := range resp.Items {
+					assert.Len(t, item, len(tc.expectedFields))
+					for _, field := range tc.expectedFields {
+						assert.Contains(t, item, field)
+					}
+				}
+			}
+
+			assertCursor(t, cursors, tc.expectedNext, resp.NextCursor)
+			assertCursor(t, cursors, tc.expectedPrev, resp.PrevCursor)
+
+			links := rr.Header().Get("Link")
+			assert.Contains(t, links, `rel="first"`)
+			if resp.NextCursor != "" {
+				assert.Contains(t, links, "cursor="+url.QueryEscape(resp.NextCursor))
+				assert.Contains(t, links, `rel="next"`)
+			}
+		})
+	}
+}
+
+func assertCursor(t *testing.T, cursors Cursors, expected *cursor, value string) {
+	t.Helper()
+
+	if expected == nil {
+		assert.Empty(t, value)
+		return
+	}
+
+	cur, err := cursors.decode(value)
+	assert.NoError(t, err)
+	assert.Equal(t, *expected, cur)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
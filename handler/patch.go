@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// field error codes specific to patches
+const (
+	fieldReadOnly    = "read_only"
+	fieldUnknown     = "unknown_field"
+	fieldInvalidType = "invalid_type"
+)
+
+// patchError is a patch document that can't be applied at all, as opposed to
+// one producing invalid field values.
+type patchError struct {
+	status int
+	code   string
+	detail string
+}
+
+func (e *patchError) Error() string {
+	return e.detail
+}
+
+func invalidPatch(format string, args ...interface{}) *patchError {
+	return &patchError{status: http.StatusBadRequest, code: CodeInvalidPatch, detail: fmt.Sprintf(format, args...)}
+}
+
+// employeeDocument is the JSON object view of an employee that patches are
+// applied to. Numbers are kept as json.Number so salaries stay exact.
+func employeeDocument(e models.Employee) map[string]interface{} {
+	doc := map[string]interface{}{
+		"id":       json.Number(strconv.FormatInt(e.ID, 10)),
+		"name":     e.Name,
+		"position": e.Position,
+		"salary":   json.Number(e.Salary.String()),
+		"currency": e.Currency,
+	}
+
+	// an employee in no department or reporting to nobody has no
+	// department_id or manager_id, like in its JSON
+	if e.DepartmentID != 0 {
+		doc["department_id"] = json.Number(strconv.FormatInt(e.DepartmentID, 10))
+	}
+
+	if e.ManagerID != 0 {
+		doc["manager_id"] = json.Number(strconv.FormatInt(e.ManagerID, 10))
+	}
+
+	return doc
+}
+
+// decodeValue unmarshals data keeping numbers as json.Number.
+func decodeValue(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	err := decoder.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after the JSON value")
+	}
+
+	return nil
+}
+
+// equalValues compares decoded JSON values, numbers by their value so 1500
+// equals 1500.00.
+func equalValues(a, b interface{}) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if !aok || !bok {
+		return reflect.DeepEqual(a, b)
+	}
+
+	ar, aok := new(big.Rat).SetString(an.String())
+	br, bok := new(big.Rat).SetString(bn.String())
+
+	return aok && bok && ar.Cmp(br) == 0
+}
+
+// applyMergePatch applies an RFC 7396 JSON Merge Patch to doc, a null member
+// removes the field.
+func applyMergePatch(doc map[string]interface{}, data []byte) error {
+	var patch map[string]interface{}
+	err := decodeValue(data, &patch)
+	if err != nil || patch == nil {
+		return invalidPatch("a merge patch must be a JSON object")
+	}
+
+	for member, value := range patch {
+		if value == nil {
+			delete(doc, member)
+			continue
+		}
+
+		doc[member] = value
+	}
+
+	return nil
+}
+
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies an RFC 6902 JSON Patch to doc. Employees are flat,
+// so only top level paths like "/salary" exist.
+func applyJSONPatch(doc map[string]interface{}, data []byte) error {
+	var operations []jsonPatchOperation
+	err := json.Unmarshal(data, &operations)
+	if err != nil {
+		return invalidPatch("a JSON patch must be an array of operations")
+	}
+
+	for i, operation := range operations {
+		member, err := pointerMember(operation.Path)
+		if err != nil {
+			return invalidPatch("operation %d: %v", i, err)
+		}
+
+		var value interface{}
+		if operation.Value != nil {
+			err = decodeValue(*operation.Value, &value)
+			if err != nil {
+				return invalidPatch("operation %d: invalid value", i)
+			}
+		}
+
+		switch operation.Op {
+		case "add":
+			if operation.Value == nil {
+				return invalidPatch("operation %d: add needs a value", i)
+			}
+
+			doc[member] = value
+		case "replace":
+			if operation.Value == nil {
+				return invalidPatch("operation %d: replace needs a value", i)
+			}
+
+			if _, ok := doc[member]; !ok {
+				return invalidPatch("operation %d: %s does not exist", i, operation.Path)
+			}
+
+			doc[member] = value
+		case "remove":
+			if _, ok := doc[member]; !ok {
+				return invalidPatch("operation %d: %s does not exist", i, operation.Path)
+			}
+
+			delete(doc, member)
+		case "test":
+			if !equalValues(doc[member], value) {
+				return &patchError{
+					status: http.StatusConflict,
+					code:   CodePatchTestFailed,
+					detail: fmt.Sprintf("operation %d: %s does not have the tested value", i, operation.Path),
+				}
+			}
+		case "copy", "move":
+			from, err := pointerMember(operation.From)
+			if err != nil {
+				return invalidPatch("operation %d: from: %v", i, err)
+			}
+
+			fromValue, ok := doc[from]
+			if !ok {
+				return invalidPatch("operation %d: %s does not exist", i, operation.From)
+			}
+
+			if operation.Op == "move" {
+				delete(doc, from)
+			}
+
+			doc[member] = fromValue
+		default:
+			return invalidPatch("operation %d: unknown op %q", i, operation.Op)
+		}
+	}
+
+	return nil
+}
+
+// pointerMember resolves a JSON pointer naming a top level member.
+func pointerMember(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("path %q must name a top level member like /name", pointer)
+	}
+
+	member := strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:])
+
+	return member, nil
+}
+
+// changesFromDocument turns a patched document into the change set against
+// current. Removing the position clears it, removing the department_id or
+// manager_id takes the employee out of its department or makes it report to
+// nobody, name, salary and currency can't be removed.
+func changesFromDocument(current models.Employee, doc map[string]interface{}) (models.EmployeeChanges, error) {
+	var changes models.EmployeeChanges
+	var errs validation.Errors
+
+	var unknown []string
+	for member := range doc {
+		switch member {
+		case "id", "name", "position", "salary", "currency", "department_id", "manager_id":
+		default:
+			unknown = append(unknown, member)
+		}
+	}
+
+	sort.Strings(unknown)
+	for _, member := range unknown {
+		errs = append(errs, FieldError{Field: member, Code: fieldUnknown, Message: "is not an employee field"})
+	}
+
+	if id, ok := doc["id"]; !ok || !equalValues(id, json.Number(strconv.FormatInt(current.ID, 10))) {
+		errs = append(errs, FieldError{Field: "id", Code: fieldReadOnly, Message: "can not be changed"})
+	}
+
+	name, ok := doc["name"]
+	switch value := name.(type) {
+	case string:
+		if value != current.Name {
+			changes.Name = &value
+		}
+	default:
+		if !ok {
+			errs = append(errs, FieldError{Field: "name", Code: validation.CodeRequired, Message: "can not be removed"})
+			break
+		}
+
+		errs = append(errs, FieldError{Field: "name", Code: fieldInvalidType, Message: "must be a string"})
+	}
+
+	position, ok := doc["position"]
+	switch value := position.(type) {
+	case string:
+		if value != current.Position {
+			changes.Position = &value
+		}
+	case nil:
+		if ok {
+			errs = append(errs, FieldError{Field: "position", Code: fieldInvalidType, Message: "must be a string"})
+			break
+		}
+
+		// removing the position clears it
+		if current.Position != "" {
+			cleared := ""
+			changes.Position = &cleared
+		}
+	default:
+		errs = append(errs, FieldError{Field: "position", Code: fieldInvalidType, Message: "must be a string"})
+	}
+
+	salary, ok := doc["salary"]
+	switch value := salary.(type) {
+	case json.Number:
+		amount, err := money.Parse(value.String())
+		if err != nil {
+			errs = append(errs, FieldError{Field: "salary", Code: fieldInvalidType, Message: fmt.Sprintf("must be a number with at most %d decimals", money.Scale)})
+			break
+		}
+
+		if amount != current.Salary {
+			changes.Salary = &amount
+		}
+	default:
+		if !ok {
+			errs = append(errs, FieldError{Field: "salary", Code: validation.CodeRequired, Message: "can not be removed"})
+			break
+		}
+
+		errs = append(errs, FieldError{Field: "salary", Code: fieldInvalidType, Message: "must be a number"})
+	}
+
+	currency, ok := doc["currency"]
+	switch value := currency.(type) {
+	case string:
+		if value != current.Currency {
+			changes.Currency = &value
+		}
+	default:
+		if !ok {
+			errs = append(errs, FieldError{Field: "currency", Code: validation.CodeRequired, Message: "can not be removed"})
+			break
+		}
+
+		errs = append(errs, FieldError{Field: "currency", Code: fieldInvalidType, Message: "must be a string"})
+	}
+
+	var err *FieldError
+	changes.DepartmentID, err = referenceChange(doc, "department_id", current.DepartmentID)
+	if err != nil {
+		errs = append(errs, *err)
+	}
+
+	changes.ManagerID, err = referenceChange(doc, "manager_id", current.ManagerID)
+	if err != nil {
+		errs = append(errs, *err)
+	}
+
+	if len(errs) > 0 {
+		return changes, errs
+	}
+
+	return changes, nil
+}
+
+// referenceChange is the change of the optional id in member from current,
+// nil when it is unchanged. Removing it or setting it to null changes it to
+// zero.
+func referenceChange(doc map[string]interface{}, member string, current int64) (*int64, *FieldError) {
+	switch value := doc[member].(type) {
+	case json.Number:
+		id, err := strconv.ParseInt(value.String(), 10, 64)
+		if err != nil {
+			return nil, &FieldError{Field: member, Code: fieldInvalidType, Message: "must be an integer"}
+		}
+
+		if id != current {
+			return &id, nil
+		}
+	case nil:
+		if current != 0 {
+			none := int64(0)
+			return &none, nil
+		}
+	default:
+		return nil, &FieldError{Field: member, Code: fieldInvalidType, Message: "must be an integer"}
+	}
+
+	return nil, nil
+}
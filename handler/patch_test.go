@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatch(t *testing.T) {
+	current := models.Employee{ID: 1, Name: "John", Position: "SDE", Salary: 30000}
+
+	name, position, salary := "Jane", "", money.Amount(0)
+
+	testCases := []struct {
+		name            string
+		contentType     string
+		body            string
+		getErr          error
+		updateErr       error
+		expectedChanges models.EmployeeChanges
+		expectedStatus  int
+		expectedCode    string
+		expectedFields  []string
+	}{
+		{
+			name:            "Merge patch sets zero salary and clears position",
+			contentType:     mergePatchContentType,
+			body:            `{"salary": 0, "position": null}`,
+			expectedChanges: models.EmployeeChanges{Position: &position, Salary: &salary},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "Merge patch with plain json content type",
+			contentType:     "application/json; charset=utf-8",
+			body:            `{"name": " Jane "}`,
+			expectedChanges: models.EmployeeChanges{Name: &name},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "Empty merge patch changes nothing",
+			contentType:     mergePatchContentType,
+			body:            `{}`,
+			expectedChanges: models.EmployeeChanges{},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:           "Merge patch can't remove the name",
+			contentType:    mergePatchContentType,
+			body:           `{"name": null, "salary": "a lot", "id": 2, "email": "x"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
+			expectedFields: []string{"email", "id", "name", "salary"},
+		},
+		{
+			name:           "Merge patch must be an object",
+			contentType:    mergePatchContentType,
+			body:           `[1]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidPatch,
+		},
+		{
+			name:           "Merge patch values are validated",
+			contentType:    mergePatchContentType,
+			body:           `{"salary": -1}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeValidation,
+			expectedFields: []string{"salary"},
+		},
+		{
+			name:            "Json patch",
+			contentType:     jsonPatchContentType,
+			body:            `[{"op": "test", "path": "/name", "value": "John"}, {"op": "replace", "path": "/name", "value": "Jane"}, {"op": "remove", "path": "/position"}]`,
+			expectedChanges: models.EmployeeChanges{Name: &name, Position: &position},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:           "Json patch failed test",
+			contentType:    jsonPatchContentType,
+			body:           `[{"op": "test", "path": "/salary", "value": 1}, {"op": "replace", "path": "/salary", "value": 2}]`,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodePatchTestFailed,
+		},
+		{
+			name:           "Json patch nested path",
+			contentType:    jsonPatchContentType,
+			body:           `[{"op": "add", "path": "/name/first", "value": "J"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidPatch,
+		},
+		{
+			name:           "Json patch unknown op",
+			contentType:    jsonPatchContentType,
+			body:           `[{"op": "merge", "path": "/name", "value": "J"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidPatch,
+		},
+		{
+			name:            "Json patch move",
+			contentType:     jsonPatchContentType,
+			body:            `[{"op": "copy", "from": "/position", "path": "/name"}, {"op": "remove", "path": "/position"}]`,
+			expectedChanges: models.EmployeeChanges{Name: &current.Position, Position: &position},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:           "Unsupported media type",
+			contentType:    "text/plain",
+			body:           `name=Jane`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   CodeUnsupportedMediaType,
+		},
+		{
+			name:           "Missing employee",
+			contentType:    mergePatchContentType,
+			body:           `{"name": "Jane"}`,
+			getErr:         database.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeNotFound,
+		},
+		{
+			name:            "Error from db",
+			contentType:     mergePatchContentType,
+			body:            `{"name": "Jane"}`,
+			updateErr:       database.ErrUnavailable,
+			expectedChanges: models.EmployeeChanges{Name: &name},
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedCode:    CodeUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stored := current
+
+			//mock for dependency
+			testDatabase := new(database.MockDatabase)
+			testDatabase.GetF = func(ctx context.Context, id int64) (models.Employee, error) {
+				return stored, tc.getErr
+			}
+
+			testDatabase.UpdateF = func(ctx context.Context, id int64, changes models.EmployeeChanges) (models.Employee, error) {
+				assert.Equal(t, tc.expectedChanges, changes)
+				stored = changes.Apply(stored)
+				return stored, tc.updateErr
+			}
+
+			mockHandler := Handler{EmployeeDB: testDatabase}
+
+			req, err := http.NewRequest(http.MethodPatch, "/employee/1", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", tc.contentType)
+			req = mux.SetURLVars(req, map[string]string{
+				"id": "1",
+			})
+
+			rr := httptest.NewRecorder()
+			mockHandler.Patch(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				problem := assertProblem(t, rr, tc.expectedStatus, tc.expectedCode)
+
+				var fields []string
+				for _, f := range problem.Errors {
+					fields = append(fields, f.Field)
+				}
+
+				assert.Equal(t, tc.expectedFields, fields)
+				return
+			}
+
+			assert.JSONEq(t, mustJSON(t, tc.expectedChanges.Apply(current)), rr.Body.String())
+		})
+	}
+}
+
+func TestPatchUnsupportedMediaTypeAdvertisesFormats(t *testing.T) {
+	mockHandler := Handler{EmployeeDB: new(database.MockDatabase)}
+
+	req, err := http.NewRequest(http.MethodPatch, "/employee/1", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{
+		"id": "1",
+	})
+
+	rr := httptest.NewRecorder()
+	mockHandler.Patch(rr, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	assert.Equal(t, mergePatchContentType+", "+jsonPatchContentType, rr.Header().Get("Accept-Patch"))
+}
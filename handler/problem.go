@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/validation"
+)
+
+// Problem is an RFC 7807 problem details body. Code is a stable machine
+// readable identifier, Errors lists per-field failures for validation errors.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError = validation.FieldError
+
+const problemContentType = "application/problem+json"
+
+// problemTypeBase prefixes Code to build the problem type URI.
+const problemTypeBase = "/problems/"
+
+// problem codes
+const (
+	CodeInvalidID    = "invalid_id"
+	CodeInvalidBody  = "invalid_body"
+	CodeInvalidQuery = "invalid_query"
+	CodeValidation   = "validation_failed"
+	CodeNotFound     = "not_found"
+	CodeDuplicate    = "duplicate"
+	CodeConflict     = "conflict"
+	CodeConstraint   = "constraint_violation"
+	CodeUnavailable  = "unavailable"
+	CodeInternal     = "internal_error"
+	CodeForbidden    = "forbidden"
+
+	CodeInvalidPatch         = "invalid_patch"
+	CodePatchTestFailed      = "patch_test_failed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeNotAcceptable        = "not_acceptable"
+
+	CodePreconditionFailed = "precondition_failed"
+	CodeInvalidCursor      = "invalid_cursor"
+
+	CodeBatchAborted = "batch_aborted"
+)
+
+func newProblem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   problemTypeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// writeProblem renders p as application/problem+json for the request.
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" && r.URL != nil {
+		p.Instance = r.URL.RequestURI()
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, p.Detail, p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
+
+func problemError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblem(w, r, newProblem(status, code, detail))
+}
+
+// validationError responds with the field errors carried by err.
+func validationError(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, validationProblem(err))
+}
+
+func validationProblem(err error) Problem {
+	p := newProblem(http.StatusBadRequest, CodeValidation, "the request body has invalid fields")
+
+	var fieldErrors validation.Errors
+	if errors.As(err, &fieldErrors) {
+		p.Errors = fieldErrors
+	}
+
+	return p
+}
+
+// dbError responds with the problem matching an error from the database
+// layer. detail is used for errors outside the database taxonomy.
+func dbError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	if errors.Is(err, database.ErrUnavailable) {
+		w.Header().Set("Retry-After", "1")
+	}
+
+	writeProblem(w, r, dbProblem(err, detail))
+}
+
+func dbProblem(err error, detail string) Problem {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return newProblem(http.StatusNotFound, CodeNotFound, "employee not found")
+	case errors.Is(err, database.ErrChangeNotFound):
+		return newProblem(http.StatusNotFound, CodeNotFound, "scheduled change not found")
+	case errors.Is(err, database.ErrDepartmentNotFound):
+		return newProblem(http.StatusNotFound, CodeNotFound, "department not found")
+	case errors.Is(err, database.ErrProposalNotFound):
+		return newProblem(http.StatusNotFound, CodeNotFound, "compensation change not found")
+	case errors.Is(err, database.ErrSelfApproval):
+		return newProblem(http.StatusForbidden, CodeForbidden, "a compensation change can't be approved by its proposer")
+	case errors.Is(err, database.ErrDepartmentExists):
+		return newProblem(http.StatusConflict, CodeDuplicate, "department already exists")
+	case errors.Is(err, database.ErrDuplicate):
+		return newProblem(http.StatusConflict, CodeDuplicate, "employee already exists")
+	case errors.Is(err, database.ErrNotDeleted):
+		return newProblem(http.StatusConflict, CodeConflict, "employee is not deleted")
+	case errors.Is(err, database.ErrDecided):
+		return newProblem(http.StatusConflict, CodeConflict, "compensation change is already decided")
+	case errors.Is(err, database.ErrStaleProposal):
+		return newProblem(http.StatusConflict, CodeConflict, "salary changed since the proposal, propose the change again")
+	case errors.Is(err, database.ErrCompensationScheduled):
+		return newProblem(http.StatusConflict, CodeConflict, "the change was scheduled by an approved compensation change and can't be cancelled")
+	case errors.Is(err, database.ErrDepartmentInUse):
+		return newProblem(http.StatusConflict, CodeConflict, "department has employees, deleted ones included until purged")
+	case errors.Is(err, database.ErrHasReports):
+		return newProblem(http.StatusConflict, CodeConflict, "employees report to the employee, reassign them first")
+	case errors.Is(err, database.ErrConflict):
+		return newProblem(http.StatusConflict, CodeConflict, "conflicting change, retry the request")
+	case errors.Is(err, database.ErrSalaryPrecision):
+		return newProblem(http.StatusUnprocessableEntity, CodeConstraint, "salary has more decimals than the employee's currency allows")
+	case errors.Is(err, database.ErrUnknownDepartment):
+		return newProblem(http.StatusUnprocessableEntity, CodeConstraint, "department_id names no department")
+	case errors.Is(err, database.ErrUnknownManager):
+		return newProblem(http.StatusUnprocessableEntity, CodeConstraint, "manager_id names no employee")
+	case errors.Is(err, database.ErrManagerCycle):
+		return newProblem(http.StatusUnprocessableEntity, CodeConstraint, "manager_id would make the employee report to itself")
+	case errors.Is(err, database.ErrConstraint):
+		return newProblem(http.StatusUnprocessableEntity, CodeConstraint, "employee violates a data constraint")
+	case errors.Is(err, database.ErrUnavailable):
+		return newProblem(http.StatusServiceUnavailable, CodeUnavailable, "database unavailable")
+	default:
+		return newProblem(http.StatusInternalServerError, CodeInternal, detail)
+	}
+}
+
+// writeJSON renders a successful response.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		problemError(w, r, http.StatusInternalServerError, CodeInternal, "error marshalling response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
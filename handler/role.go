@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RoleHeader carries the caller's role. The service trusts it as set by the
+// authenticating proxy in front of it, which must drop any sent by clients.
+const RoleHeader = "X-Role"
+
+// RoleAdmin may see and restore deleted employees.
+const RoleAdmin = "admin"
+
+func isAdmin(r *http.Request) bool {
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get(RoleHeader)), RoleAdmin)
+}
+
+// requireAdmin responds with a problem and returns false unless the caller
+// is an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request, action string) bool {
+	if isAdmin(r) {
+		return true
+	}
+
+	problemError(w, r, http.StatusForbidden, CodeForbidden, "only admins may "+action)
+	return false
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/m/Assesment/models"
+)
+
+// MaxQueryLength bounds the search query in characters.
+const MaxQueryLength = 200
+
+// SearchResult is an employee matching a search. Highlights holds the
+// matched fields with the matched fragments wrapped in <mark> tags, the
+// rest of the value HTML escaped.
+type SearchResult struct {
+	Employee   models.Employee   `json:"employee"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type SearchResponse struct {
+	Items []SearchResult `json:"items"`
+}
+
+// Search finds employees by the words of the q query parameter in their
+// name and position, best matches first. Words match ignoring case, as a
+// prefix, or with a typo or two in longer words. limit caps the results.
+func (h Handler) Search(w http.ResponseWriter, r *http.Request) {
+	if h.Searcher == nil {
+		problemError(w, r, http.StatusNotImplemented, CodeUnavailable, "search is not enabled")
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "missing search query q")
+		return
+	}
+
+	if utf8.RuneCountInString(q) > MaxQueryLength {
+		problemError(w, r, http.StatusBadRequest, CodeInvalidQuery, "search query q is too long")
+		return
+	}
+
+	limit, ok := intParam(w, r, "limit", DefaultPageSize, 1, MaxPageSize)
+	if !ok {
+		return
+	}
+
+	hits, err := h.Searcher.Search(r.Context(), q, limit)
+	if err != nil {
+		dbError(w, r, err, "error searching employees")
+		return
+	}
+
+	resp := SearchResponse{Items: make([]SearchResult, 0, len(hits))}
+	for _, hit := range hits {
+		resp.Items = append(resp.Items, SearchResult{Employee: hit.Employee, Score: hit.Score, Highlights: hit.Highlights})
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	indexed := database.NewIndexed(database.NewMemory())
+	for _, e := range []models.Employee{
+		{Name: "John Doe", Position: "SDE", Salary: money.Major(1000), Currency: "USD"},
+		{Name: "Jane Johnson", Position: "Manager", Salary: money.Major(2000), Currency: "USD"},
+	} {
+		_, err := indexed.Create(context.Background(), e)
+		assert.NoError(t, err)
+	}
+
+	tt := []struct {
+		name       string
+		searcher   database.Searcher
+		target     string
+		statusCode int
+		ids        []int64
+		code       string
+	}{
+		{name: "ranked", searcher: indexed, target: "/employee/search?q=john", statusCode: http.StatusOK, ids: []int64{1, 2}},
+		{name: "limit", searcher: indexed, target: "/employee/search?q=john&limit=1", statusCode: http.StatusOK, ids: []int64{1}},
+		{name: "typo", searcher: indexed, target: "/employee/search?q=mangaer", statusCode: http.StatusOK, ids: []int64{2}},
+		{name: "no match", searcher: indexed, target: "/employee/search?q=zebra", statusCode: http.StatusOK, ids: []int64{}},
+		{name: "missing query", searcher: indexed, target: "/employee/search?q=+", statusCode: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "invalid limit", searcher: indexed, target: "/employee/search?q=john&limit=0", statusCode: http.StatusBadRequest, code: CodeInvalidQuery},
+		{name: "disabled", target: "/employee/search?q=john", statusCode: http.StatusNotImplemented, code: CodeUnavailable},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Handler{EmployeeDB: indexed, Searcher: tc.searcher}
+
+			w := httptest.NewRecorder()
+			h.Search(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			if tc.code != "" {
+				assertProblem(t, w, tc.statusCode, tc.code)
+				return
+			}
+
+			assert.Equal(t, tc.statusCode, w.Code)
+
+			var resp SearchResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+			ids := []int64{}
+			for _, item := range resp.Items {
+				ids = append(ids, item.Employee.ID)
+			}
+
+			assert.Equal(t, tc.ids, ids)
+		})
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	indexed := database.NewIndexed(database.NewMemory())
+	_, err := indexed.Create(context.Background(), models.Employee{Name: "John Doe", Position: "SDE", Salary: money.Major(1000), Currency: "USD"})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	Handler{Searcher: indexed}.Search(w, httptest.NewRequest(http.MethodGet, "/employee/search?q=jo", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp SearchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 1)
+	assert.Equal(t, map[string]string{"name": "<mark>Jo</mark>hn Doe"}, resp.Items[0].Highlights)
+	assert.Positive(t, resp.Items[0].Score)
+}
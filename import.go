@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"example.com/m/Assesment/config"
+	"example.com/m/Assesment/importer"
+	"example.com/m/Assesment/validation"
+)
+
+// mappingFlags collects repeated -map flags.
+type mappingFlags []string
+
+func (m *mappingFlags) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *mappingFlags) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// runImport implements the import subcommand:
+//
+//	import <file> [-dry-run] [-upsert] [-key name,position] [-map Header=field]... [-report errors.csv]
+//
+// The file's format follows its extension, CSV unless it is .xlsx.
+func runImport(ctx context.Context, cfg config.Config) error {
+	if cfg.Store != config.StoreSQL {
+		return errors.New("import needs an sql store")
+	}
+
+	if len(cfg.Args) == 0 {
+		return errors.New("import needs the file to import")
+	}
+
+	path := cfg.Args[0]
+
+	var mappings mappingFlags
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and match the rows without writing")
+	upsert := fs.Bool("upsert", false, "update the employees rows match")
+	key := fs.String("key", "", "comma separated natural key fields, name by default")
+	reportPath := fs.String("report", "", "write the rejected rows as CSV to this file")
+	fs.Var(&mappings, "map", "map a column header to a field, as Header=field (repeatable)")
+
+	_, err := commandArgs(fs, cfg.Args[1:], 0)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := importer.ParseMapping(mappings)
+	if err != nil {
+		return err
+	}
+
+	empDB, closeDB, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	converter := cfg.Converter()
+	validator := validation.NewEmployee(validation.EmployeeOptions{
+		Positions:  cfg.Positions,
+		MaxSalary:  cfg.MaxSalary,
+		Currencies: converter.Currencies(),
+		Currency:   cfg.Currency,
+	})
+
+	report, err := importer.Run(ctx, empDB, file, importer.Options{
+		Format:    importer.FormatOf(path, ""),
+		Mapping:   mapping,
+		Key:       splitKey(*key),
+		Upsert:    *upsert,
+		DryRun:    *dryRun,
+		Validator: &validator,
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if report.DryRun {
+		prefix = "dry run: "
+	}
+
+	log.Printf("%s%d rows: %d created, %d updated, %d unchanged, %d rejected",
+		prefix, report.Rows, report.Created, report.Updated, report.Unchanged, report.Rejected)
+
+	for _, e := range report.Errors {
+		log.Printf("row %d: %s", e.Row, strings.Join(e.Reasons, "; "))
+	}
+
+	if *reportPath == "" {
+		return nil
+	}
+
+	out, err := os.Create(*reportPath)
+	if err != nil {
+		return err
+	}
+
+	err = report.WriteCSV(out)
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("writing the report: %w", err)
+	}
+
+	return out.Close()
+}
+
+func splitKey(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
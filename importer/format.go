@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is the spreadsheet format of an import.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// ParseFormat reads a format name, CSV when empty.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case "", CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	default:
+		return "", fmt.Errorf("%w: unknown format %q, want csv or xlsx", ErrInvalidOptions, name)
+	}
+}
+
+// FormatOf guesses the format from a file name's extension or, without
+// one, from a content type. CSV is the default.
+func FormatOf(filename, contentType string) Format {
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		if ext == ".xlsx" {
+			return XLSX
+		}
+
+		return CSV
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == xlsxContentType {
+		return XLSX
+	}
+
+	return CSV
+}
+
+// rowReader reads a sheet row by row with their 1-based row numbers, io.EOF
+// after the last.
+type rowReader interface {
+	Next() ([]string, int, error)
+	Close() error
+}
+
+func newRowReader(r io.Reader, format Format) (rowReader, error) {
+	switch format {
+	case "", CSV:
+		cr := csv.NewReader(r)
+		// rows may leave trailing empty cells out
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+
+		return csvRows{cr}, nil
+	case XLSX:
+		return newXLSXRows(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidOptions, format)
+	}
+}
+
+type csvRows struct {
+	r *csv.Reader
+}
+
+func (c csvRows) Next() ([]string, int, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return nil, 0, err
+	}
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	// the reader skips empty lines, the line keeps the numbering of the file
+	line, _ := c.r.FieldPos(0)
+
+	return record, line, nil
+}
+
+func (csvRows) Close() error {
+	return nil
+}
+
+// xlsxRows reads the first sheet of a workbook. The workbook is a zip
+// archive, read whole before its rows stream.
+type xlsxRows struct {
+	file   *excelize.File
+	rows   *excelize.Rows
+	number int
+}
+
+func newXLSXRows(r io.Reader) (*xlsxRows, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		file.Close()
+		return nil, fmt.Errorf("%w: the workbook has no sheet", ErrInvalidFile)
+	}
+
+	rows, err := file.Rows(sheets[0])
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	return &xlsxRows{file: file, rows: rows}, nil
+}
+
+func (x *xlsxRows) Next() ([]string, int, error) {
+	if !x.rows.Next() {
+		err := x.rows.Error()
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+
+		return nil, 0, io.EOF
+	}
+
+	x.number++
+
+	columns, err := x.rows.Columns()
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	return columns, x.number, nil
+}
+
+func (x *xlsxRows) Close() error {
+	x.rows.Close()
+	return x.file.Close()
+}
+
+// WriteCSV writes the rejected rows as CSV: the file's header and each
+// rejected row, preceded by its row number and followed by its reasons.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := append([]string{"row"}, r.Header...)
+	err := cw.Write(append(header, "errors"))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range r.Errors {
+		record := make([]string, len(r.Header))
+		copy(record, e.Record)
+
+		line := append([]string{strconv.Itoa(e.Row)}, record...)
+		err = cw.Write(append(line, strings.Join(e.Reasons, "; ")))
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
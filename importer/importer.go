@@ -0,0 +1,553 @@
+// Package importer loads employees from CSV and XLSX spreadsheets, row by
+// row, validating each and reporting the rows it rejects.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"example.com/m/Assesment/validation"
+)
+
+// employee fields columns can be mapped to
+const (
+	FieldName     = "name"
+	FieldPosition = "position"
+	FieldSalary   = "salary"
+	FieldCurrency = "currency"
+)
+
+var fields = []string{FieldName, FieldPosition, FieldSalary, FieldCurrency}
+
+// Errors returned by Run before any row is written.
+var (
+	ErrInvalidOptions = errors.New("invalid import options")
+	ErrInvalidFile    = errors.New("invalid import file")
+)
+
+// Mapping maps column headers to employee fields. Headers are compared
+// ignoring case and surrounding space.
+type Mapping map[string]string
+
+// DefaultMapping knows the usual headers of HR spreadsheets.
+var DefaultMapping = Mapping{
+	"name":          FieldName,
+	"full name":     FieldName,
+	"employee name": FieldName,
+	"position":      FieldPosition,
+	"title":         FieldPosition,
+	"job title":     FieldPosition,
+	"role":          FieldPosition,
+	"salary":        FieldSalary,
+	"annual salary": FieldSalary,
+	"pay":           FieldSalary,
+	"currency":      FieldCurrency,
+}
+
+// ParseMapping reads mappings written as "Header=field".
+func ParseMapping(values []string) (Mapping, error) {
+	mapping := make(Mapping)
+
+	for _, value := range values {
+		header, field, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("%w: mapping %q must be written as Header=field", ErrInvalidOptions, value)
+		}
+
+		mapping[header] = strings.TrimSpace(field)
+	}
+
+	return mapping, nil
+}
+
+// Options tunes an import. The zero value creates employees from a CSV file
+// with the default mapping, matching existing employees by name.
+type Options struct {
+	Format Format
+	// Mapping adds to and overrides DefaultMapping.
+	Mapping Mapping
+	// Key are the fields identifying an employee across imports, the name
+	// when empty. Values are compared ignoring case.
+	Key []string
+	// Upsert updates the employees a row matches, which are otherwise
+	// rejected as existing.
+	Upsert bool
+	// DryRun validates and matches every row but writes nothing.
+	DryRun bool
+	// Validator holds the employee rules, the defaults when nil.
+	Validator *validation.Employee
+}
+
+// Report sums up an import. In a dry run the counts are what the import
+// would have done.
+type Report struct {
+	DryRun    bool       `json:"dry_run"`
+	Rows      int        `json:"rows"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Rejected  int        `json:"rejected"`
+	Errors    []RowError `json:"errors"`
+	// Header is the file's header row, for the CSV error report.
+	Header []string `json:"-"`
+}
+
+// RowError is a rejected row with the reasons it was rejected.
+type RowError struct {
+	// Row is the row's number in the file, counting the header.
+	Row     int      `json:"row"`
+	Record  []string `json:"record"`
+	Reasons []string `json:"reasons"`
+}
+
+// createBatchSize is the number of new employees written per CreateMany.
+const createBatchSize = 100
+
+// row is a valid row waiting to be written.
+type row struct {
+	number   int
+	record   []string
+	employee models.Employee
+}
+
+type importer struct {
+	store     database.Employee
+	opts      Options
+	validator validation.Employee
+	columns   map[int]string
+	key       []string
+	// existing are the stored employees by natural key
+	existing map[string][]models.Employee
+	// seen are the rows already imported by natural key
+	seen    map[string]int
+	pending []row
+	report  Report
+}
+
+// Run imports the rows read from r into store. It fails before writing when
+// the options or the header are invalid; after that rows are written as they
+// are read, and a row that can't be imported is reported without stopping
+// the import.
+func Run(ctx context.Context, store database.Employee, r io.Reader, opts Options) (Report, error) {
+	im := &importer{store: store, opts: opts, seen: make(map[string]int)}
+	if opts.Validator != nil {
+		im.validator = *opts.Validator
+	} else {
+		im.validator = validation.NewEmployee(validation.EmployeeOptions{})
+	}
+
+	im.report.DryRun = opts.DryRun
+	im.report.Errors = []RowError{}
+
+	key, err := keyFields(opts.Key)
+	if err != nil {
+		return im.report, err
+	}
+
+	im.key = key
+
+	rows, err := newRowReader(r, opts.Format)
+	if err != nil {
+		return im.report, err
+	}
+	defer rows.Close()
+
+	header, _, err := rows.Next()
+	if errors.Is(err, io.EOF) {
+		return im.report, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+
+	if err != nil {
+		return im.report, err
+	}
+
+	im.report.Header = header
+
+	im.columns, err = mapColumns(header, opts.Mapping)
+	if err != nil {
+		return im.report, err
+	}
+
+	for _, field := range im.key {
+		if !mapped(im.columns, field) {
+			return im.report, fmt.Errorf("%w: no column maps to the key field %s", ErrInvalidFile, field)
+		}
+	}
+
+	err = im.loadExisting(ctx)
+	if err != nil {
+		return im.report, err
+	}
+
+	for {
+		record, number, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return im.report, err
+		}
+
+		if blank(record) {
+			continue
+		}
+
+		im.report.Rows++
+
+		err = im.importRow(ctx, number, record)
+		if err != nil {
+			return im.report, err
+		}
+	}
+
+	return im.report, im.flush(ctx)
+}
+
+func keyFields(key []string) ([]string, error) {
+	if len(key) == 0 {
+		return []string{FieldName}, nil
+	}
+
+	for _, field := range key {
+		if field != FieldName && field != FieldPosition {
+			return nil, fmt.Errorf("%w: key field %q must be name or position", ErrInvalidOptions, field)
+		}
+	}
+
+	return key, nil
+}
+
+// mapColumns maps the header's columns to fields, ignoring unknown headers.
+func mapColumns(header []string, extra Mapping) (map[int]string, error) {
+	mapping := make(Mapping, len(DefaultMapping)+len(extra))
+	for h, field := range DefaultMapping {
+		mapping[h] = field
+	}
+
+	for h, field := range extra {
+		known := false
+		for _, f := range fields {
+			known = known || f == field
+		}
+
+		if !known {
+			return nil, fmt.Errorf("%w: %q maps to unknown field %q, want any of %s", ErrInvalidOptions, h, field, strings.Join(fields, ", "))
+		}
+
+		mapping[normalizeHeader(h)] = field
+	}
+
+	columns := make(map[int]string)
+	mapped := make(map[string]string)
+
+	for i, h := range header {
+		field, ok := mapping[normalizeHeader(h)]
+		if !ok {
+			continue
+		}
+
+		if previous, ok := mapped[field]; ok {
+			return nil, fmt.Errorf("%w: columns %q and %q both map to %s", ErrInvalidFile, previous, h, field)
+		}
+
+		columns[i] = field
+		mapped[field] = h
+	}
+
+	if _, ok := mapped[FieldName]; !ok {
+		return nil, fmt.Errorf("%w: no column maps to name", ErrInvalidFile)
+	}
+
+	return columns, nil
+}
+
+func mapped(columns map[int]string, field string) bool {
+	for _, f := range columns {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeHeader(header string) string {
+	// spreadsheets saved as UTF-8 CSV often start with a byte order mark
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+}
+
+func blank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// listPageSize is the number of employees read per page of loadExisting.
+const listPageSize = 500
+
+// loadExisting indexes the stored employees by natural key.
+func (im *importer) loadExisting(ctx context.Context) error {
+	im.existing = make(map[string][]models.Employee)
+	opts := database.ListOptions{Limit: listPageSize}
+
+	for {
+		page, err := im.store.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, employee := range page.Employees {
+			key := im.naturalKey(employee)
+			im.existing[key] = append(im.existing[key], employee)
+		}
+
+		if !page.More {
+			return nil
+		}
+
+		last := database.KeysetOf(page.Employees[len(page.Employees)-1])
+		opts.After = &last
+	}
+}
+
+func (im *importer) naturalKey(employee models.Employee) string {
+	values := make([]string, 0, len(im.key))
+	for _, field := range im.key {
+		switch field {
+		case FieldName:
+			values = append(values, strings.ToLower(employee.Name))
+		case FieldPosition:
+			values = append(values, strings.ToLower(employee.Position))
+		}
+	}
+
+	return strings.Join(values, "\x00")
+}
+
+func (im *importer) reject(number int, record []string, reasons ...string) {
+	im.report.Rejected++
+	im.report.Errors = append(im.report.Errors, RowError{Row: number, Record: record, Reasons: reasons})
+}
+
+func (im *importer) importRow(ctx context.Context, number int, record []string) error {
+	employee, present, reasons := im.parse(record)
+	if len(reasons) > 0 {
+		im.reject(number, record, reasons...)
+		return nil
+	}
+
+	employee = validation.Normalize(employee)
+
+	key := im.naturalKey(employee)
+	if first, ok := im.seen[key]; ok {
+		im.reject(number, record, fmt.Sprintf("repeats the employee of row %d", first))
+		return nil
+	}
+
+	matches := im.existing[key]
+	switch {
+	case len(matches) > 1:
+		im.reject(number, record, fmt.Sprintf("matches %d employees, the key %s is ambiguous", len(matches), strings.Join(im.key, "+")))
+		return nil
+	case len(matches) == 1 && !im.opts.Upsert:
+		im.reject(number, record, fmt.Sprintf("employee %d already exists", matches[0].ID))
+		return nil
+	case len(matches) == 1:
+		return im.update(ctx, number, record, key, matches[0], employee, present)
+	}
+
+	employee = im.validator.Defaults(employee)
+
+	err := im.validator.ValidateCreate(employee)
+	if err != nil {
+		im.reject(number, record, validationReasons(err)...)
+		return nil
+	}
+
+	im.seen[key] = number
+	im.pending = append(im.pending, row{number: number, record: record, employee: employee})
+	if len(im.pending) >= createBatchSize {
+		return im.flush(ctx)
+	}
+
+	return nil
+}
+
+// parse reads the mapped cells of a record, present tells which fields had
+// a column.
+func (im *importer) parse(record []string) (models.Employee, map[string]bool, []string) {
+	var employee models.Employee
+	var reasons []string
+	present := make(map[string]bool)
+
+	for i, field := range im.columns {
+		present[field] = true
+
+		value := ""
+		if i < len(record) {
+			value = strings.TrimSpace(record[i])
+		}
+
+		switch field {
+		case FieldName:
+			employee.Name = value
+		case FieldPosition:
+			employee.Position = value
+		case FieldSalary:
+			if value == "" {
+				continue
+			}
+
+			// spreadsheets often group thousands, "50,000"
+			salary, err := money.Parse(strings.ReplaceAll(value, ",", ""))
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("salary: %q is not a number with at most 2 decimals", value))
+				continue
+			}
+
+			employee.Salary = salary
+		case FieldCurrency:
+			employee.Currency = value
+		}
+	}
+
+	return employee, present, reasons
+}
+
+// update writes the fields the file has columns for, unless they already
+// hold those values.
+func (im *importer) update(ctx context.Context, number int, record []string, key string, current, employee models.Employee, present map[string]bool) error {
+	var changes models.EmployeeChanges
+
+	if present[FieldName] && employee.Name != current.Name {
+		changes.Name = &employee.Name
+	}
+
+	if present[FieldPosition] && employee.Position != current.Position {
+		changes.Position = &employee.Position
+	}
+
+	if present[FieldSalary] && employee.Salary != current.Salary {
+		changes.Salary = &employee.Salary
+	}
+
+	// a blank currency keeps the current one
+	if present[FieldCurrency] && employee.Currency != "" && employee.Currency != current.Currency {
+		changes.Currency = &employee.Currency
+	}
+
+	err := im.validator.ValidateChanges(changes)
+	if err != nil {
+		im.reject(number, record, validationReasons(err)...)
+		return nil
+	}
+
+	im.seen[key] = number
+
+	if changes.Empty() {
+		im.report.Unchanged++
+		return nil
+	}
+
+	if im.opts.DryRun {
+		im.report.Updated++
+		return nil
+	}
+
+	// the employee must not have changed since it was matched
+	changes.IfVersion = current.Version
+
+	_, err = im.store.Update(ctx, current.ID, changes)
+	if err != nil {
+		return im.writeError(number, record, err)
+	}
+
+	im.report.Updated++
+
+	return nil
+}
+
+// flush creates the pending rows in one batch, row by row when the batch
+// fails to find the rows at fault.
+func (im *importer) flush(ctx context.Context) error {
+	pending := im.pending
+	im.pending = nil
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if im.opts.DryRun {
+		im.report.Created += len(pending)
+		return nil
+	}
+
+	employees := make([]models.Employee, 0, len(pending))
+	for _, p := range pending {
+		employees = append(employees, p.employee)
+	}
+
+	_, err := im.store.CreateMany(ctx, employees)
+	if err == nil {
+		im.report.Created += len(pending)
+		return nil
+	}
+
+	for _, p := range pending {
+		_, err := im.store.Create(ctx, p.employee)
+		if err != nil {
+			err = im.writeError(p.number, p.record, err)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		im.report.Created++
+	}
+
+	return nil
+}
+
+// writeError rejects the row a write failed for, unless the failure is not
+// the row's own and ends the import.
+func (im *importer) writeError(number int, record []string, err error) error {
+	switch {
+	case errors.Is(err, database.ErrConflict):
+		im.reject(number, record, "the employee changed during the import")
+	case errors.Is(err, database.ErrNotFound):
+		im.reject(number, record, "the employee was deleted during the import")
+	case errors.Is(err, database.ErrDuplicate), errors.Is(err, database.ErrConstraint):
+		im.reject(number, record, err.Error())
+	default:
+		return err
+	}
+
+	return nil
+}
+
+func validationReasons(err error) []string {
+	var errs validation.Errors
+	if !errors.As(err, &errs) {
+		return []string{err.Error()}
+	}
+
+	reasons := make([]string, 0, len(errs))
+	for _, e := range errs {
+		reasons = append(reasons, e.Field+": "+e.Message)
+	}
+
+	return reasons
+}
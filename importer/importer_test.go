@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// stored is a store holding John, an SDE earning 1000.
+func stored(t *testing.T) *database.Memory {
+	store := database.NewMemory()
+	_, err := store.Create(context.Background(), models.Employee{Name: "John", Position: "SDE", Salary: money.Major(1000), Currency: "USD"})
+	assert.NoError(t, err)
+
+	return store
+}
+
+func all(t *testing.T, store database.Employee) []models.Employee {
+	page, err := store.List(context.Background(), database.ListOptions{Limit: 100})
+	assert.NoError(t, err)
+
+	return page.Employees
+}
+
+func TestRun(t *testing.T) {
+	file := "\ufeffFull Name,Job Title,Salary,Notes\n" +
+		"Jane,PM,\"2,000\",new\n" +
+		"\n" +
+		"John,SDE-2,1500,\n" +
+		",QA,10,\n" +
+		"Jim,QA,lots,\n" +
+		"jane,PM,3000,\n"
+
+	tt := []struct {
+		name      string
+		opts      Options
+		report    Report
+		employees []models.Employee
+	}{
+		{
+			name: "create",
+			report: Report{Rows: 5, Created: 1, Rejected: 4, Errors: []RowError{
+				{Row: 4, Record: []string{"John", "SDE-2", "1500", ""}, Reasons: []string{"employee 1 already exists"}},
+				{Row: 5, Record: []string{"", "QA", "10", ""}, Reasons: []string{"name: is required"}},
+				{Row: 6, Record: []string{"Jim", "QA", "lots", ""}, Reasons: []string{`salary: "lots" is not a number with at most 2 decimals`}},
+				{Row: 7, Record: []string{"jane", "PM", "3000", ""}, Reasons: []string{"repeats the employee of row 2"}},
+			}},
+			employees: []models.Employee{
+				{ID: 1, Name: "John", Position: "SDE", Salary: money.Major(1000), Currency: "USD", Version: 1},
+				{ID: 2, Name: "Jane", Position: "PM", Salary: money.Major(2000), Currency: "USD", Version: 1},
+			},
+		},
+		{
+			name: "upsert",
+			opts: Options{Upsert: true},
+			report: Report{Rows: 5, Created: 1, Updated: 1, Rejected: 3, Errors: []RowError{
+				{Row: 5, Record: []string{"", "QA", "10", ""}, Reasons: []string{"name: is required"}},
+				{Row: 6, Record: []string{"Jim", "QA", "lots", ""}, Reasons: []string{`salary: "lots" is not a number with at most 2 decimals`}},
+				{Row: 7, Record: []string{"jane", "PM", "3000", ""}, Reasons: []string{"repeats the employee of row 2"}},
+			}},
+			employees: []models.Employee{
+				{ID: 1, Name: "John", Position: "SDE-2", Salary: money.Major(1500), Currency: "USD", Version: 2},
+				{ID: 2, Name: "Jane", Position: "PM", Salary: money.Major(2000), Currency: "USD", Version: 1},
+			},
+		},
+		{
+			name: "dry run",
+			opts: Options{Upsert: true, DryRun: true},
+			report: Report{DryRun: true, Rows: 5, Created: 1, Updated: 1, Rejected: 3, Errors: []RowError{
+				{Row: 5, Record: []string{"", "QA", "10", ""}, Reasons: []string{"name: is required"}},
+				{Row: 6, Record: []string{"Jim", "QA", "lots", ""}, Reasons: []string{`salary: "lots" is not a number with at most 2 decimals`}},
+				{Row: 7, Record: []string{"jane", "PM", "3000", ""}, Reasons: []string{"repeats the employee of row 2"}},
+			}},
+			employees: []models.Employee{
+				{ID: 1, Name: "John", Position: "SDE", Salary: money.Major(1000), Currency: "USD", Version: 1},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := stored(t)
+
+			report, err := Run(context.Background(), store, strings.NewReader(file), tc.opts)
+			assert.NoError(t, err)
+
+			tc.report.Header = []string{"\ufeffFull Name", "Job Title", "Salary", "Notes"}
+			assert.Equal(t, tc.report, report)
+			assert.Equal(t, tc.employees, all(t, store))
+		})
+	}
+}
+
+func TestRunMapping(t *testing.T) {
+	store := stored(t)
+
+	// key by name and position, with a custom header for the salary
+	file := "name,position,Gross\nJohn,SDE,1000\nJohn,QA,500\n"
+	opts := Options{Key: []string{FieldName, FieldPosition}, Mapping: Mapping{" GROSS ": FieldSalary}, Upsert: true}
+
+	report, err := Run(context.Background(), store, strings.NewReader(file), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, 1, report.Created)
+
+	// a name alone is ambiguous now
+	report, err = Run(context.Background(), store, strings.NewReader("name,salary\njohn,2000\n"), Options{Upsert: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"matches 2 employees, the key name is ambiguous"}, report.Errors[0].Reasons)
+}
+
+func TestRunCurrency(t *testing.T) {
+	store := stored(t)
+
+	// a blank currency is the default one for new employees
+	file := "name,position,salary,currency\nJohn,SDE,1000,eur\nJim,QA,1500.50,\nJoe,QA,12.345,USD\nAnn,QA,10.5,JPY\n"
+
+	report, err := Run(context.Background(), store, strings.NewReader(file), Options{Upsert: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, []RowError{
+		{Row: 4, Record: []string{"Joe", "QA", "12.345", "USD"}, Reasons: []string{`salary: "12.345" is not a number with at most 2 decimals`}},
+		{Row: 5, Record: []string{"Ann", "QA", "10.5", "JPY"}, Reasons: []string{"salary: has more decimals than JPY allows"}},
+	}, report.Errors)
+
+	employees := all(t, store)
+	assert.Equal(t, "EUR", employees[0].Currency)
+	assert.Equal(t, models.Employee{ID: 2, Name: "Jim", Position: "QA", Salary: 150050, Currency: "USD", Version: 1}, employees[1])
+}
+
+func TestRunInvalid(t *testing.T) {
+	tt := []struct {
+		name string
+		file string
+		opts Options
+		err  error
+	}{
+		{name: "empty", file: "", err: ErrInvalidFile},
+		{name: "no name column", file: "title,salary\nSDE,1\n", err: ErrInvalidFile},
+		{name: "two name columns", file: "name,full name\nJohn,John\n", err: ErrInvalidFile},
+		{name: "unmapped key", file: "name\nJohn\n", opts: Options{Key: []string{FieldPosition}}, err: ErrInvalidFile},
+		{name: "unknown key", file: "name\nJohn\n", opts: Options{Key: []string{FieldSalary}}, err: ErrInvalidOptions},
+		{name: "unknown field", file: "name\nJohn\n", opts: Options{Mapping: Mapping{"x": "age"}}, err: ErrInvalidOptions},
+		{name: "broken csv", file: "name\n\"John\n", err: ErrInvalidFile},
+		{name: "broken xlsx", file: "name\nJohn\n", opts: Options{Format: XLSX}, err: ErrInvalidFile},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			store := stored(t)
+
+			_, err := Run(context.Background(), store, strings.NewReader(tc.file), tc.opts)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Len(t, all(t, store), 1)
+		})
+	}
+}
+
+func TestRunXLSX(t *testing.T) {
+	f := excelize.NewFile()
+	for i, row := range [][]interface{}{
+		{"Name", "Position", "Salary"},
+		{"Jane", "PM", 2000},
+		{"Jim", "QA"},
+	} {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		assert.NoError(t, err)
+		assert.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, f.Write(&buf))
+
+	store := stored(t)
+	report, err := Run(context.Background(), store, &buf, Options{Format: XLSX})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, []RowError{{Row: 3, Record: []string{"Jim", "QA"}, Reasons: []string{"salary: is required"}}}, report.Errors)
+	assert.Equal(t, "Jane", all(t, store)[1].Name)
+}
+
+func TestReportCSV(t *testing.T) {
+	report := Report{
+		Header: []string{"name", "salary"},
+		Errors: []RowError{{Row: 3, Record: []string{"Jim"}, Reasons: []string{"salary: is required", "name: too short"}}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.WriteCSV(&buf))
+	assert.Equal(t, "row,name,salary,errors\n3,Jim,,salary: is required; name: too short\n", buf.String())
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, XLSX, FormatOf("staff.XLSX", "text/csv"))
+	assert.Equal(t, CSV, FormatOf("staff.csv", xlsxContentType))
+	assert.Equal(t, XLSX, FormatOf("", xlsxContentType))
+	assert.Equal(t, CSV, FormatOf("", ""))
+
+	_, err := ParseFormat("ods")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
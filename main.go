@@ -1,33 +1,225 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"example.com/m/Assesment/config"
 	"example.com/m/Assesment/database"
 	"example.com/m/Assesment/handler"
+	"example.com/m/Assesment/validation"
 	"github.com/gorilla/mux"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
+	// the first argument may name a subcommand, serving is the default
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "serve":
+		_, err = commandArgs(flag.NewFlagSet("serve", flag.ContinueOnError), cfg.Args, 0)
+		if err == nil {
+			err = run(cfg)
+		}
+	case "migrate":
+		err = runMigrate(context.Background(), cfg)
+	case "import":
+		err = runImport(context.Background(), cfg)
+	case "purge":
+		err = runPurge(context.Background(), cfg)
+	default:
+		err = fmt.Errorf("unknown command %q, want serve, migrate, import or purge", command)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// commandArgs parses the flags of a command from the arguments left after the
+// global ones, returning at most max positional arguments.
+func commandArgs(fs *flag.FlagSet, args []string, max int) ([]string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := config.Positional(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > max {
+		return nil, fmt.Errorf("%s: unexpected argument %q", fs.Name(), rest[max])
+	}
+
+	return rest, nil
+}
+
+func run(cfg config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	empDB, closeDB, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	// searching through an in-process index, built from the store at
+	// startup and kept in step with the writes made by this instance
+	indexed := database.NewIndexed(empDB)
+	err = indexed.Rebuild(ctx)
+	if err != nil {
+		return fmt.Errorf("building the search index: %w", err)
+	}
+
+	// deleted employees are kept for restoring until past retention
+	if cfg.PurgeRetention > 0 {
+		go runPurger(ctx, empDB, cfg.PurgeRetention, cfg.PurgeInterval)
+	}
+
+	// scheduled changes go through the index, which follows them
+	go runScheduler(ctx, indexed, cfg.ScheduleInterval)
+
+	converter := cfg.Converter()
+	validator := validation.NewEmployee(validation.EmployeeOptions{
+		Positions:  cfg.Positions,
+		MaxSalary:  cfg.MaxSalary,
+		Currencies: converter.Currencies(),
+		Currency:   cfg.Currency,
+	})
+	eh := handler.Handler{EmployeeDB: indexed, HistoryDB: indexed, CompensationDB: indexed, DepartmentDB: empDB, HierarchyDB: empDB, Validator: &validator, Searcher: indexed, Converter: &converter}
+
+	if cfg.CursorSecret != "" {
+		cursors := handler.NewCursors([]byte(cfg.CursorSecret))
+		eh.Cursors = &cursors
+	} else {
+		log.Printf("no cursor secret configured, list cursors expire on restart")
+	}
+
+	server := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      newRouter(eh),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", cfg.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, draining in-flight requests")
 
-	// connecting to db
-	dsn := "username:password@/dbname"
-	db, err := sql.Open("mysql", dsn)
+	// draining in-flight requests, db is closed by the deferred call afterwards
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	return server.Shutdown(shutdownCtx)
+}
+
+func openStore(ctx context.Context, cfg config.Config) (database.Store, func() error, error) {
+	if cfg.Store == config.StoreMemory {
+		log.Printf("using in-memory employee store, data is lost on exit")
+		return database.NewMemory(), func() error { return nil }, nil
+	}
+
+	// connecting to db, the DSN scheme picks the dialect
+	db, dialect, err := database.Open(cfg.DSN)
 	if err != nil {
-		return
+		return nil, nil, err
 	}
-	defer db.Close()
 
-	empDB := database.New(db)
-	eh := handler.Handler{EmployeeDB: empDB}
+	// failing fast if the database is not reachable
+	pingCtx, cancel := context.WithTimeout(ctx, cfg.PingTimeout)
+	err = db.PingContext(pingCtx)
+	cancel()
+	if err != nil {
+		db.Close()
+		return nil, nil, errors.New("database unreachable at startup: " + err.Error())
+	}
 
+	if cfg.Migrate {
+		err = migrateOnStartup(ctx, db, dialect)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	}
+
+	empDB := database.New(db, dialect, database.Timeouts{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout})
+
+	return empDB, db.Close, nil
+}
+
+func newRouter(eh handler.Handler) *mux.Router {
 	r := mux.NewRouter()
+	r.Use(handler.WithCaller)
 
+	// registered ahead of /employee/{id}, which would match them too
+	r.HandleFunc("/employee/search", eh.Search).Methods(http.MethodGet)
+	r.HandleFunc("/employee/batch", eh.CreateBatch).Methods(http.MethodPost)
+	r.HandleFunc("/employee/batch", eh.UpdateBatch).Methods(http.MethodPatch)
+	r.HandleFunc("/employee/batch", eh.DeleteBatch).Methods(http.MethodDelete)
+	r.HandleFunc("/employee/import", eh.Import).Methods(http.MethodPost)
+	r.HandleFunc("/employee/export", eh.Export).Methods(http.MethodGet)
+	r.HandleFunc("/employee/audit", eh.Audit).Methods(http.MethodGet)
+	r.HandleFunc("/employee/compensation/report", eh.CompensationReport).Methods(http.MethodGet)
+	r.HandleFunc("/employee/orgchart", eh.OrgChart).Methods(http.MethodGet)
 	r.HandleFunc("/employee/{id}", eh.Get).Methods(http.MethodGet)
 	r.HandleFunc("/employee/", eh.GetAll).Methods(http.MethodGet)
 	r.HandleFunc("/employee", eh.Create).Methods(http.MethodPost)
 	r.HandleFunc("/employee/{id}", eh.Update).Methods(http.MethodPut)
+	r.HandleFunc("/employee/{id}", eh.Patch).Methods(http.MethodPatch)
 	r.HandleFunc("/employee/{id}", eh.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/employee/{id}/restore", eh.Restore).Methods(http.MethodPost)
+	r.HandleFunc("/employee/{id}/audit", eh.EmployeeAudit).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/history", eh.History).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/reports", eh.Reports).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/chain", eh.Chain).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/subtree", eh.Subtree).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/scheduled", eh.Schedule).Methods(http.MethodPost)
+	r.HandleFunc("/employee/{id}/scheduled", eh.Scheduled).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/scheduled/{change}", eh.CancelScheduled).Methods(http.MethodDelete)
+	r.HandleFunc("/employee/{id}/compensation", eh.ProposeCompensation).Methods(http.MethodPost)
+	r.HandleFunc("/employee/{id}/compensation", eh.Compensation).Methods(http.MethodGet)
+	r.HandleFunc("/employee/{id}/compensation/{change}/approve", eh.ApproveCompensation).Methods(http.MethodPost)
+	r.HandleFunc("/employee/{id}/compensation/{change}/reject", eh.RejectCompensation).Methods(http.MethodPost)
+
+	// registered ahead of /department/{id}, which would match it too
+	r.HandleFunc("/department/summary", eh.DepartmentSummary).Methods(http.MethodGet)
+	r.HandleFunc("/department/", eh.ListDepartments).Methods(http.MethodGet)
+	r.HandleFunc("/department", eh.CreateDepartment).Methods(http.MethodPost)
+	r.HandleFunc("/department/{id}", eh.GetDepartment).Methods(http.MethodGet)
+	r.HandleFunc("/department/{id}", eh.UpdateDepartment).Methods(http.MethodPut)
+	r.HandleFunc("/department/{id}", eh.DeleteDepartment).Methods(http.MethodDelete)
 
+	return r
 }
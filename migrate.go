@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"example.com/m/Assesment/config"
+	"example.com/m/Assesment/database"
+	"example.com/m/Assesment/migration"
+)
+
+// runMigrate implements the migrate subcommand:
+//
+//	migrate [up]       apply pending migrations
+//	migrate down [n]   roll back the last n migrations, default 1
+//	migrate status     list migrations and whether they are applied
+//	migrate unlock     release a lock left behind by a crashed run
+func runMigrate(ctx context.Context, cfg config.Config) error {
+	if cfg.Store != config.StoreSQL {
+		return errors.New("migrate needs an sql store")
+	}
+
+	args, err := commandArgs(flag.NewFlagSet("migrate", flag.ContinueOnError), cfg.Args, 2)
+	if err != nil {
+		return err
+	}
+
+	db, dialect, err := database.Open(cfg.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migration.New(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		return migrateUp(ctx, migrator)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations to roll back %q", args[1])
+			}
+		}
+
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("reverted %d_%s", m.Version, m.Name)
+		}
+
+		return err
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+
+		return w.Flush()
+	case "unlock":
+		return migrator.Unlock(ctx)
+	default:
+		return fmt.Errorf("unknown migrate action %q, want up, down, status or unlock", action)
+	}
+}
+
+func migrateUp(ctx context.Context, migrator *migration.Migrator) error {
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		log.Printf("applied %d_%s", m.Version, m.Name)
+	}
+
+	return err
+}
+
+// migrateOnStartup applies pending migrations when the service is started
+// with -migrate.
+func migrateOnStartup(ctx context.Context, db *sql.DB, dialect database.Dialect) error {
+	migrator, err := migration.New(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	return migrateUp(ctx, migrator)
+}
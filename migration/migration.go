@@ -0,0 +1,391 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// files holds one directory of versioned scripts per dialect, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+//
+//go:embed sql
+var files embed.FS
+
+var (
+	ErrLocked           = errors.New("migration: another run holds the migration lock")
+	ErrChecksumMismatch = errors.New("migration: applied migration differs from the embedded script")
+	ErrUnknownVersion   = errors.New("migration: database is at a version this build does not know")
+)
+
+// Dialect is the part of database.Dialect the migrator needs.
+type Dialect interface {
+	Name() string
+	Rebind(query string) string
+}
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Dialect    Dialect
+	Migrations []Migration
+	// Owner is recorded with the lock so a stuck lock can be traced back.
+	Owner string
+}
+
+const (
+	createMigrationsTable = `create table if not exists schema_migrations (
+	version bigint not null primary key,
+	name varchar(255) not null,
+	checksum varchar(64) not null,
+	applied_at timestamp not null default current_timestamp
+)`
+	createLockTable = `create table if not exists schema_migrations_lock (
+	id int not null primary key,
+	owner varchar(255) not null,
+	locked_at timestamp not null default current_timestamp
+)`
+	lockQuery        = "insert into schema_migrations_lock (id, owner) values (1, ?)"
+	lockOwnerQuery   = "select owner from schema_migrations_lock where id = 1"
+	unlockQuery      = "delete from schema_migrations_lock where id = 1 and owner = ?"
+	forceUnlockQuery = "delete from schema_migrations_lock where id = 1"
+	appliedQuery     = "select version, checksum, applied_at from schema_migrations order by version"
+	recordQuery      = "insert into schema_migrations (version, name, checksum) values (?, ?, ?)"
+	forgetQuery      = "delete from schema_migrations where version = ?"
+)
+
+// New returns a Migrator for the scripts embedded for the dialect.
+func New(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := Load(files, path.Join("sql", dialect.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+
+	return &Migrator{
+		DB:         db,
+		Dialect:    dialect,
+		Migrations: migrations,
+		Owner:      host + ":" + strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Load reads the migrations in dir sorted by version. Every version needs an
+// up script, the down script is optional.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migration: reading %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration: %s is not named <version>_<name>.(up|down).sql", name)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+
+		if m.Name != label {
+			return nil, fmt.Errorf("migration: version %d used by %q and %q", version, m.Name, label)
+		}
+
+		switch direction {
+		case ".up":
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		case ".down":
+			m.Down = string(data)
+		default:
+			return nil, fmt.Errorf("migration: %s is neither an up nor a down script", name)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration: version %d has no up script", m.Version)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns the ones applied. MySQL commits DDL implicitly, so
+// there a failing script can leave its earlier statements applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.locked(ctx, func(done map[int64]Status) error {
+		for _, migration := range m.Migrations {
+			if done[migration.Version].Applied {
+				continue
+			}
+
+			err := m.apply(ctx, migration.Up, recordQuery, migration.Version, migration.Name, migration.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration: applying %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns the ones rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.locked(ctx, func(done map[int64]Status) error {
+		for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.Migrations[i]
+			if !done[migration.Version].Applied {
+				continue
+			}
+
+			if migration.Down == "" {
+				return fmt.Errorf("migration: %d_%s has no down script", migration.Version, migration.Name)
+			}
+
+			err := m.apply(ctx, migration.Down, forgetQuery, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration: reverting %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	err := m.ensureTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []Status
+	for _, migration := range m.Migrations {
+		s := done[migration.Version]
+		s.Migration = migration
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// Unlock removes the migration lock whoever holds it, for recovering from a
+// run that died without releasing it.
+func (m *Migrator) Unlock(ctx context.Context) error {
+	err := m.ensureTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, forceUnlockQuery)
+	return err
+}
+
+// locked runs fn while holding the migration lock, after checking that the
+// applied migrations still match the embedded ones.
+func (m *Migrator) locked(ctx context.Context, fn func(done map[int64]Status) error) error {
+	err := m.ensureTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, m.Dialect.Rebind(lockQuery), m.Owner)
+	if err != nil {
+		// a failed insert with a row present means someone else holds it
+		var owner string
+		if m.DB.QueryRowContext(ctx, lockOwnerQuery).Scan(&owner) == nil {
+			return fmt.Errorf("%w (held by %s)", ErrLocked, owner)
+		}
+
+		return err
+	}
+
+	defer m.DB.ExecContext(context.Background(), m.Dialect.Rebind(unlockQuery), m.Owner)
+
+	done, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = m.verify(done)
+	if err != nil {
+		return err
+	}
+
+	return fn(done)
+}
+
+func (m *Migrator) verify(done map[int64]Status) error {
+	known := make(map[int64]Migration, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, s := range done {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+
+		if s.Checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	for _, query := range []string{createMigrationsTable, createLockTable} {
+		_, err := m.DB.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("migration: creating bookkeeping tables: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]Status, error) {
+	rows, err := m.DB.QueryContext(ctx, appliedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	done := make(map[int64]Status)
+	for rows.Next() {
+		var s Status
+		err = rows.Scan(&s.Version, &s.Checksum, &s.AppliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Applied = true
+		done[s.Version] = s
+	}
+
+	return done, rows.Err()
+}
+
+// apply runs a script and its bookkeeping statement in one transaction.
+func (m *Migrator) apply(ctx context.Context, script, bookkeeping string, args ...interface{}) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, statement := range statements(script) {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, m.Dialect.Rebind(bookkeeping), args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// statements splits a script on semicolons ending a line, so drivers that
+// refuse multi-statement execs can run it.
+func statements(script string) []string {
+	var result []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+
+	return result
+}
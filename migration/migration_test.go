@@ -0,0 +1,206 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"example.com/m/Assesment/database"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(t *testing.T) (*sql.DB, database.Dialect) {
+	t.Helper()
+
+	db, dialect, err := database.Open("sqlite://" + filepath.Join(t.TempDir(), "employee.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db, dialect
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	// every dialect ships the same versions
+	var versions []int64
+	for _, dialect := range []database.Dialect{database.MySQL, database.Postgres, database.SQLite} {
+		m, err := New(nil, dialect)
+		assert.NoError(t, err)
+
+		var current []int64
+		for _, migration := range m.Migrations {
+			current = append(current, migration.Version)
+			assert.NotEmpty(t, migration.Down, "%s %d has no down script", dialect.Name(), migration.Version)
+		}
+
+		if versions == nil {
+			versions = current
+		}
+
+		assert.Equal(t, versions, current, dialect.Name())
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	db, dialect := openSQLite(t)
+	ctx := context.Background()
+
+	m, err := New(db, dialect)
+	assert.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(m.Migrations))
+
+	_, err = db.ExecContext(ctx, "insert into employee (name, position, salary) values ('John', 'SDE', 1)")
+	assert.NoError(t, err)
+
+	// nothing left to apply
+	applied, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.True(t, s.Applied)
+		assert.NotEmpty(t, s.AppliedAt)
+	}
+
+	reverted, err := m.Down(ctx, len(m.Migrations))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(m.Migrations))
+
+	_, err = db.ExecContext(ctx, "select count(*) from employee")
+	assert.Error(t, err)
+
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.False(t, s.Applied)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db, dialect := openSQLite(t)
+	ctx := context.Background()
+
+	m := &Migrator{DB: db, Dialect: dialect, Owner: "test", Migrations: []Migration{
+		{Version: 1, Name: "first", Up: "create table first (id int)", Down: "drop table first", Checksum: "a"},
+	}}
+
+	_, err := m.Up(ctx)
+	assert.NoError(t, err)
+
+	m.Migrations[0].Checksum = "b"
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	// a database ahead of the build is refused too
+	m.Migrations = nil
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestLock(t *testing.T) {
+	db, dialect := openSQLite(t)
+	ctx := context.Background()
+
+	m, err := New(db, dialect)
+	assert.NoError(t, err)
+
+	other := *m
+	other.Owner = "other-runner"
+
+	// simulate a concurrent runner holding the lock
+	assert.NoError(t, m.ensureTables(ctx))
+	_, err = db.ExecContext(ctx, "insert into schema_migrations_lock (id, owner) values (1, 'other-runner')")
+	assert.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	assert.NoError(t, m.Unlock(ctx))
+
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+
+	// the lock is released after a run
+	_, err = other.Up(ctx)
+	assert.NoError(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int64
+		wantErr  bool
+	}{
+		{
+			name: "Sorted by version",
+			files: fstest.MapFS{
+				"m/0002_second.up.sql":  {Data: []byte("b")},
+				"m/0001_first.up.sql":   {Data: []byte("a")},
+				"m/0001_first.down.sql": {Data: []byte("c")},
+				"m/README.md":           {Data: []byte("ignored")},
+			},
+			versions: []int64{1, 2},
+		},
+		{
+			name:    "Bad name",
+			files:   fstest.MapFS{"m/first.up.sql": {Data: []byte("a")}},
+			wantErr: true,
+		},
+		{
+			name:    "Missing up script",
+			files:   fstest.MapFS{"m/0001_first.down.sql": {Data: []byte("a")}},
+			wantErr: true,
+		},
+		{
+			name: "Version reused",
+			files: fstest.MapFS{
+				"m/0001_first.up.sql":  {Data: []byte("a")},
+				"m/0001_second.up.sql": {Data: []byte("b")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			migrations, err := Load(tc.files, "m")
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var versions []int64
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+				assert.Len(t, m.Checksum, 64)
+			}
+
+			assert.Equal(t, tc.versions, versions)
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := "-- comment\ncreate table a (\n\tid int\n);\n\ncreate index a_id on a (id);\ninsert into a values (1)"
+
+	assert.Equal(t, []string{
+		"create table a (\n\tid int\n)",
+		"create index a_id on a (id)",
+		"insert into a values (1)",
+	}, statements(script))
+}
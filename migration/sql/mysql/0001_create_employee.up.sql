@@ -0,0 +1,6 @@
+create table if not exists employee (
+	id bigint not null auto_increment primary key,
+	name varchar(255) not null,
+	position varchar(255) not null,
+	salary double not null
+) engine = InnoDB;
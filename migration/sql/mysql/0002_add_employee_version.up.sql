@@ -0,0 +1 @@
+alter table employee add column version bigint not null default 1;
@@ -0,0 +1 @@
+alter table employee drop column deleted_at;
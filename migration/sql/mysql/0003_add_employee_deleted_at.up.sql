@@ -0,0 +1,2 @@
+alter table employee add column deleted_at datetime null;
+create index employee_deleted_at on employee (deleted_at);
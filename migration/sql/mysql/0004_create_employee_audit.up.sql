@@ -0,0 +1,13 @@
+create table if not exists employee_audit (
+	id bigint not null auto_increment primary key,
+	employee_id bigint not null,
+	operation varchar(16) not null,
+	actor varchar(255) not null,
+	request_id varchar(255) not null default '',
+	changed_at datetime(6) not null,
+	version bigint not null,
+	changes text not null
+);
+create index employee_audit_employee on employee_audit (employee_id, id);
+create index employee_audit_actor on employee_audit (actor, id);
+create index employee_audit_changed_at on employee_audit (changed_at);
@@ -0,0 +1,26 @@
+create table if not exists employee_history (
+	employee_id bigint not null,
+	version bigint not null,
+	name varchar(255) not null,
+	position varchar(255) not null,
+	salary double not null,
+	valid_from datetime(6) not null,
+	valid_to datetime(6) null,
+	primary key (employee_id, version)
+);
+create index employee_history_valid_from on employee_history (employee_id, valid_from);
+create table if not exists employee_schedule (
+	id bigint not null auto_increment primary key,
+	employee_id bigint not null,
+	effective_at datetime(6) not null,
+	name varchar(255) null,
+	position varchar(255) null,
+	salary double null,
+	actor varchar(255) not null,
+	request_id varchar(255) not null default ''
+);
+create index employee_schedule_effective_at on employee_schedule (effective_at, id);
+create index employee_schedule_employee on employee_schedule (employee_id, effective_at);
+-- employees written before history was kept start their history now
+insert into employee_history (employee_id, version, name, position, salary, valid_from)
+	select id, version, name, position, salary, utc_timestamp(6) from employee where deleted_at is null;
@@ -0,0 +1,19 @@
+create table if not exists compensation_change (
+	id bigint not null auto_increment primary key,
+	employee_id bigint not null,
+	position varchar(255) not null,
+	current_salary double not null,
+	salary double not null,
+	reason varchar(32) not null,
+	effective_at datetime(6) not null,
+	status varchar(16) not null,
+	proposed_by varchar(255) not null,
+	proposed_at datetime(6) not null,
+	decided_by varchar(255) not null default '',
+	decided_at datetime(6) null,
+	schedule_id bigint null,
+	note varchar(1000) not null default ''
+);
+create index compensation_change_employee on compensation_change (employee_id, id);
+create index compensation_change_effective_at on compensation_change (status, effective_at);
+create index compensation_change_schedule on compensation_change (schedule_id);
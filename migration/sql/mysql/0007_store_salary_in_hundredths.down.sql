@@ -0,0 +1,8 @@
+alter table compensation_change drop column currency, modify current_salary double not null, modify salary double not null;
+update compensation_change set current_salary = current_salary / 100, salary = salary / 100;
+alter table employee_schedule drop column currency, modify salary double null;
+update employee_schedule set salary = salary / 100;
+alter table employee_history drop column currency, modify salary double not null;
+update employee_history set salary = salary / 100;
+alter table employee drop column currency, modify salary double not null;
+update employee set salary = salary / 100;
@@ -0,0 +1,11 @@
+-- salaries become integers in hundredths of the major unit of their
+-- currency, whatever its minor unit (1000 JPY is 100000, 12.34 USD is 1234),
+-- existing ones in USD
+update employee set salary = round(salary * 100);
+alter table employee modify salary bigint not null, add column currency char(3) not null default 'USD';
+update employee_history set salary = round(salary * 100);
+alter table employee_history modify salary bigint not null, add column currency char(3) not null default 'USD';
+update employee_schedule set salary = round(salary * 100);
+alter table employee_schedule modify salary bigint null, add column currency char(3) null;
+update compensation_change set current_salary = round(current_salary * 100), salary = round(salary * 100);
+alter table compensation_change modify current_salary bigint not null, modify salary bigint not null, add column currency char(3) not null default 'USD';
@@ -0,0 +1,6 @@
+alter table employee_schedule drop column department_id;
+alter table employee_history drop column department_id;
+alter table employee drop foreign key employee_department;
+drop index employee_department_id on employee;
+alter table employee drop column department_id;
+drop table if exists department;
@@ -0,0 +1,12 @@
+create table if not exists department (
+	id bigint not null auto_increment primary key,
+	name varchar(255) not null,
+	unique key department_name (name)
+) engine = InnoDB;
+-- employees are in no department until assigned one
+alter table employee add column department_id bigint null,
+	add constraint employee_department foreign key (department_id) references department (id);
+create index employee_department_id on employee (department_id);
+alter table employee_history add column department_id bigint null;
+-- a scheduled move is checked again when made, zero takes the employee out
+alter table employee_schedule add column department_id bigint null;
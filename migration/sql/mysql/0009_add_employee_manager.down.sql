@@ -0,0 +1,5 @@
+alter table employee_schedule drop column manager_id;
+alter table employee_history drop column manager_id;
+alter table employee drop foreign key employee_manager;
+drop index employee_manager_id on employee;
+alter table employee drop column manager_id;
@@ -0,0 +1,6 @@
+create table if not exists employee (
+	id bigserial primary key,
+	name text not null,
+	position text not null,
+	salary double precision not null
+);
@@ -0,0 +1,2 @@
+drop table if exists employee_schedule;
+drop table if exists employee_history;
@@ -0,0 +1,26 @@
+create table if not exists employee_history (
+	employee_id bigint not null,
+	version bigint not null,
+	name text not null,
+	position text not null,
+	salary double precision not null,
+	valid_from timestamptz not null,
+	valid_to timestamptz null,
+	primary key (employee_id, version)
+);
+create index employee_history_valid_from on employee_history (employee_id, valid_from);
+create table if not exists employee_schedule (
+	id bigserial primary key,
+	employee_id bigint not null,
+	effective_at timestamptz not null,
+	name text null,
+	position text null,
+	salary double precision null,
+	actor text not null,
+	request_id text not null default ''
+);
+create index employee_schedule_effective_at on employee_schedule (effective_at, id);
+create index employee_schedule_employee on employee_schedule (employee_id, effective_at);
+-- employees written before history was kept start their history now
+insert into employee_history (employee_id, version, name, position, salary, valid_from)
+	select id, version, name, position, salary, now() from employee where deleted_at is null;
@@ -0,0 +1,19 @@
+create table if not exists compensation_change (
+	id bigserial primary key,
+	employee_id bigint not null,
+	position text not null,
+	current_salary double precision not null,
+	salary double precision not null,
+	reason text not null,
+	effective_at timestamptz not null,
+	status text not null,
+	proposed_by text not null,
+	proposed_at timestamptz not null,
+	decided_by text not null default '',
+	decided_at timestamptz null,
+	schedule_id bigint null,
+	note text not null default ''
+);
+create index compensation_change_employee on compensation_change (employee_id, id);
+create index compensation_change_effective_at on compensation_change (status, effective_at);
+create index compensation_change_schedule on compensation_change (schedule_id);
@@ -0,0 +1,5 @@
+alter table compensation_change drop column currency, alter column current_salary type double precision using current_salary / 100.0,
+	alter column salary type double precision using salary / 100.0;
+alter table employee_schedule drop column currency, alter column salary type double precision using salary / 100.0;
+alter table employee_history drop column currency, alter column salary type double precision using salary / 100.0;
+alter table employee drop column currency, alter column salary type double precision using salary / 100.0;
@@ -0,0 +1,8 @@
+-- salaries become integers in hundredths of the major unit of their
+-- currency, whatever its minor unit (1000 JPY is 100000, 12.34 USD is 1234),
+-- existing ones in USD
+alter table employee alter column salary type bigint using round(salary * 100), add column currency char(3) not null default 'USD';
+alter table employee_history alter column salary type bigint using round(salary * 100), add column currency char(3) not null default 'USD';
+alter table employee_schedule alter column salary type bigint using round(salary * 100), add column currency char(3) null;
+alter table compensation_change alter column current_salary type bigint using round(current_salary * 100),
+	alter column salary type bigint using round(salary * 100), add column currency char(3) not null default 'USD';
@@ -0,0 +1,12 @@
+create table if not exists department (
+	id bigserial primary key,
+	name text not null,
+	constraint department_name unique (name)
+);
+-- employees are in no department until assigned one
+alter table employee add column department_id bigint null
+	constraint employee_department references department (id);
+create index employee_department_id on employee (department_id);
+alter table employee_history add column department_id bigint null;
+-- a scheduled move is checked again when made, zero takes the employee out
+alter table employee_schedule add column department_id bigint null;
@@ -0,0 +1,6 @@
+create table if not exists employee (
+	id integer primary key autoincrement,
+	name text not null,
+	position text not null,
+	salary real not null
+);
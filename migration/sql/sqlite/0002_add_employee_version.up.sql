@@ -0,0 +1 @@
+alter table employee add column version integer not null default 1;
@@ -0,0 +1,2 @@
+drop index employee_deleted_at;
+alter table employee drop column deleted_at;
@@ -0,0 +1,13 @@
+create table if not exists employee_audit (
+	id integer primary key autoincrement,
+	employee_id integer not null,
+	operation text not null,
+	actor text not null,
+	request_id text not null default '',
+	changed_at timestamp not null,
+	version integer not null,
+	changes text not null
+);
+create index employee_audit_employee on employee_audit (employee_id, id);
+create index employee_audit_actor on employee_audit (actor, id);
+create index employee_audit_changed_at on employee_audit (changed_at);
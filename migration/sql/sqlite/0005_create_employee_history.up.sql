@@ -0,0 +1,27 @@
+create table if not exists employee_history (
+	employee_id integer not null,
+	version integer not null,
+	name text not null,
+	position text not null,
+	salary real not null,
+	valid_from timestamp not null,
+	valid_to timestamp null,
+	primary key (employee_id, version)
+);
+create index employee_history_valid_from on employee_history (employee_id, valid_from);
+create table if not exists employee_schedule (
+	id integer primary key autoincrement,
+	employee_id integer not null,
+	effective_at timestamp not null,
+	name text null,
+	position text null,
+	salary real null,
+	actor text not null,
+	request_id text not null default ''
+);
+create index employee_schedule_effective_at on employee_schedule (effective_at, id);
+create index employee_schedule_employee on employee_schedule (employee_id, effective_at);
+-- employees written before history was kept start their history now, in the
+-- text format the driver writes times in so they compare in order
+insert into employee_history (employee_id, version, name, position, salary, valid_from)
+	select id, version, name, position, salary, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') from employee where deleted_at is null;
@@ -0,0 +1,19 @@
+create table if not exists compensation_change (
+	id integer primary key autoincrement,
+	employee_id integer not null,
+	position text not null,
+	current_salary real not null,
+	salary real not null,
+	reason text not null,
+	effective_at timestamp not null,
+	status text not null,
+	proposed_by text not null,
+	proposed_at timestamp not null,
+	decided_by text not null default '',
+	decided_at timestamp null,
+	schedule_id integer null,
+	note text not null default ''
+);
+create index compensation_change_employee on compensation_change (employee_id, id);
+create index compensation_change_effective_at on compensation_change (status, effective_at);
+create index compensation_change_schedule on compensation_change (schedule_id);
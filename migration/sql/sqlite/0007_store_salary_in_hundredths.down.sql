@@ -0,0 +1,23 @@
+alter table compensation_change drop column currency;
+alter table compensation_change add column current_salary_real real not null default 0;
+alter table compensation_change add column salary_real real not null default 0;
+update compensation_change set current_salary_real = current_salary / 100.0, salary_real = salary / 100.0;
+alter table compensation_change drop column current_salary;
+alter table compensation_change drop column salary;
+alter table compensation_change rename column current_salary_real to current_salary;
+alter table compensation_change rename column salary_real to salary;
+alter table employee_schedule drop column currency;
+alter table employee_schedule add column salary_real real null;
+update employee_schedule set salary_real = salary / 100.0;
+alter table employee_schedule drop column salary;
+alter table employee_schedule rename column salary_real to salary;
+alter table employee_history drop column currency;
+alter table employee_history add column salary_real real not null default 0;
+update employee_history set salary_real = salary / 100.0;
+alter table employee_history drop column salary;
+alter table employee_history rename column salary_real to salary;
+alter table employee drop column currency;
+alter table employee add column salary_real real not null default 0;
+update employee set salary_real = salary / 100.0;
+alter table employee drop column salary;
+alter table employee rename column salary_real to salary;
@@ -0,0 +1,28 @@
+-- salaries become integers in hundredths of the major unit of their
+-- currency, whatever its minor unit (1000 JPY is 100000, 12.34 USD is 1234),
+-- existing ones in USD, in new integer columns since a real column keeps the
+-- values it gets real
+alter table employee add column salary_hundredths integer not null default 0;
+update employee set salary_hundredths = cast(round(salary * 100) as integer);
+alter table employee drop column salary;
+alter table employee rename column salary_hundredths to salary;
+alter table employee add column currency text not null default 'USD';
+alter table employee_history add column salary_hundredths integer not null default 0;
+update employee_history set salary_hundredths = cast(round(salary * 100) as integer);
+alter table employee_history drop column salary;
+alter table employee_history rename column salary_hundredths to salary;
+alter table employee_history add column currency text not null default 'USD';
+alter table employee_schedule add column salary_hundredths integer null;
+update employee_schedule set salary_hundredths = cast(round(salary * 100) as integer);
+alter table employee_schedule drop column salary;
+alter table employee_schedule rename column salary_hundredths to salary;
+alter table employee_schedule add column currency text null;
+alter table compensation_change add column current_salary_hundredths integer not null default 0;
+alter table compensation_change add column salary_hundredths integer not null default 0;
+update compensation_change set current_salary_hundredths = cast(round(current_salary * 100) as integer),
+	salary_hundredths = cast(round(salary * 100) as integer);
+alter table compensation_change drop column current_salary;
+alter table compensation_change drop column salary;
+alter table compensation_change rename column current_salary_hundredths to current_salary;
+alter table compensation_change rename column salary_hundredths to salary;
+alter table compensation_change add column currency text not null default 'USD';
@@ -0,0 +1,5 @@
+alter table employee_schedule drop column department_id;
+alter table employee_history drop column department_id;
+drop index if exists employee_department_id;
+alter table employee drop column department_id;
+drop table if exists department;
@@ -0,0 +1,11 @@
+create table if not exists department (
+	id integer primary key autoincrement,
+	name text not null,
+	constraint department_name unique (name)
+);
+-- employees are in no department until assigned one
+alter table employee add column department_id integer null references department (id);
+create index employee_department_id on employee (department_id);
+alter table employee_history add column department_id integer null;
+-- a scheduled move is checked again when made, zero takes the employee out
+alter table employee_schedule add column department_id integer null;
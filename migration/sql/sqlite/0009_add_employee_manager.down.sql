@@ -0,0 +1,4 @@
+alter table employee_schedule drop column manager_id;
+alter table employee_history drop column manager_id;
+drop index if exists employee_manager_id;
+alter table employee drop column manager_id;
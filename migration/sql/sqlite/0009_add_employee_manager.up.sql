@@ -0,0 +1,7 @@
+-- employees report to nobody until given a manager, purging a manager leaves
+-- the deleted employees that reported to them with none
+alter table employee add column manager_id integer null references employee (id) on delete set null;
+create index employee_manager_id on employee (manager_id);
+alter table employee_history add column manager_id integer null;
+-- a scheduled manager is checked again when made, zero for nobody
+alter table employee_schedule add column manager_id integer null;
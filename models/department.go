@@ -0,0 +1,18 @@
+package models
+
+import "example.com/m/Assesment/money"
+
+// Department groups employees, each employee is in at most one.
+type Department struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// DepartmentSummary totals the employees of a department, zero for those in
+// none, paid in one currency.
+type DepartmentSummary struct {
+	DepartmentID int64
+	Currency     string
+	Headcount    int64
+	Payroll      money.Amount
+}
@@ -1,8 +1,171 @@
 package models
 
+import (
+	"time"
+
+	"example.com/m/Assesment/money"
+)
+
 type Employee struct {
-	ID       int64   `json:"id"`
-	Name     string  `json:"name"`
-	Position string  `josn:"position"`
-	Salary   float64 `json:"salary"`
+	ID       int64        `json:"id"`
+	Name     string       `json:"name"`
+	Position string       `json:"position"`
+	Salary   money.Amount `json:"salary"`
+	// Currency is the ISO 4217 code of the salary.
+	Currency string `json:"currency"`
+	// DepartmentID is the department the employee is in, zero for none.
+	DepartmentID int64 `json:"department_id,omitempty"`
+	// ManagerID is the employee this one reports to, zero for none.
+	ManagerID int64 `json:"manager_id,omitempty"`
+	// Version is bumped on every change, it is exposed through ETags.
+	Version int64 `json:"-"`
+	// DeletedAt is set once the employee is deleted, until it is restored
+	// or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// InitialVersion is the version of a newly created employee.
+const InitialVersion int64 = 1
+
+// EmployeeChanges is a typed change set for an employee. A nil field is left
+// untouched, a non-nil one is written even when it holds the zero value.
+type EmployeeChanges struct {
+	Name     *string
+	Position *string
+	Salary   *money.Amount
+	Currency *string
+	// DepartmentID moves the employee to a department, zero takes it out
+	// of any.
+	DepartmentID *int64
+	// ManagerID makes the employee report to another, zero to nobody.
+	ManagerID *int64
+	// IfVersion makes the change conditional on the employee's current
+	// version, zero applies it unconditionally.
+	IfVersion int64
+}
+
+// ChangesFrom returns a change set replacing every field with e's values,
+// but for an empty currency which is left as it is.
+func ChangesFrom(e Employee) EmployeeChanges {
+	changes := EmployeeChanges{Name: &e.Name, Position: &e.Position, Salary: &e.Salary, DepartmentID: &e.DepartmentID, ManagerID: &e.ManagerID}
+	if e.Currency != "" {
+		changes.Currency = &e.Currency
+	}
+
+	return changes
+}
+
+// Empty reports whether the change set changes no field.
+func (c EmployeeChanges) Empty() bool {
+	return c.Name == nil && c.Position == nil && c.Salary == nil && c.Currency == nil && c.DepartmentID == nil && c.ManagerID == nil
+}
+
+// Apply returns e with the changes applied.
+func (c EmployeeChanges) Apply(e Employee) Employee {
+	if c.Name != nil {
+		e.Name = *c.Name
+	}
+
+	if c.Position != nil {
+		e.Position = *c.Position
+	}
+
+	if c.Salary != nil {
+		e.Salary = *c.Salary
+	}
+
+	if c.Currency != nil {
+		e.Currency = *c.Currency
+	}
+
+	if c.DepartmentID != nil {
+		e.DepartmentID = *c.DepartmentID
+	}
+
+	if c.ManagerID != nil {
+		e.ManagerID = *c.ManagerID
+	}
+
+	return e
+}
+
+// EmployeeVersion is the employee as it was from ValidFrom until ValidTo,
+// which is nil for the current version. A deleted employee has no current
+// version.
+type EmployeeVersion struct {
+	Employee
+	ValidFrom time.Time
+	ValidTo   *time.Time
+}
+
+// ScheduledChange is a change of an employee that takes effect at
+// EffectiveAt, when it is applied as made by Actor in request RequestID.
+type ScheduledChange struct {
+	ID          int64
+	EmployeeID  int64
+	EffectiveAt time.Time
+	Changes     EmployeeChanges
+	Actor       string
+	RequestID   string
+}
+
+// reasons for a compensation change
+const (
+	ReasonPromotion  = "promotion"
+	ReasonMerit      = "merit"
+	ReasonCorrection = "correction"
+)
+
+// CompensationReasons are the accepted reasons.
+var CompensationReasons = []string{ReasonPromotion, ReasonMerit, ReasonCorrection}
+
+// statuses of a compensation change, proposed until decided
+const (
+	StatusProposed = "proposed"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// CompensationChange is a salary change of an employee going through
+// approval, from the CurrentSalary and Position it was proposed against.
+// Both salaries are in Currency, the employee's at the time. ScheduleID is
+// the scheduled change an approval effective later waits in.
+type CompensationChange struct {
+	ID            int64
+	EmployeeID    int64
+	Position      string
+	CurrentSalary money.Amount
+	Salary        money.Amount
+	Currency      string
+	Reason        string
+	EffectiveAt   time.Time
+	Status        string
+	ProposedBy    string
+	ProposedAt    time.Time
+	DecidedBy     string
+	DecidedAt     *time.Time
+	ScheduleID    *int64
+	Note          string
+}
+
+// PercentChange is the change of salary in percent of the current one,
+// false when the current salary is zero.
+func (c CompensationChange) PercentChange() (float64, bool) {
+	if c.CurrentSalary == 0 {
+		return 0, false
+	}
+
+	return float64(c.Salary-c.CurrentSalary) * 100 / float64(c.CurrentSalary), true
+}
+
+// RaiseSummary totals the approved compensation changes of a group in one
+// currency. TotalPercent adds up the percent changes of the Percents changes
+// made from a non-zero salary.
+type RaiseSummary struct {
+	Group        string
+	Currency     string
+	Changes      int64
+	TotalRaise   money.Amount
+	Percents     int64
+	TotalPercent float64
 }
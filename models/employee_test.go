@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmployeeChanges(t *testing.T) {
+	current := Employee{ID: 1, Name: "John", Position: "SDE", Salary: money.Major(30000), Currency: "USD"}
+
+	assert.True(t, EmployeeChanges{}.Empty())
+	assert.Equal(t, current, EmployeeChanges{}.Apply(current))
+
+	// zero values are applied when present
+	position, salary := "", money.Amount(0)
+	changes := EmployeeChanges{Position: &position, Salary: &salary}
+	assert.False(t, changes.Empty())
+	assert.Equal(t, Employee{ID: 1, Name: "John", Currency: "USD"}, changes.Apply(current))
+
+	replacement := Employee{Name: "Jane", Position: "QA", Salary: 1, Currency: "EUR", DepartmentID: 2, ManagerID: 4}
+	expected := replacement
+	expected.ID = 1
+	assert.Equal(t, expected, ChangesFrom(replacement).Apply(current))
+
+	// a replacement without a currency keeps the current one
+	replacement.Currency = ""
+	expected.Currency = "USD"
+	assert.Equal(t, expected, ChangesFrom(replacement).Apply(current))
+
+	// a replacement in no department and reporting to nobody clears both
+	current.DepartmentID, current.ManagerID = 3, 5
+	replacement.DepartmentID, replacement.ManagerID = 0, 0
+	expected.DepartmentID, expected.ManagerID = 0, 0
+	assert.Equal(t, expected, ChangesFrom(replacement).Apply(current))
+}
@@ -0,0 +1,52 @@
+package money
+
+import "sort"
+
+// DefaultCurrency is the currency of amounts given without one.
+const DefaultCurrency = "USD"
+
+// decimals maps the supported ISO 4217 currency codes to the decimals of
+// their minor unit. Currencies with three decimals don't fit an Amount and
+// are left out.
+var decimals = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0,
+	"CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JPY": 0,
+	"KES": 2, "KRW": 0, "MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NZD": 2,
+	"PHP": 2, "PKR": 2, "PLN": 2, "RON": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Supported reports whether code is a currency amounts can be kept in.
+func Supported(code string) bool {
+	_, ok := decimals[code]
+	return ok
+}
+
+// Currencies lists the supported currency codes in order.
+func Currencies() []string {
+	codes := make([]string, 0, len(decimals))
+	for code := range decimals {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	return codes
+}
+
+// Fits reports whether the amount is a whole number of minor units of the
+// currency, so no yen cents. Unknown currencies allow any Amount.
+func (a Amount) Fits(currency string) bool {
+	d, ok := decimals[currency]
+	if !ok {
+		return true
+	}
+
+	step := Amount(1)
+	for i := d; i < Scale; i++ {
+		step *= 10
+	}
+
+	return a%step == 0
+}
@@ -0,0 +1,147 @@
+// Package money represents amounts of money exactly, as whole hundredths of
+// the major unit of an ISO 4217 currency whatever its minor unit, and
+// converts them between currencies for reporting.
+package money
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimals an Amount keeps, the most any currency in
+// the table allows. A currency with fewer, such as JPY, keeps zeros in them.
+const Scale = 2
+
+// unit is one major unit in Amount.
+const unit = 100
+
+// Amount is an exact amount of money in hundredths of the major unit of its
+// currency, cents for USD. It reads and writes as a plain JSON number, or a
+// string holding one, without going through a float.
+type Amount int64
+
+// Major is the amount of the given whole major units.
+func Major(units int64) Amount {
+	return Amount(units * unit)
+}
+
+// amountPattern is a decimal number as JSON writes them, the exponent kept
+// small so it can't stand for an amount past the range anyway.
+var amountPattern = regexp.MustCompile(`^([+-]?)([0-9]+)(?:\.([0-9]+))?(?:[eE]([+-]?[0-9]{1,2}))?$`)
+
+// Parse reads a decimal amount such as "1234.56", "-3" or "1e3". Rather than
+// rounding it fails on more than Scale decimals.
+func Parse(s string) (Amount, error) {
+	m := amountPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("money: invalid amount %q", s)
+	}
+
+	digits := m[2] + m[3]
+	exponent := Scale - len(m[3])
+
+	if m[4] != "" {
+		e, _ := strconv.Atoi(m[4])
+		exponent += e
+	}
+
+	if exponent < 0 {
+		cut := len(digits) + exponent
+		if cut < 0 {
+			cut = 0
+		}
+
+		if strings.Trim(digits[cut:], "0") != "" {
+			return 0, fmt.Errorf("money: amount %q has more than %d decimals", s, Scale)
+		}
+
+		digits = digits[:cut]
+	} else {
+		digits += strings.Repeat("0", exponent)
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(m[1]+digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("money: amount %q out of range", s)
+	}
+
+	return Amount(n), nil
+}
+
+// String writes the amount as a decimal number without trailing zeros,
+// "1234.5" or "1000".
+func (a Amount) String() string {
+	sign := ""
+	n := uint64(a)
+	if a < 0 {
+		sign = "-"
+		n = -n
+	}
+
+	whole, fraction := n/unit, n%unit
+	if fraction == 0 {
+		return sign + strconv.FormatUint(whole, 10)
+	}
+
+	s := fmt.Sprintf("%s%d.%0*d", sign, whole, Scale, fraction)
+
+	return strings.TrimRight(s, "0")
+}
+
+// Float64 is the amount in major units, for display and statistics only.
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+// Quo divides the amount by n, a positive count, rounding half away from
+// zero.
+func (a Amount) Quo(n int64) Amount {
+	q, r := int64(a)/n, int64(a)%n
+
+	switch {
+	case 2*r >= n:
+		q++
+	case -2*r >= n:
+		q--
+	}
+
+	return Amount(q)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number, or a string holding one, leaving the
+// amount untouched for null.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("money: invalid amount %s", s)
+		}
+
+		s = unquoted
+	}
+
+	amount, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		input    string
+		expected Amount
+		wantErr  bool
+	}{
+		{input: "1234.56", expected: 123456},
+		{input: "-3", expected: -300},
+		{input: " 0.1 ", expected: 10},
+		{input: "1e3", expected: 100000},
+		{input: "1.5E-1", expected: 15},
+		{input: "2.500", expected: 250},
+		{input: "000", expected: 0},
+		{input: "10.001", wantErr: true},
+		{input: "1e-3", wantErr: true},
+		{input: "92233720368547758.08", wantErr: true},
+		{input: "1e100", wantErr: true},
+		{input: "NaN", wantErr: true},
+		{input: "1,000", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			amount, err := Parse(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, amount)
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "1000", Major(1000).String())
+	assert.Equal(t, "1234.5", Amount(123450).String())
+	assert.Equal(t, "0.05", Amount(5).String())
+	assert.Equal(t, "-0.05", Amount(-5).String())
+	assert.Equal(t, "0", Amount(0).String())
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		Salary  Amount  `json:"salary"`
+		Bonus   *Amount `json:"bonus"`
+		Default Amount  `json:"default"`
+	}
+	v.Default = 7
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"salary": 0.29, "bonus": "15.5", "default": null}`), &v))
+	assert.Equal(t, Amount(29), v.Salary)
+	assert.Equal(t, Amount(1550), *v.Bonus)
+	assert.Equal(t, Amount(7), v.Default)
+
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"salary": 0.29, "bonus": 15.5, "default": 0.07}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"salary": 0.291}`), &v))
+	assert.Error(t, json.Unmarshal([]byte(`{"salary": true}`), &v))
+}
+
+func TestQuo(t *testing.T) {
+	assert.Equal(t, Amount(33), Amount(100).Quo(3))
+	assert.Equal(t, Amount(67), Amount(200).Quo(3))
+	assert.Equal(t, Amount(2), Amount(3).Quo(2))
+	assert.Equal(t, Amount(-2), Amount(-3).Quo(2))
+}
+
+func TestFits(t *testing.T) {
+	assert.True(t, Amount(150).Fits("USD"))
+	assert.True(t, Major(150).Fits("JPY"))
+	assert.False(t, Amount(150).Fits("JPY"))
+	assert.True(t, Amount(1).Fits("XXX"))
+}
+
+func TestRates(t *testing.T) {
+	rates, err := ParseRates("eur=1.08, GBP=1.27,")
+	assert.NoError(t, err)
+	assert.Equal(t, Rates{"EUR": big.NewRat(108, 100), "GBP": big.NewRat(127, 100)}, rates)
+
+	for _, invalid := range []string{"EUR", "EUR=0", "EUR=-1", "EUR=abc", "XBT=1"} {
+		_, err = ParseRates(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	var fromJSON Rates
+	assert.NoError(t, json.Unmarshal([]byte(`{"EUR": 1.08, "gbp": "1.27"}`), &fromJSON))
+	assert.Equal(t, rates, fromJSON)
+	assert.Error(t, json.Unmarshal([]byte(`["EUR"]`), &fromJSON))
+}
+
+func TestConvert(t *testing.T) {
+	c := Converter{Base: "USD", Rates: Rates{"EUR": big.NewRat(108, 100), "JPY": big.NewRat(67, 10000)}}
+
+	assert.Equal(t, []string{"EUR", "JPY", "USD"}, c.Currencies())
+
+	tt := []struct {
+		amount   Amount
+		currency string
+		expected Amount
+	}{
+		{amount: 12345, currency: "USD", expected: 12345},
+		{amount: Major(100), currency: "EUR", expected: Major(108)},
+		// 0.54 rounds half away from zero
+		{amount: 50, currency: "EUR", expected: 54},
+		{amount: -50, currency: "EUR", expected: -54},
+		{amount: 125, currency: "EUR", expected: 135},
+		{amount: Major(150), currency: "JPY", expected: 101},
+	}
+
+	for _, tc := range tt {
+		converted, err := c.Convert(tc.amount, tc.currency)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, converted, "%s %s", tc.amount, tc.currency)
+	}
+
+	_, err := c.Convert(1, "GBP")
+	assert.ErrorIs(t, err, ErrNoRate)
+}
@@ -0,0 +1,132 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// ErrNoRate is returned converting from a currency without a rate.
+var ErrNoRate = errors.New("money: no conversion rate")
+
+// Rates are exchange rates into a base currency: what one major unit of
+// each currency is worth in it.
+type Rates map[string]*big.Rat
+
+// ParseRates reads rates written like "EUR=1.08, GBP=1.27".
+func ParseRates(s string) (Rates, error) {
+	rates := Rates{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		code, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("money: invalid rate %q, want CODE=rate", item)
+		}
+
+		err := rates.set(strings.TrimSpace(code), strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rates, nil
+}
+
+// UnmarshalJSON reads an object of rates by currency code, each a number or
+// a string holding one.
+func (r *Rates) UnmarshalJSON(data []byte) error {
+	var values map[string]json.RawMessage
+	err := json.Unmarshal(data, &values)
+	if err != nil {
+		return fmt.Errorf("money: rates must be an object of numbers by currency code")
+	}
+
+	rates := Rates{}
+	for code, value := range values {
+		err = rates.set(code, strings.Trim(string(value), `"`))
+		if err != nil {
+			return err
+		}
+	}
+
+	*r = rates
+
+	return nil
+}
+
+func (r Rates) set(code, value string) error {
+	code = strings.ToUpper(code)
+	if !Supported(code) {
+		return fmt.Errorf("money: unsupported currency %q", code)
+	}
+
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || len(value) > 32 || rate.Sign() <= 0 {
+		return fmt.Errorf("money: invalid rate %q for %s, want a positive number", value, code)
+	}
+
+	r[code] = rate
+
+	return nil
+}
+
+// Converter converts amounts into its Base currency.
+type Converter struct {
+	Base  string
+	Rates Rates
+}
+
+// Currencies lists the base and every currency with a rate, in order.
+func (c Converter) Currencies() []string {
+	codes := []string{c.Base}
+	for code := range c.Rates {
+		if code != c.Base {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Strings(codes)
+
+	return codes
+}
+
+// Convert is the amount of currency in the base currency, rounded half away
+// from zero to a cent. It fails with ErrNoRate for currencies other than the
+// base without a rate.
+func (c Converter) Convert(a Amount, currency string) (Amount, error) {
+	if currency == c.Base {
+		return a, nil
+	}
+
+	rate, ok := c.Rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w from %s to %s", ErrNoRate, currency, c.Base)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(a)), rate)
+
+	q, r := new(big.Int).QuoRem(converted.Num(), converted.Denom(), new(big.Int))
+
+	// half away from zero: compare twice the remainder with the divisor
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(converted.Denom()) >= 0 {
+		if converted.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("money: %s %s out of range in %s", a, currency, c.Base)
+	}
+
+	return Amount(q.Int64()), nil
+}
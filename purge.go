@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"time"
+
+	"example.com/m/Assesment/config"
+	"example.com/m/Assesment/database"
+)
+
+// purgeDeleted removes for good the employees deleted longer than
+// retention ago.
+func purgeDeleted(ctx context.Context, store database.Employee, retention time.Duration) error {
+	purged, err := store.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("purged %d employees deleted more than %s ago", purged, retention)
+	}
+
+	return nil
+}
+
+// runPurger purges deleted employees past retention every interval, until
+// ctx is done.
+func runPurger(ctx context.Context, store database.Employee, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := purgeDeleted(ctx, store, retention)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("purging deleted employees: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runPurge implements the purge subcommand, a single pass of the purge job
+// for deployments scheduling it outside the service.
+func runPurge(ctx context.Context, cfg config.Config) error {
+	if cfg.Store != config.StoreSQL {
+		return errors.New("purge needs an sql store")
+	}
+
+	_, err := commandArgs(flag.NewFlagSet("purge", flag.ContinueOnError), cfg.Args, 0)
+	if err != nil {
+		return err
+	}
+
+	if cfg.PurgeRetention == 0 {
+		return errors.New("purge needs a purge retention")
+	}
+
+	empDB, closeDB, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	return purgeDeleted(ctx, empDB, cfg.PurgeRetention)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"example.com/m/Assesment/database"
+)
+
+// runScheduler makes the scheduled changes that came due every interval,
+// until ctx is done, logging any it had to drop.
+func runScheduler(ctx context.Context, store database.History, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		applied, err := store.ApplyScheduled(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("applying scheduled changes: %v", err)
+		}
+
+		if len(applied.Employees) > 0 {
+			log.Printf("applied %d scheduled changes", len(applied.Employees))
+		}
+
+		for _, dropped := range applied.Dropped {
+			change := dropped.Change
+			log.Printf("dropped scheduled change %d of employee %d, scheduled by %q for %s: %v",
+				change.ID, change.EmployeeID, change.Actor, change.EffectiveAt.Format(time.RFC3339), dropped.Err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
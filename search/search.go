@@ -0,0 +1,432 @@
+// Package search is an in-process inverted index over employee names and
+// positions with prefix and typo tolerant matching, relevance ranking and
+// highlighting.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"example.com/m/Assesment/models"
+)
+
+// indexed fields with their weight in the ranking
+const (
+	FieldName     = "name"
+	FieldPosition = "position"
+)
+
+var fieldWeights = map[string]float64{
+	FieldName:     2,
+	FieldPosition: 1,
+}
+
+// weights of the ways a query token can match a term
+const (
+	exactWeight  = 1.0
+	prefixWeight = 0.7
+	fuzzyWeight  = 0.5
+)
+
+// highlight markers around matched fragments, the rest of the value is
+// HTML escaped
+const (
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+)
+
+// Hit is an employee matching a query.
+type Hit struct {
+	Employee models.Employee
+	Score    float64
+	// Highlights holds the matched fields with the matched fragments
+	// wrapped in MarkStart and MarkEnd.
+	Highlights map[string]string
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mu   sync.RWMutex
+	docs map[int64]models.Employee
+	// postings lists the documents holding a term, with how many times
+	// each field holds it
+	postings map[string]map[int64]map[string]int
+	// terms is the sorted vocabulary, for prefix lookups
+	terms []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[int64]models.Employee),
+		postings: make(map[string]map[int64]map[string]int),
+	}
+}
+
+// Len is the number of indexed employees.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return len(x.docs)
+}
+
+// Put indexes the employee, replacing an older version of it. A version
+// older than the indexed one is ignored, so writes racing to the index
+// can't bring back stale values.
+func (x *Index) Put(employee models.Employee) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if current, ok := x.docs[employee.ID]; ok {
+		if current.Version > employee.Version {
+			return
+		}
+
+		x.remove(current)
+	}
+
+	x.docs[employee.ID] = employee
+
+	for field, value := range fieldValues(employee) {
+		for _, token := range tokenize(value) {
+			docs, ok := x.postings[token.text]
+			if !ok {
+				docs = make(map[int64]map[string]int)
+				x.postings[token.text] = docs
+				x.insertTerm(token.text)
+			}
+
+			if docs[employee.ID] == nil {
+				docs[employee.ID] = make(map[string]int)
+			}
+
+			docs[employee.ID][field]++
+		}
+	}
+}
+
+// Load replaces the indexed employees.
+func (x *Index) Load(employees []models.Employee) {
+	fresh := NewIndex()
+	for _, employee := range employees {
+		fresh.Put(employee)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.docs, x.postings, x.terms = fresh.docs, fresh.postings, fresh.terms
+}
+
+// Remove drops the employee from the index.
+func (x *Index) Remove(id int64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if current, ok := x.docs[id]; ok {
+		x.remove(current)
+		delete(x.docs, id)
+	}
+}
+
+func (x *Index) remove(employee models.Employee) {
+	for _, value := range fieldValues(employee) {
+		for _, token := range tokenize(value) {
+			docs := x.postings[token.text]
+			delete(docs, employee.ID)
+
+			if len(docs) == 0 {
+				delete(x.postings, token.text)
+				x.removeTerm(token.text)
+			}
+		}
+	}
+}
+
+func (x *Index) insertTerm(term string) {
+	i := sort.SearchStrings(x.terms, term)
+	x.terms = append(x.terms, "")
+	copy(x.terms[i+1:], x.terms[i:])
+	x.terms[i] = term
+}
+
+func (x *Index) removeTerm(term string) {
+	i := sort.SearchStrings(x.terms, term)
+	if i < len(x.terms) && x.terms[i] == term {
+		x.terms = append(x.terms[:i], x.terms[i+1:]...)
+	}
+}
+
+// match is a vocabulary term a query token matched.
+type match struct {
+	term   string
+	weight float64
+	// prefix is the length in runes of the matched prefix, zero when the
+	// whole term matched
+	prefix int
+}
+
+// Search returns up to limit employees matching every token of query, best
+// first. A token matches a term exactly, as its prefix, or within a small
+// edit distance growing with the token's length.
+func (x *Index) Search(query string, limit int) []Hit {
+	tokens := tokenize(query)
+	if len(tokens) == 0 || limit <= 0 {
+		return nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	scores := make(map[int64]float64)
+	matched := make(map[string]match)
+
+	for i, token := range tokens {
+		tokenScores := make(map[int64]float64)
+
+		for _, m := range x.matches(token.text) {
+			if current, ok := matched[m.term]; !ok || m.weight > current.weight {
+				matched[m.term] = m
+			}
+
+			docs := x.postings[m.term]
+			idf := math.Log(1 + float64(len(x.docs))/float64(len(docs)))
+
+			for id, fields := range docs {
+				score := 0.0
+				for field, count := range fields {
+					score += fieldWeights[field] * (1 + math.Log(float64(count)))
+				}
+
+				// a token counts once per document, through its best term
+				tokenScores[id] = math.Max(tokenScores[id], m.weight*idf*score)
+			}
+		}
+
+		// every token has to match
+		for id := range scores {
+			if _, ok := tokenScores[id]; !ok {
+				delete(scores, id)
+			}
+		}
+
+		for id, score := range tokenScores {
+			if _, ok := scores[id]; ok || i == 0 {
+				scores[id] += score
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{Employee: x.docs[id], Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+
+		return hits[i].Employee.ID < hits[j].Employee.ID
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for i := range hits {
+		hits[i].Highlights = highlights(hits[i].Employee, matched)
+	}
+
+	return hits
+}
+
+// matches finds the terms a query token matches, the best way for each.
+func (x *Index) matches(token string) []match {
+	var matches []match
+
+	if _, ok := x.postings[token]; ok {
+		matches = append(matches, match{term: token, weight: exactWeight})
+	}
+
+	prefix := utf8.RuneCountInString(token)
+	for i := sort.SearchStrings(x.terms, token); i < len(x.terms) && strings.HasPrefix(x.terms[i], token); i++ {
+		if x.terms[i] != token {
+			matches = append(matches, match{term: x.terms[i], weight: prefixWeight, prefix: prefix})
+		}
+	}
+
+	maxEdits := allowedEdits(token)
+	if maxEdits == 0 {
+		return matches
+	}
+
+	for _, term := range x.terms {
+		if term == token || strings.HasPrefix(term, token) {
+			continue
+		}
+
+		distance := editDistance(token, term, maxEdits)
+		if distance <= maxEdits {
+			matches = append(matches, match{term: term, weight: fuzzyWeight / float64(distance)})
+		}
+	}
+
+	return matches
+}
+
+// allowedEdits is the typo tolerance of a token, none for short tokens
+// where a single edit already matches too much.
+func allowedEdits(token string) int {
+	switch n := utf8.RuneCountInString(token); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the optimal string alignment distance between a and b,
+// counting a transposition as one edit. It gives up with max+1 once the
+// distance is known to exceed max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+
+			rowMin = min(rowMin, curr[j])
+		}
+
+		if rowMin > max {
+			return max + 1
+		}
+
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// token is a lower cased word of a value with its byte offsets.
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits value into lower cased words of letters and digits.
+func tokenize(value string) []token {
+	var tokens []token
+
+	start := -1
+	for i, r := range value {
+		word := unicode.IsLetter(r) || unicode.IsNumber(r)
+
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			tokens = append(tokens, token{text: strings.ToLower(value[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, token{text: strings.ToLower(value[start:]), start: start, end: len(value)})
+	}
+
+	return tokens
+}
+
+func fieldValues(employee models.Employee) map[string]string {
+	return map[string]string{
+		FieldName:     employee.Name,
+		FieldPosition: employee.Position,
+	}
+}
+
+// highlights marks the words of each field matching a query token, only
+// the matched prefix of a prefix match.
+func highlights(employee models.Employee, matched map[string]match) map[string]string {
+	result := make(map[string]string)
+
+	for field, value := range fieldValues(employee) {
+		var b strings.Builder
+		last, found := 0, false
+
+		for _, token := range tokenize(value) {
+			m, ok := matched[token.text]
+			if !ok {
+				continue
+			}
+
+			end := token.end
+			if m.prefix > 0 {
+				end = token.start + prefixBytes(value[token.start:token.end], m.prefix)
+			}
+
+			b.WriteString(html.EscapeString(value[last:token.start]))
+			b.WriteString(MarkStart + html.EscapeString(value[token.start:end]) + MarkEnd)
+			last, found = end, true
+		}
+
+		if found {
+			b.WriteString(html.EscapeString(value[last:]))
+			result[field] = b.String()
+		}
+	}
+
+	return result
+}
+
+// prefixBytes is the length in bytes of the first n runes of word.
+func prefixBytes(word string, n int) int {
+	for i := range word {
+		if n == 0 {
+			return i
+		}
+
+		n--
+	}
+
+	return len(word)
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"testing"
+
+	"example.com/m/Assesment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func employees() []models.Employee {
+	return []models.Employee{
+		{ID: 1, Name: "John Doe", Position: "Software Engineer", Version: 1},
+		{ID: 2, Name: "Jane Johnson", Position: "Engineering Manager", Version: 1},
+		{ID: 3, Name: "Alice Smith", Position: "Designer", Version: 1},
+	}
+}
+
+func hitIDs(hits []Hit) []int64 {
+	var result []int64
+	for _, hit := range hits {
+		result = append(result, hit.Employee.ID)
+	}
+
+	return result
+}
+
+func TestSearch(t *testing.T) {
+	index := NewIndex()
+	index.Load(employees())
+
+	tt := []struct {
+		name  string
+		query string
+		want  []int64
+	}{
+		{name: "exact", query: "doe", want: []int64{1}},
+		{name: "case", query: "ALICE", want: []int64{3}},
+		// the exact name match ranks above the prefix
+		{name: "prefix", query: "john", want: []int64{1, 2}},
+		{name: "typo", query: "desginer", want: []int64{3}},
+		{name: "typos in long words", query: "enginere", want: []int64{1}},
+		{name: "no typo in short words", query: "jon", want: nil},
+		{name: "every word", query: "jane engineer", want: []int64{2}},
+		{name: "name over position", query: "eng", want: []int64{1, 2}},
+		{name: "no match", query: "zebra", want: nil},
+		{name: "no words", query: " ,.", want: nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, hitIDs(index.Search(tc.query, 10)))
+		})
+	}
+
+	assert.Len(t, index.Search("e", 1), 1)
+}
+
+func TestSearchHighlights(t *testing.T) {
+	index := NewIndex()
+	index.Put(models.Employee{ID: 1, Name: "John <b>Doe</b>", Position: "Software Engineer"})
+
+	hits := index.Search("jo engneer", 10)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, map[string]string{
+		FieldName:     "<mark>Jo</mark>hn &lt;b&gt;Doe&lt;/b&gt;",
+		FieldPosition: "Software <mark>Engineer</mark>",
+	}, hits[0].Highlights)
+}
+
+func TestIndexWrites(t *testing.T) {
+	index := NewIndex()
+	index.Load(employees())
+	assert.Equal(t, 3, index.Len())
+
+	index.Put(models.Employee{ID: 1, Name: "Johnny Cash", Position: "Singer", Version: 2})
+	assert.Equal(t, []int64{1}, hitIDs(index.Search("singer", 10)))
+	assert.Empty(t, index.Search("doe", 10))
+
+	// an older version arriving late is ignored
+	index.Put(models.Employee{ID: 1, Name: "John Doe", Position: "Software Engineer", Version: 1})
+	assert.Empty(t, index.Search("doe", 10))
+
+	index.Remove(1)
+	assert.Empty(t, index.Search("singer", 10))
+	assert.Equal(t, 2, index.Len())
+	assert.NotContains(t, index.terms, "singer")
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("abc", "abc", 2))
+	assert.Equal(t, 1, editDistance("abcd", "abdc", 2))
+	assert.Equal(t, 1, editDistance("abc", "abxc", 2))
+	assert.Equal(t, 2, editDistance("kitten", "sittin", 2))
+	assert.Equal(t, 2, editDistance("kitten", "sitting", 1))
+}
@@ -0,0 +1,28 @@
+package validation
+
+import (
+	"strings"
+
+	"example.com/m/Assesment/models"
+)
+
+const MaxDepartmentNameLength = 100
+
+// departmentRules are the same whatever the configuration, departments are
+// named freely.
+var departmentRules = Rules[models.Department]{
+	{Name: "name", Value: departmentName, Rules: []Rule{Required(), Length(1, MaxDepartmentNameLength)}},
+}
+
+// NormalizeDepartment trims surrounding whitespace from the name.
+func NormalizeDepartment(d models.Department) models.Department {
+	d.Name = strings.TrimSpace(d.Name)
+
+	return d
+}
+
+func ValidateDepartment(d models.Department) error {
+	return nilIfEmpty(departmentRules.Validate(d))
+}
+
+func departmentName(d models.Department) interface{} { return d.Name }
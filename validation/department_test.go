@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDepartment(t *testing.T) {
+	assert.NoError(t, ValidateDepartment(NormalizeDepartment(models.Department{Name: " R&D "})))
+
+	var errs Errors
+	err := ValidateDepartment(NormalizeDepartment(models.Department{Name: "  "}))
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{{Field: "name", Code: CodeRequired, Message: "is required"}}, errs)
+
+	err = ValidateDepartment(models.Department{Name: strings.Repeat("a", MaxDepartmentNameLength+1)})
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, CodeTooLong, errs[0].Code)
+}
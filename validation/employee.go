@@ -0,0 +1,228 @@
+package validation
+
+import (
+	"regexp"
+	"strings"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+)
+
+var (
+	namePattern     = regexp.MustCompile(`^[\p{L}\p{M}][\p{L}\p{M} .'-]*$`)
+	positionPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} .,&()/+#-]*$`)
+)
+
+const (
+	MaxNameLength     = 100
+	MaxPositionLength = 100
+
+	DefaultMaxSalary = money.Amount(1e9 * 100)
+)
+
+// EmployeeOptions configures the employee rules.
+type EmployeeOptions struct {
+	// Positions is the catalogue of allowed positions, empty allows any.
+	Positions []string
+	// MaxSalary caps the salary, DefaultMaxSalary when zero.
+	MaxSalary money.Amount
+	// Currencies is the catalogue of allowed salary currencies, empty
+	// allows any supported one.
+	Currencies []string
+	// Currency is given to new employees without one,
+	// money.DefaultCurrency when empty.
+	Currency string
+}
+
+// Employee holds the rule sets for full employees (create and replace) and
+// for change sets, shared by the HTTP layer, bulk imports and the CLI.
+type Employee struct {
+	Create  Rules[models.Employee]
+	Changes Rules[models.EmployeeChanges]
+	// Currency is the currency Defaults gives new employees.
+	Currency string
+}
+
+func NewEmployee(opts EmployeeOptions) Employee {
+	if opts.MaxSalary == 0 {
+		opts.MaxSalary = DefaultMaxSalary
+	}
+
+	if opts.Currency == "" {
+		opts.Currency = money.DefaultCurrency
+	}
+
+	name := []Rule{
+		Length(1, MaxNameLength),
+		Matches(namePattern, "letters, spaces, apostrophes, hyphens and periods"),
+	}
+
+	position := []Rule{
+		Length(1, MaxPositionLength),
+		Matches(positionPattern, "letters, digits, spaces and . , & ( ) / + # -"),
+		OneOf(opts.Positions),
+	}
+
+	salary := []Rule{
+		AmountBetween(0, opts.MaxSalary),
+		MinorUnits(),
+	}
+
+	currency := []Rule{
+		Currency(opts.Currencies),
+	}
+
+	return Employee{
+		Create: Rules[models.Employee]{
+			{Name: "name", Value: employeeName, Rules: append([]Rule{Required()}, name...)},
+			{Name: "position", Value: employeePosition, Rules: append([]Rule{Required()}, position...)},
+			{Name: "salary", Value: employeeSalary, Rules: append([]Rule{Required()}, salary...)},
+			// an empty currency keeps the current one on replace, Defaults
+			// fills it in on create
+			{Name: "currency", Value: employeeCurrency, Rules: []Rule{Optional(currency...)}},
+			{Name: "department_id", Value: employeeDepartment, Rules: []Rule{Reference()}},
+			{Name: "manager_id", Value: employeeManager, Rules: []Rule{Reference()}},
+		},
+		// a change set may clear the position and set a zero salary, but a
+		// name it carries must still be a valid one
+		Changes: Rules[models.EmployeeChanges]{
+			{Name: "name", Value: changedName, Rules: []Rule{Present(append([]Rule{Required()}, name...)...)}},
+			{Name: "position", Value: changedPosition, Rules: []Rule{Present(Optional(position...))}},
+			{Name: "salary", Value: changedSalary, Rules: []Rule{Present(AmountRange(0, opts.MaxSalary), MinorUnits())}},
+			{Name: "currency", Value: changedCurrency, Rules: []Rule{Present(append([]Rule{Required()}, currency...)...)}},
+			{Name: "department_id", Value: changedDepartment, Rules: []Rule{Present(Reference())}},
+			{Name: "manager_id", Value: changedManager, Rules: []Rule{Present(Reference())}},
+		},
+		Currency: opts.Currency,
+	}
+}
+
+// Normalize trims surrounding whitespace from the text fields, upper cases
+// the currency and drops the deletion time, which only deletes set.
+func Normalize(e models.Employee) models.Employee {
+	e.DeletedAt = nil
+	e.Name = strings.TrimSpace(e.Name)
+	e.Position = strings.TrimSpace(e.Position)
+	e.Currency = normalizeCurrency(e.Currency)
+
+	return e
+}
+
+// Defaults is the new employee e, normalized and in the default currency
+// unless it has one.
+func (v Employee) Defaults(e models.Employee) models.Employee {
+	e = Normalize(e)
+	if e.Currency == "" {
+		e.Currency = v.Currency
+	}
+
+	return e
+}
+
+// NormalizeChanges trims surrounding whitespace from the text fields present
+// and upper cases the currency.
+func NormalizeChanges(c models.EmployeeChanges) models.EmployeeChanges {
+	if c.Name != nil {
+		name := strings.TrimSpace(*c.Name)
+		c.Name = &name
+	}
+
+	if c.Position != nil {
+		position := strings.TrimSpace(*c.Position)
+		c.Position = &position
+	}
+
+	if c.Currency != nil {
+		currency := normalizeCurrency(*c.Currency)
+		c.Currency = &currency
+	}
+
+	return c
+}
+
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+func (v Employee) ValidateCreate(e models.Employee) error {
+	return nilIfEmpty(v.Create.Validate(e))
+}
+
+func (v Employee) ValidateChanges(c models.EmployeeChanges) error {
+	return nilIfEmpty(v.Changes.Validate(c))
+}
+
+// nilIfEmpty avoids returning a typed nil inside the error interface.
+func nilIfEmpty(errs Errors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+func employeeName(e models.Employee) interface{}       { return e.Name }
+func employeePosition(e models.Employee) interface{}   { return e.Position }
+func employeeCurrency(e models.Employee) interface{}   { return e.Currency }
+func employeeDepartment(e models.Employee) interface{} { return e.DepartmentID }
+func employeeManager(e models.Employee) interface{}    { return e.ManagerID }
+
+func employeeSalary(e models.Employee) interface{} {
+	return Money{Amount: e.Salary, Currency: e.Currency}
+}
+
+// change set accessors return nil for absent fields
+func changedName(c models.EmployeeChanges) interface{} {
+	if c.Name == nil {
+		return nil
+	}
+
+	return *c.Name
+}
+
+func changedPosition(c models.EmployeeChanges) interface{} {
+	if c.Position == nil {
+		return nil
+	}
+
+	return *c.Position
+}
+
+// changedSalary is checked against the currency only when the change sets
+// it too.
+func changedSalary(c models.EmployeeChanges) interface{} {
+	if c.Salary == nil {
+		return nil
+	}
+
+	m := Money{Amount: *c.Salary}
+	if c.Currency != nil {
+		m.Currency = *c.Currency
+	}
+
+	return m
+}
+
+func changedCurrency(c models.EmployeeChanges) interface{} {
+	if c.Currency == nil {
+		return nil
+	}
+
+	return *c.Currency
+}
+
+func changedDepartment(c models.EmployeeChanges) interface{} {
+	if c.DepartmentID == nil {
+		return nil
+	}
+
+	return *c.DepartmentID
+}
+
+func changedManager(c models.EmployeeChanges) interface{} {
+	if c.ManagerID == nil {
+		return nil
+	}
+
+	return *c.ManagerID
+}
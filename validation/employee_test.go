@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/m/Assesment/models"
+	"example.com/m/Assesment/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCreate(t *testing.T) {
+	v := NewEmployee(EmployeeOptions{Positions: []string{"SDE", "SDE-2", "QA"}, MaxSalary: money.Major(100000)})
+
+	testCases := []struct {
+		name     string
+		employee models.Employee
+		expected Errors
+	}{
+		{
+			name:     "Valid employee",
+			employee: models.Employee{Name: "Jean-Luc O'Neil Jr.", Position: "sde-2", Salary: 30000},
+		},
+		{
+			name:     "Everything missing",
+			employee: models.Employee{Name: " "},
+			expected: Errors{
+				{Field: "name", Code: CodeRequired, Message: "is required"},
+				{Field: "position", Code: CodeRequired, Message: "is required"},
+				{Field: "salary", Code: CodeRequired, Message: "is required"},
+			},
+		},
+		{
+			name:     "Name too long",
+			employee: models.Employee{Name: strings.Repeat("a", MaxNameLength+1), Position: "SDE", Salary: 1},
+			expected: Errors{{Field: "name", Code: CodeTooLong, Message: "must be at most 100 characters"}},
+		},
+		{
+			name:     "Name with digits",
+			employee: models.Employee{Name: "R2D2", Position: "SDE", Salary: 1},
+			expected: Errors{{Field: "name", Code: CodeCharacter, Message: "may only contain letters, spaces, apostrophes, hyphens and periods"}},
+		},
+		{
+			name:     "Position outside catalogue",
+			employee: models.Employee{Name: "John", Position: "CEO", Salary: 1},
+			expected: Errors{{Field: "position", Code: CodeNotAllowed, Message: "must be one of SDE, SDE-2, QA"}},
+		},
+		{
+			name:     "Negative salary",
+			employee: models.Employee{Name: "John", Position: "SDE", Salary: -5},
+			expected: Errors{{Field: "salary", Code: CodeRange, Message: "must be greater than 0 and at most 100000"}},
+		},
+		{
+			name:     "Salary above maximum",
+			employee: models.Employee{Name: "John", Position: "SDE", Salary: money.Major(100000) + 1},
+			expected: Errors{{Field: "salary", Code: CodeRange, Message: "must be greater than 0 and at most 100000"}},
+		},
+		{
+			name:     "Salary in cents of yen",
+			employee: models.Employee{Name: "John", Position: "SDE", Salary: 150, Currency: "jpy"},
+			expected: Errors{{Field: "salary", Code: CodePrecision, Message: "has more decimals than JPY allows"}},
+		},
+		{
+			name:     "Unknown currency",
+			employee: models.Employee{Name: "John", Position: "SDE", Salary: 1, Currency: "XYZ"},
+			expected: Errors{{Field: "currency", Code: CodeNotAllowed, Message: "must be a supported ISO 4217 currency code"}},
+		},
+		{
+			name:     "Negative department",
+			employee: models.Employee{Name: "John", Position: "SDE", Salary: 1, DepartmentID: -1},
+			expected: Errors{{Field: "department_id", Code: CodeRange, Message: "must be a positive id, or zero for none"}},
+		},
+		{
+			name:     "Negative manager",
+			employee: models.Employee{Name: "John", Position: "SDE", Salary: 1, ManagerID: -2},
+			expected: Errors{{Field: "manager_id", Code: CodeRange, Message: "must be a positive id, or zero for none"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.ValidateCreate(Normalize(tc.employee))
+			if tc.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs Errors
+			assert.True(t, errors.As(err, &errs))
+			assert.Equal(t, tc.expected, errs)
+		})
+	}
+}
+
+func TestValidateChanges(t *testing.T) {
+	v := NewEmployee(EmployeeOptions{Positions: []string{"SDE"}})
+
+	empty, zero := "", money.Amount(0)
+
+	// absent fields are not checked, clearing the position and a zero salary
+	// are allowed
+	assert.NoError(t, v.ValidateChanges(models.EmployeeChanges{}))
+	assert.NoError(t, v.ValidateChanges(models.EmployeeChanges{Position: &empty, Salary: &zero}))
+
+	name, position, salary := "<script>", "CEO", money.Amount(-100)
+	err := v.ValidateChanges(models.EmployeeChanges{Name: &name, Position: &position, Salary: &salary})
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []string{CodeCharacter, CodeNotAllowed, CodeRange}, []string{errs[0].Code, errs[1].Code, errs[2].Code})
+	assert.Contains(t, err.Error(), "name: may only contain")
+
+	// the salary is checked against a currency changed along with it
+	yen, currency := money.Amount(150), "jpy"
+	err = v.ValidateChanges(NormalizeChanges(models.EmployeeChanges{Salary: &yen, Currency: &currency}))
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{{Field: "salary", Code: CodePrecision, Message: "has more decimals than JPY allows"}}, errs)
+
+	// zero takes the employee out of its department
+	none, negative := int64(0), int64(-3)
+	assert.NoError(t, v.ValidateChanges(models.EmployeeChanges{DepartmentID: &none}))
+	err = v.ValidateChanges(models.EmployeeChanges{DepartmentID: &negative})
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{{Field: "department_id", Code: CodeRange, Message: "must be a positive id, or zero for none"}}, errs)
+
+	// likewise zero makes the employee report to nobody
+	assert.NoError(t, v.ValidateChanges(models.EmployeeChanges{ManagerID: &none}))
+	err = v.ValidateChanges(models.EmployeeChanges{ManagerID: &negative})
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{{Field: "manager_id", Code: CodeRange, Message: "must be a positive id, or zero for none"}}, errs)
+
+	// a present name can't be blanked
+	err = v.ValidateChanges(NormalizeChanges(models.EmployeeChanges{Name: &empty}))
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{{Field: "name", Code: CodeRequired, Message: "is required"}}, errs)
+}
@@ -0,0 +1,313 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/m/Assesment/money"
+)
+
+// field error codes
+const (
+	CodeRequired   = "required"
+	CodeTooShort   = "too_short"
+	CodeTooLong    = "too_long"
+	CodeCharacter  = "invalid_characters"
+	CodeRange      = "out_of_range"
+	CodeNotAllowed = "not_allowed"
+	CodePrecision  = "too_precise"
+)
+
+// Violation is what a Rule reports when a value breaks it.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+// Rule checks a single value. Rules receive the field's value as is, a rule
+// given a type it does not handle reports nothing.
+type Rule func(value interface{}) *Violation
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors aggregates the failures of every field.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	var parts []string
+	for _, f := range e {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Field binds rules to a named value extracted from T.
+type Field[T any] struct {
+	Name  string
+	Value func(T) interface{}
+	Rules []Rule
+}
+
+// Rules is an ordered set of field constraints. Each field reports at most
+// its first violation, every field is checked.
+type Rules[T any] []Field[T]
+
+func (rs Rules[T]) Validate(v T) Errors {
+	var errs Errors
+
+	for _, field := range rs {
+		value := field.Value(v)
+
+		for _, rule := range field.Rules {
+			violation := rule(value)
+			if violation != nil {
+				errs = append(errs, FieldError{Field: field.Name, Code: violation.Code, Message: violation.Message})
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+func isZero(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case float64:
+		return v == 0
+	case int64:
+		return v == 0
+	case Money:
+		return v.Amount == 0
+	}
+
+	return false
+}
+
+// Required rejects empty strings, zero numbers and zero amounts.
+func Required() Rule {
+	return func(value interface{}) *Violation {
+		if isZero(value) {
+			return &Violation{Code: CodeRequired, Message: "is required"}
+		}
+
+		return nil
+	}
+}
+
+// Optional runs rules only when the value is set, for partial updates where
+// zero means "not provided".
+func Optional(rules ...Rule) Rule {
+	return func(value interface{}) *Violation {
+		if isZero(value) {
+			return nil
+		}
+
+		for _, rule := range rules {
+			violation := rule(value)
+			if violation != nil {
+				return violation
+			}
+		}
+
+		return nil
+	}
+}
+
+// Present runs rules only when the value was provided, for change sets whose
+// field accessors return nil for absent fields.
+func Present(rules ...Rule) Rule {
+	return func(value interface{}) *Violation {
+		if value == nil {
+			return nil
+		}
+
+		for _, rule := range rules {
+			violation := rule(value)
+			if violation != nil {
+				return violation
+			}
+		}
+
+		return nil
+	}
+}
+
+// Length bounds the number of characters of a string.
+func Length(min, max int) Rule {
+	return func(value interface{}) *Violation {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+
+		n := utf8.RuneCountInString(s)
+		if n < min {
+			return &Violation{Code: CodeTooShort, Message: fmt.Sprintf("must be at least %d characters", min)}
+		}
+
+		if n > max {
+			return &Violation{Code: CodeTooLong, Message: fmt.Sprintf("must be at most %d characters", max)}
+		}
+
+		return nil
+	}
+}
+
+// Matches requires a string to match pattern, described in messages as
+// allowed.
+func Matches(pattern *regexp.Regexp, allowed string) Rule {
+	return func(value interface{}) *Violation {
+		s, ok := value.(string)
+		if !ok || pattern.MatchString(s) {
+			return nil
+		}
+
+		return &Violation{Code: CodeCharacter, Message: "may only contain " + allowed}
+	}
+}
+
+// Between requires a finite number with min < value <= max.
+func Between(min, max float64) Rule {
+	return func(value interface{}) *Violation {
+		f, ok := value.(float64)
+		if !ok {
+			return nil
+		}
+
+		if math.IsNaN(f) || math.IsInf(f, 0) || f <= min || f > max {
+			return &Violation{Code: CodeRange, Message: fmt.Sprintf("must be greater than %g and at most %g", min, max)}
+		}
+
+		return nil
+	}
+}
+
+// Range requires a finite number with min <= value <= max.
+func Range(min, max float64) Rule {
+	return func(value interface{}) *Violation {
+		f, ok := value.(float64)
+		if !ok {
+			return nil
+		}
+
+		if math.IsNaN(f) || math.IsInf(f, 0) || f < min || f > max {
+			return &Violation{Code: CodeRange, Message: fmt.Sprintf("must be between %g and %g", min, max)}
+		}
+
+		return nil
+	}
+}
+
+// Money is an amount with its currency, as the amount rules see it.
+type Money struct {
+	Amount   money.Amount
+	Currency string
+}
+
+// AmountBetween requires an amount with min < amount <= max.
+func AmountBetween(min, max money.Amount) Rule {
+	return func(value interface{}) *Violation {
+		m, ok := value.(Money)
+		if !ok || (m.Amount > min && m.Amount <= max) {
+			return nil
+		}
+
+		return &Violation{Code: CodeRange, Message: fmt.Sprintf("must be greater than %s and at most %s", min, max)}
+	}
+}
+
+// AmountRange requires an amount with min <= amount <= max.
+func AmountRange(min, max money.Amount) Rule {
+	return func(value interface{}) *Violation {
+		m, ok := value.(Money)
+		if !ok || (m.Amount >= min && m.Amount <= max) {
+			return nil
+		}
+
+		return &Violation{Code: CodeRange, Message: fmt.Sprintf("must be between %s and %s", min, max)}
+	}
+}
+
+// MinorUnits requires an amount in whole minor units of its currency, no
+// fractions of a yen.
+func MinorUnits() Rule {
+	return func(value interface{}) *Violation {
+		m, ok := value.(Money)
+		if !ok || m.Amount.Fits(m.Currency) {
+			return nil
+		}
+
+		return &Violation{Code: CodePrecision, Message: "has more decimals than " + m.Currency + " allows"}
+	}
+}
+
+// Currency requires a supported ISO 4217 currency code from catalogue, or
+// any supported one when catalogue is empty.
+func Currency(catalogue []string) Rule {
+	return func(value interface{}) *Violation {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+
+		allowed := money.Supported(s)
+		if allowed && len(catalogue) > 0 {
+			allowed = false
+			for _, c := range catalogue {
+				allowed = allowed || c == s
+			}
+		}
+
+		if allowed {
+			return nil
+		}
+
+		if len(catalogue) == 0 {
+			return &Violation{Code: CodeNotAllowed, Message: "must be a supported ISO 4217 currency code"}
+		}
+
+		return &Violation{Code: CodeNotAllowed, Message: "must be one of " + strings.Join(catalogue, ", ")}
+	}
+}
+
+// Reference requires the id of another resource, zero standing for none.
+func Reference() Rule {
+	return func(value interface{}) *Violation {
+		id, ok := value.(int64)
+		if !ok || id >= 0 {
+			return nil
+		}
+
+		return &Violation{Code: CodeRange, Message: "must be a positive id, or zero for none"}
+	}
+}
+
+// OneOf requires a string from catalogue, compared case-insensitively. An
+// empty catalogue allows anything.
+func OneOf(catalogue []string) Rule {
+	allowed := make(map[string]bool, len(catalogue))
+	for _, c := range catalogue {
+		allowed[strings.ToLower(c)] = true
+	}
+
+	return func(value interface{}) *Violation {
+		s, ok := value.(string)
+		if !ok || len(allowed) == 0 || allowed[strings.ToLower(s)] {
+			return nil
+		}
+
+		return &Violation{Code: CodeNotAllowed, Message: "must be one of " + strings.Join(catalogue, ", ")}
+	}
+}